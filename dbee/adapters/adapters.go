@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 )
@@ -115,6 +117,34 @@ func (wa *wrappedAdapter) GetHelpers(opts *core.TableOptions) map[string]string
 	return helpers
 }
 
+// isDataQuery reports if the query starts with a statement which reads or modifies data
+// (as opposed to e.g. DDL or session statements). Leading comments and parentheses are skipped.
+func isDataQuery(query string) bool {
+	query = strings.TrimSpace(query)
+	for {
+		switch {
+		case strings.HasPrefix(query, "--"):
+			_, query, _ = strings.Cut(query, "\n")
+		case strings.HasPrefix(query, "/*"):
+			_, query, _ = strings.Cut(query, "*/")
+		case strings.HasPrefix(query, "("):
+			query = query[1:]
+		default:
+			// keyword ends with any whitespace or punctuation
+			end := strings.IndexFunc(query, func(r rune) bool { return !unicode.IsLetter(r) })
+			if end < 0 {
+				end = len(query)
+			}
+			switch strings.ToUpper(query[:end]) {
+			case "SELECT", "WITH", "INSERT", "UPDATE", "DELETE", "MERGE":
+				return true
+			}
+			return false
+		}
+		query = strings.TrimSpace(query)
+	}
+}
+
 // NewConnection is a wrapper around core.NewConnection that uses the internal mux for
 // adapter registration.
 func NewConnection(params *core.ConnectionParams) (*core.Connection, error) {
@@ -0,0 +1,37 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isDataQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		give string
+		want bool
+	}{
+		{name: "select", give: "SELECT * FROM t", want: true},
+		{name: "lowercase", give: "select 1", want: true},
+		{name: "single keyword", give: "select", want: true},
+		{name: "newline", give: "SELECT\n  *\nFROM t", want: true},
+		{name: "tab", give: "SELECT\t*\tFROM t", want: true},
+		{name: "carriage return", give: "UPDATE\r\nt SET a = 1", want: true},
+		{name: "cte on new line", give: "WITH\nx AS (SELECT 1)\nSELECT * FROM x", want: true},
+		{name: "parenthesis after keyword", give: "WITH(x) AS (SELECT 1) SELECT * FROM x", want: true},
+		{name: "leading comments", give: "-- comment\n/* block\ncomment */\n\tINSERT INTO t VALUES (1)", want: true},
+		{name: "parenthesized", give: "(\n(SELECT 1))", want: true},
+		{name: "keyword prefix", give: "SELECTED", want: false},
+		{name: "ddl", give: "CREATE\nTABLE t (a INT)", want: false},
+		{name: "session statement", give: "USE\tCATALOG main", want: false},
+		{name: "empty", give: " \n", want: false},
+		{name: "only comment", give: "-- SELECT", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isDataQuery(tt.give))
+		})
+	}
+}
@@ -16,7 +16,13 @@ import (
 	"google.golang.org/api/iterator"
 )
 
-var _ core.Driver = (*bigQueryDriver)(nil)
+var (
+	_ core.Driver       = (*bigQueryDriver)(nil)
+	_ core.ParamQuerier = (*bigQueryDriver)(nil)
+	_ core.Canceler     = (*bigQueryDriver)(nil)
+	_ core.Explainer    = (*bigQueryDriver)(nil)
+	_ core.Estimator    = (*bigQueryDriver)(nil)
+)
 
 type bigQueryDriver struct {
 	c *bigquery.Client
@@ -24,11 +30,24 @@ type bigQueryDriver struct {
 }
 
 func (d *bigQueryDriver) Query(ctx context.Context, queryStr string) (core.ResultStream, error) {
+	return d.QueryWithParams(ctx, queryStr, nil)
+}
+
+// QueryWithParams binds params as positional (?) or named (@name) query parameters.
+func (d *bigQueryDriver) QueryWithParams(ctx context.Context, queryStr string, params *core.QueryParams) (core.ResultStream, error) {
 	query := d.c.Query(queryStr)
 	d.Q = query.Q
 	query.QueryConfig = d.QueryConfig
+	query.Parameters = bigqueryParameters(params)
+
+	job, err := query.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// location is needed to look up the job when canceling it
+	core.ReportQueryID(ctx, job.Location()+"."+job.ID())
 
-	iter, err := query.Read(ctx)
+	iter, err := job.Read(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +64,9 @@ func (d *bigQueryDriver) Query(ctx context.Context, queryStr string) (core.Resul
 		}
 	}
 
+	// total number of rows is known once the first page is retrieved
+	core.ReportQueryProgress(ctx, core.QueryProgress{TotalRows: int64(iter.TotalRows)})
+
 	header := d.buildHeader("", iter.Schema)
 
 	nextFn := func() (core.Row, error) {
@@ -73,6 +95,178 @@ func (d *bigQueryDriver) Query(ctx context.Context, queryStr string) (core.Resul
 	return result, nil
 }
 
+// CancelQuery cancels the job with the provided "<location>.<job id>" identifier.
+func (d *bigQueryDriver) CancelQuery(ctx context.Context, queryID string) error {
+	location, jobID, ok := strings.Cut(queryID, ".")
+	if !ok {
+		return fmt.Errorf("invalid job identifier: %q", queryID)
+	}
+
+	job, err := d.c.JobFromIDLocation(ctx, jobID, location)
+	if err != nil {
+		return fmt.Errorf("d.c.JobFromIDLocation: %w", err)
+	}
+
+	return job.Cancel(ctx)
+}
+
+// Explain estimates the query with a dry run, which only reports the number of processed bytes.
+// Analyze runs the query (which is billed!) to get the plan of its execution stages. Only SELECT
+// statements are analyzed, as running any other statement would modify data.
+func (d *bigQueryDriver) Explain(ctx context.Context, queryStr string, opts *core.ExplainOptions) (*core.PlanNode, error) {
+	if opts.Analyze {
+		err := d.checkReadOnly(ctx, queryStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := d.c.Query(queryStr)
+	query.QueryConfig = d.QueryConfig
+	query.Q = queryStr
+	query.DryRun = !opts.Analyze
+
+	job, err := query.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := job.LastStatus()
+	if opts.Analyze {
+		status, err = job.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	if status.Statistics == nil {
+		return nil, errors.New("no statistics for the query")
+	}
+
+	stats, _ := status.Statistics.Details.(*bigquery.QueryStatistics)
+	return bigqueryPlan(status.Statistics, stats), nil
+}
+
+// checkReadOnly returns an error if the query isn't a SELECT statement, using a dry run.
+func (d *bigQueryDriver) checkReadOnly(ctx context.Context, queryStr string) error {
+	query := d.c.Query(queryStr)
+	query.QueryConfig = d.QueryConfig
+	query.Q = queryStr
+	query.DryRun = true
+
+	job, err := query.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	status := job.LastStatus()
+	if err := status.Err(); err != nil {
+		return err
+	}
+	if status.Statistics == nil {
+		return errors.New("no statistics for the query")
+	}
+
+	stats, ok := status.Statistics.Details.(*bigquery.QueryStatistics)
+	if !ok || stats.StatementType != "SELECT" {
+		statementType := "unknown"
+		if ok {
+			statementType = stats.StatementType
+		}
+		return fmt.Errorf("%w: only SELECT statements can be analyzed (statement type: %s)", core.ErrExplainAnalyzeNotSupported, statementType)
+	}
+
+	return nil
+}
+
+// Estimate returns the number of bytes the query processes (which it's billed by) using a dry run.
+func (d *bigQueryDriver) Estimate(ctx context.Context, queryStr string, params *core.QueryParams) (*core.Estimate, error) {
+	query := d.c.Query(queryStr)
+	query.QueryConfig = d.QueryConfig
+	query.Q = queryStr
+	query.Parameters = bigqueryParameters(params)
+	query.DryRun = true
+
+	job, err := query.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := job.LastStatus()
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	if status.Statistics == nil {
+		return nil, errors.New("no statistics for the query")
+	}
+
+	return &core.Estimate{
+		Bytes:  status.Statistics.TotalBytesProcessed,
+		Detail: fmt.Sprintf("%d bytes processed", status.Statistics.TotalBytesProcessed),
+	}, nil
+}
+
+// bigqueryPlan converts statistics of a query job to a plan tree.
+// Execution stages (only available for executed queries) are children of the query node.
+func bigqueryPlan(jobStats *bigquery.JobStatistics, stats *bigquery.QueryStatistics) *core.PlanNode {
+	root := &core.PlanNode{
+		Operation: "Query",
+		Detail:    fmt.Sprintf("%d bytes processed", jobStats.TotalBytesProcessed),
+		// bytes processed are what the query is billed by
+		Cost: float64(jobStats.TotalBytesProcessed),
+	}
+	if !jobStats.StartTime.IsZero() && !jobStats.EndTime.IsZero() {
+		root.Time = jobStats.EndTime.Sub(jobStats.StartTime)
+	}
+	if stats == nil || len(stats.QueryPlan) < 1 {
+		return root
+	}
+
+	stages := make(map[int64]*bigquery.ExplainQueryStage, len(stats.QueryPlan))
+	isInput := make(map[int64]bool)
+	for _, stage := range stats.QueryPlan {
+		stages[stage.ID] = stage
+		for _, input := range stage.InputStages {
+			isInput[input] = true
+		}
+	}
+
+	var stageNode func(stage *bigquery.ExplainQueryStage) *core.PlanNode
+	stageNode = func(stage *bigquery.ExplainQueryStage) *core.PlanNode {
+		var kinds []string
+		for _, step := range stage.Steps {
+			kinds = append(kinds, step.Kind)
+		}
+
+		node := &core.PlanNode{
+			Operation:  stage.Name,
+			Detail:     strings.Join(kinds, ", "),
+			ActualRows: float64(stage.RecordsWritten),
+		}
+		if !stage.StartTime.IsZero() && !stage.EndTime.IsZero() {
+			node.Time = stage.EndTime.Sub(stage.StartTime)
+		}
+
+		for _, input := range stage.InputStages {
+			if s, ok := stages[input]; ok {
+				node.Children = append(node.Children, stageNode(s))
+			}
+		}
+		return node
+	}
+
+	// output stages are not inputs of any other stage
+	for _, stage := range stats.QueryPlan {
+		if !isInput[stage.ID] {
+			root.Children = append(root.Children, stageNode(stage))
+		}
+	}
+
+	return root
+}
+
 func (d *bigQueryDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
 	query := fmt.Sprintf(
 		"SELECT COLUMN_NAME, DATA_TYPE FROM `%s.INFORMATION_SCHEMA.COLUMNS` WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'",
@@ -265,3 +459,21 @@ func setFieldFromString(fieldValue reflect.Value, val string) error {
 	}
 	return nil
 }
+
+// bigqueryParameters converts query params to bigquery query parameters.
+// Positional parameters are unnamed.
+func bigqueryParameters(params *core.QueryParams) []bigquery.QueryParameter {
+	if params.IsEmpty() {
+		return nil
+	}
+
+	parameters := make([]bigquery.QueryParameter, 0, len(params.Positional)+len(params.Named))
+	for _, value := range params.Positional {
+		parameters = append(parameters, bigquery.QueryParameter{Value: value})
+	}
+	for name, value := range params.Named {
+		parameters = append(parameters, bigquery.QueryParameter{Name: name, Value: value})
+	}
+
+	return parameters
+}
@@ -2,9 +2,15 @@ package adapters
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
 )
@@ -12,6 +18,10 @@ import (
 var (
 	_ core.Driver           = (*clickhouseDriver)(nil)
 	_ core.DatabaseSwitcher = (*clickhouseDriver)(nil)
+	_ core.CurrentDatabaser = (*clickhouseDriver)(nil)
+	_ core.Canceler         = (*clickhouseDriver)(nil)
+	_ core.Explainer        = (*clickhouseDriver)(nil)
+	_ core.ErrorParser      = (*clickhouseDriver)(nil)
 )
 
 type clickhouseDriver struct {
@@ -20,10 +30,122 @@ type clickhouseDriver struct {
 }
 
 func (c *clickhouseDriver) Query(ctx context.Context, query string) (core.ResultStream, error) {
+	opts := []clickhouse.QueryOption{
+		// feed native progress packets (increments of rows and bytes read) to the call progress
+		clickhouse.WithProgress(func(p *clickhouse.Progress) {
+			core.ReportQueryProgress(ctx, core.QueryProgress{
+				RowsRead:  int64(p.Rows),
+				BytesRead: int64(p.Bytes),
+				TotalRows: int64(p.TotalRows),
+			})
+		}),
+	}
+
+	if core.WantsQueryID(ctx) {
+		// tag the query, so that it can be killed on the server
+		queryID := uuid.NewString()
+		opts = append(opts, clickhouse.WithQueryID(queryID))
+		core.ReportQueryID(ctx, queryID)
+	}
+
+	ctx = clickhouse.Context(ctx, opts...)
+
 	// run query, fallback to affected rows
 	return c.c.QueryUntilNotEmpty(ctx, query, "select changes() as 'Rows Affected'")
 }
 
+// CancelQuery kills the query with the provided query_id.
+func (c *clickhouseDriver) CancelQuery(ctx context.Context, queryID string) error {
+	return c.c.ExecOutsideTransaction(ctx, "KILL QUERY WHERE query_id = ?", queryID)
+}
+
+// clickhouseErrorPositionRegex matches the location in syntax errors ("Syntax error: failed at position 10 ('FORM'): ...").
+var clickhouseErrorPositionRegex = regexp.MustCompile(`failed at position (\d+)`)
+
+func (c *clickhouseDriver) ParseError(err error) *core.QueryError {
+	var chErr *clickhouse.Exception
+	if !errors.As(err, &chErr) {
+		return nil
+	}
+
+	qe := core.NewQueryError(err)
+	qe.Code = strconv.Itoa(int(chErr.Code))
+	qe.Message = chErr.Message
+	qe.Detail = chErr.Name
+	if match := clickhouseErrorPositionRegex.FindStringSubmatch(chErr.Message); match != nil {
+		qe.Position, _ = strconv.Atoi(match[1])
+	}
+
+	return qe
+}
+
+// Explain explains the query with EXPLAIN PLAN in json format.
+func (c *clickhouseDriver) Explain(ctx context.Context, query string, opts *core.ExplainOptions) (*core.PlanNode, error) {
+	if opts.Analyze {
+		return nil, core.ErrExplainAnalyzeNotSupported
+	}
+
+	// json output is split to rows
+	out, err := c.c.QueryText(ctx, "EXPLAIN PLAN json = 1, description = 1, indexes = 1 "+query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseClickhousePlan([]byte(out))
+}
+
+// clickhousePlan is a node of clickhouse's JSON plan.
+type clickhousePlan struct {
+	NodeType    string `json:"Node Type"`
+	Description string `json:"Description"`
+	Indexes     []struct {
+		Type             string `json:"Type"`
+		InitialParts     int64  `json:"Initial Parts"`
+		SelectedParts    int64  `json:"Selected Parts"`
+		InitialGranules  int64  `json:"Initial Granules"`
+		SelectedGranules int64  `json:"Selected Granules"`
+	} `json:"Indexes"`
+	Plans []*clickhousePlan `json:"Plans"`
+}
+
+// parseClickhousePlan converts output of EXPLAIN PLAN json = 1 to a plan tree.
+func parseClickhousePlan(data []byte) (*core.PlanNode, error) {
+	var explained []struct {
+		Plan *clickhousePlan `json:"Plan"`
+	}
+	err := json.Unmarshal(data, &explained)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	if len(explained) < 1 || explained[0].Plan == nil {
+		return nil, fmt.Errorf("no plan in explain output")
+	}
+
+	return explained[0].Plan.toNode(), nil
+}
+
+func (p *clickhousePlan) toNode() *core.PlanNode {
+	var detail []string
+	if p.Description != "" {
+		detail = append(detail, p.Description)
+	}
+	for _, index := range p.Indexes {
+		detail = append(detail, fmt.Sprintf("index %s: %d/%d parts, %d/%d granules",
+			index.Type, index.SelectedParts, index.InitialParts, index.SelectedGranules, index.InitialGranules))
+	}
+
+	node := &core.PlanNode{
+		Operation: p.NodeType,
+		Detail:    strings.Join(detail, "; "),
+	}
+
+	for _, child := range p.Plans {
+		node.Children = append(node.Children, child.toNode())
+	}
+
+	return node
+}
+
 func (c *clickhouseDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
 	return c.c.ColumnsFromQuery(`
 		SELECT name, type
@@ -67,6 +189,11 @@ func (c *clickhouseDriver) Close() {
 	c.c.Close()
 }
 
+// CurrentDatabase asks the server, since the database can be changed with a USE statement.
+func (c *clickhouseDriver) CurrentDatabase(ctx context.Context) (string, error) {
+	return c.c.QueryText(ctx, "SELECT currentDatabase()", nil, nil)
+}
+
 func (c *clickhouseDriver) ListDatabases() (current string, available []string, err error) {
 	query := `
 		SELECT
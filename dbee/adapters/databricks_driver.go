@@ -3,8 +3,13 @@ package adapters
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
@@ -13,6 +18,8 @@ import (
 var (
 	_ core.Driver           = (*databricksDriver)(nil)
 	_ core.DatabaseSwitcher = (*databricksDriver)(nil)
+	_ core.CurrentDatabaser = (*databricksDriver)(nil)
+	_ core.Estimator        = (*databricksDriver)(nil)
 )
 
 // databricksDriver is a driver for Databricks.
@@ -28,6 +35,94 @@ func (d *databricksDriver) Query(ctx context.Context, query string) (core.Result
 	return d.c.QueryUntilNotEmpty(ctx, query)
 }
 
+// Estimate returns the size of relations read by the query, based on statistics of its
+// optimized logical plan. Only statements which read or modify data are estimated.
+func (d *databricksDriver) Estimate(ctx context.Context, query string, _ *core.QueryParams) (*core.Estimate, error) {
+	if !isDataQuery(query) {
+		return nil, nil
+	}
+
+	plan, err := d.c.QueryText(ctx, "EXPLAIN COST "+query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDatabricksEstimate(plan)
+}
+
+var (
+	databricksSizeRegex     = regexp.MustCompile(`sizeInBytes=([0-9.E+]+) ?(B|KiB|MiB|GiB|TiB|PiB|EiB)`)
+	databricksRowCountRegex = regexp.MustCompile(`rowCount=([0-9.E+]+)`)
+	databricksSizeUnits     = map[string]float64{
+		"B":   1,
+		"KiB": 1 << 10,
+		"MiB": 1 << 20,
+		"GiB": 1 << 30,
+		"TiB": 1 << 40,
+		"PiB": 1 << 50,
+		"EiB": 1 << 60,
+	}
+)
+
+// parseDatabricksEstimate parses the optimized logical plan of "EXPLAIN COST" output.
+// Sizes of leaf nodes (scanned relations) are summed up and the row count is taken from the root node.
+func parseDatabricksEstimate(plan string) (*core.Estimate, error) {
+	_, optimized, ok := strings.Cut(plan, "== Optimized Logical Plan ==")
+	if !ok {
+		return nil, errors.New("no optimized logical plan in explain output")
+	}
+	optimized, _, _ = strings.Cut(optimized, "\n== ")
+
+	var lines []string
+	for _, line := range strings.Split(optimized, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	// depth of a node is the width of its tree prefix ("+- ", ":  ")
+	depth := func(line string) int {
+		return len(line) - len(strings.TrimLeft(line, " :+-"))
+	}
+
+	estimate := &core.Estimate{}
+	var bytes float64
+	leaves := 0
+	for i, line := range lines {
+		if i == 0 {
+			if match := databricksRowCountRegex.FindStringSubmatch(line); match != nil {
+				rows, _ := strconv.ParseFloat(match[1], 64)
+				estimate.Rows = int64(rows)
+			}
+		}
+
+		// a node is a leaf if the next node isn't its child
+		if i+1 < len(lines) && depth(lines[i+1]) > depth(line) {
+			continue
+		}
+		match := databricksSizeRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		size, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("strconv.ParseFloat: %w", err)
+		}
+		bytes += size * databricksSizeUnits[match[2]]
+		leaves++
+	}
+	if leaves == 0 {
+		return nil, errors.New("no statistics in explain output")
+	}
+
+	estimate.Bytes = math.MaxInt64
+	if bytes < math.MaxInt64 {
+		estimate.Bytes = int64(bytes)
+	}
+	estimate.Detail = fmt.Sprintf("%d bytes in %d relations", estimate.Bytes, leaves)
+
+	return estimate, nil
+}
+
 // Columns returns the columns and their types for the given table.
 func (d *databricksDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
 	return d.c.ColumnsFromQuery(`
@@ -73,6 +168,10 @@ func (d *databricksDriver) Close() {
 	d.c.Close()
 }
 
+func (d *databricksDriver) CurrentDatabase(context.Context) (string, error) {
+	return d.currentCatalog, nil
+}
+
 // ListDatabases returns the current catalog and a list of
 // available catalogs.
 func (d *databricksDriver) ListDatabases() (current string, available []string, err error) {
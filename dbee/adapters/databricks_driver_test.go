@@ -231,3 +231,82 @@ func Test_getDatabricksStructureType(t *testing.T) {
 		})
 	}
 }
+
+func Test_databricksDriver_Estimate(t *testing.T) {
+	plan := `== Parsed Logical Plan ==
+'Project [*]
++- 'UnresolvedRelation [orders]
+
+== Optimized Logical Plan ==
+Join Inner, (customer_id#1 = id#5), Statistics(sizeInBytes=2.5 GiB, rowCount=1.00E+6)
+:- Filter isnotnull(customer_id#1), Statistics(sizeInBytes=2.0 GiB)
+:  +- Relation main.sales.orders[id#0,customer_id#1] parquet, Statistics(sizeInBytes=2.0 GiB)
++- Relation main.sales.customers[id#5,name#6] parquet, Statistics(sizeInBytes=512.0 MiB)
+
+== Physical Plan ==
+AdaptiveSparkPlan isFinalPlan=false
+`
+
+	tests := []struct {
+		name     string
+		give     string
+		wantPlan string
+		want     *core.Estimate
+		wantErr  bool
+	}{
+		{
+			name:     "should sum sizes of scanned relations",
+			give:     "SELECT * FROM orders JOIN customers ON customer_id = customers.id",
+			wantPlan: plan,
+			want: &core.Estimate{
+				Bytes:  2<<30 + 512<<20,
+				Rows:   1000000,
+				Detail: "2684354560 bytes in 2 relations",
+			},
+		},
+		{
+			name:     "should estimate multi-line queries",
+			give:     "WITH\n\torders AS (SELECT * FROM orders)\nSELECT\n  *\nFROM orders JOIN customers ON customer_id = customers.id",
+			wantPlan: plan,
+			want: &core.Estimate{
+				Bytes:  2<<30 + 512<<20,
+				Rows:   1000000,
+				Detail: "2684354560 bytes in 2 relations",
+			},
+		},
+		{
+			name: "should skip statements which don't read data",
+			give: "USE CATALOG main",
+		},
+		{
+			name:     "should fail without optimized plan",
+			give:     "-- comment\n(SELECT 1)",
+			wantPlan: "== Physical Plan ==\nLocalTableScan",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			driver, mock := setupDatabricksTestDriver(t)
+
+			if tt.wantPlan != "" {
+				mock.ExpectQuery("EXPLAIN COST " + tt.give).
+					WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow(tt.wantPlan))
+			}
+
+			got, err := driver.Estimate(context.Background(), tt.give, nil)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
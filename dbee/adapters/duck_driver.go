@@ -11,6 +11,8 @@ import (
 var (
 	_ core.Driver           = (*duckDriver)(nil)
 	_ core.DatabaseSwitcher = (*duckDriver)(nil)
+	_ core.CurrentDatabaser = (*duckDriver)(nil)
+	_ core.Transactor       = (*duckDriver)(nil)
 )
 
 type duckDriver struct {
@@ -55,6 +57,10 @@ func getDuckDBStructureType(typ string) core.StructureType {
 	}
 }
 
+func (d *duckDriver) CurrentDatabase(context.Context) (string, error) {
+	return d.currentDB, nil
+}
+
 // ListDatabases returns the current catalog and a list of available catalogs.
 // NOTE: (phdah) As of now, swapping catalogs is not enabled and only the
 // current will be shown
@@ -72,3 +78,19 @@ func (d *duckDriver) SelectDatabase(name string) error {
 func (d *duckDriver) Close() {
 	d.c.Close()
 }
+
+func (d *duckDriver) BeginTransaction(ctx context.Context) error {
+	return d.c.BeginTransaction(ctx)
+}
+
+func (d *duckDriver) CommitTransaction() error {
+	return d.c.CommitTransaction()
+}
+
+func (d *duckDriver) RollbackTransaction() error {
+	return d.c.RollbackTransaction()
+}
+
+func (d *duckDriver) InTransaction() bool {
+	return d.c.InTransaction()
+}
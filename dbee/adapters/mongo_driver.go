@@ -17,6 +17,7 @@ import (
 var (
 	_ core.Driver           = (*mongoDriver)(nil)
 	_ core.DatabaseSwitcher = (*mongoDriver)(nil)
+	_ core.CurrentDatabaser = (*mongoDriver)(nil)
 )
 
 type mongoDriver struct {
@@ -138,6 +139,10 @@ func (c *mongoDriver) Close() {
 	_ = c.c.Disconnect(context.TODO())
 }
 
+func (c *mongoDriver) CurrentDatabase(ctx context.Context) (string, error) {
+	return c.getCurrentDatabase(ctx)
+}
+
 func (c *mongoDriver) ListDatabases() (current string, available []string, err error) {
 	ctx := context.Background()
 
@@ -37,7 +37,7 @@ func (m *MySQL) Connect(url string) (core.Driver, error) {
 	}
 
 	return &mySQLDriver{
-		c: builders.NewClient(db),
+		c: builders.NewClient(db, builders.WithSessionIDQuery("SELECT CONNECTION_ID()")),
 	}, nil
 }
 
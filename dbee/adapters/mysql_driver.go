@@ -2,12 +2,28 @@ package adapters
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
 )
 
-var _ core.Driver = (*mySQLDriver)(nil)
+var (
+	_ core.Driver       = (*mySQLDriver)(nil)
+	_ core.ParamQuerier = (*mySQLDriver)(nil)
+	_ core.Transactor   = (*mySQLDriver)(nil)
+	_ core.Canceler     = (*mySQLDriver)(nil)
+	_ core.Explainer    = (*mySQLDriver)(nil)
+	_ core.ErrorParser  = (*mySQLDriver)(nil)
+)
 
 type mySQLDriver struct {
 	c *builders.Client
@@ -18,6 +34,168 @@ func (c *mySQLDriver) Query(ctx context.Context, query string) (core.ResultStrea
 	return c.c.QueryUntilNotEmpty(ctx, query, "select ROW_COUNT() as 'Rows Affected'")
 }
 
+// QueryWithParams binds params as positional (?) bind variables.
+func (c *mySQLDriver) QueryWithParams(ctx context.Context, query string, params *core.QueryParams) (core.ResultStream, error) {
+	args, err := builders.PositionalArgs(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// run query, fallback to affected rows
+	return c.c.QueryUntilNotEmptyWithArgs(ctx, args, query, "select ROW_COUNT() as 'Rows Affected'")
+}
+
+// CancelQuery kills the query running on the connection with the provided id.
+func (c *mySQLDriver) CancelQuery(ctx context.Context, queryID string) error {
+	// KILL doesn't accept bind variables
+	id, err := strconv.ParseUint(queryID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid connection id: %q", queryID)
+	}
+
+	return c.c.ExecOutsideTransaction(ctx, fmt.Sprintf("KILL QUERY %d", id))
+}
+
+// mySQLErrorLineRegex matches the location in syntax errors ("... near 'FORM t' at line 1").
+var mySQLErrorLineRegex = regexp.MustCompile(`at line (\d+)$`)
+
+func (c *mySQLDriver) ParseError(err error) *core.QueryError {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return nil
+	}
+
+	qe := core.NewQueryError(err)
+	qe.Code = strconv.Itoa(int(myErr.Number))
+	if myErr.SQLState != [5]byte{} {
+		qe.SQLState = string(myErr.SQLState[:])
+	}
+	qe.Message = myErr.Message
+	if match := mySQLErrorLineRegex.FindStringSubmatch(myErr.Message); match != nil {
+		qe.Line, _ = strconv.Atoi(match[1])
+	}
+
+	return qe
+}
+
+// Explain explains the query with EXPLAIN FORMAT=JSON.
+func (c *mySQLDriver) Explain(ctx context.Context, query string, opts *core.ExplainOptions) (*core.PlanNode, error) {
+	// EXPLAIN ANALYZE only supports the tree format
+	if opts.Analyze {
+		return nil, core.ErrExplainAnalyzeNotSupported
+	}
+
+	out, err := c.c.QueryText(ctx, "EXPLAIN FORMAT=JSON "+query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMySQLPlan([]byte(out))
+}
+
+// parseMySQLPlan converts output of EXPLAIN FORMAT=JSON to a plan tree.
+// Every nested object of the output (query block, join, table, subquery...) becomes a node.
+func parseMySQLPlan(data []byte) (*core.PlanNode, error) {
+	var explained map[string]any
+	err := json.Unmarshal(data, &explained)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	block, ok := explained["query_block"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("no query block in explain output")
+	}
+
+	return mySQLPlanNode("query_block", block), nil
+}
+
+func mySQLPlanNode(operation string, obj map[string]any) *core.PlanNode {
+	node := &core.PlanNode{
+		Operation: strings.ReplaceAll(operation, "_", " "),
+	}
+
+	if costInfo, ok := obj["cost_info"].(map[string]any); ok {
+		// cumulative cost if present
+		for _, key := range []string{"query_cost", "prefix_cost", "sort_cost"} {
+			if cost, ok := mySQLPlanNumber(costInfo[key]); ok {
+				node.Cost = cost
+				break
+			}
+		}
+	}
+	if rows, ok := mySQLPlanNumber(obj["rows_examined_per_scan"]); ok {
+		node.Rows = rows
+	}
+
+	var detail []string
+	if table, ok := obj["table_name"].(string); ok {
+		detail = append(detail, "on "+table)
+	}
+	if access, ok := obj["access_type"].(string); ok {
+		detail = append(detail, "access: "+access)
+	}
+	if key, ok := obj["key"].(string); ok {
+		detail = append(detail, "using "+key)
+	}
+	if cond, ok := obj["attached_condition"].(string); ok {
+		detail = append(detail, "filter: "+cond)
+	}
+	node.Detail = strings.Join(detail, " ")
+
+	node.Children = mySQLPlanChildren(obj)
+
+	return node
+}
+
+// mySQLPlanChildren converts nested objects and lists of objects to nodes.
+func mySQLPlanChildren(obj map[string]any) []*core.PlanNode {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var children []*core.PlanNode
+	for _, key := range keys {
+		switch v := obj[key].(type) {
+		case map[string]any:
+			if key == "cost_info" {
+				continue
+			}
+			children = append(children, mySQLPlanNode(key, v))
+		case []any:
+			// e.g. nested loop of tables or a list of subqueries
+			list := &core.PlanNode{
+				Operation: strings.ReplaceAll(key, "_", " "),
+			}
+			for _, elem := range v {
+				if m, ok := elem.(map[string]any); ok {
+					list.Children = append(list.Children, mySQLPlanChildren(m)...)
+				}
+			}
+			if len(list.Children) > 0 {
+				children = append(children, list)
+			}
+		}
+	}
+
+	return children
+}
+
+// mySQLPlanNumber parses numbers which are reported as strings or numbers.
+func mySQLPlanNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
 func (c *mySQLDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
 	return c.c.ColumnsFromQuery("DESCRIBE `%s`.`%s`", opts.Schema, opts.Table)
 }
@@ -67,3 +245,19 @@ func (c *mySQLDriver) Structure() ([]*core.Structure, error) {
 func (c *mySQLDriver) Close() {
 	c.c.Close()
 }
+
+func (c *mySQLDriver) BeginTransaction(ctx context.Context) error {
+	return c.c.BeginTransaction(ctx)
+}
+
+func (c *mySQLDriver) CommitTransaction() error {
+	return c.c.CommitTransaction()
+}
+
+func (c *mySQLDriver) RollbackTransaction() error {
+	return c.c.RollbackTransaction()
+}
+
+func (c *mySQLDriver) InTransaction() bool {
+	return c.c.InTransaction()
+}
@@ -2,19 +2,35 @@ package adapters
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/sijms/go-ora/v2/network"
+
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
 )
 
-var _ core.Driver = (*oracleDriver)(nil)
+var (
+	_ core.Driver       = (*oracleDriver)(nil)
+	_ core.ParamQuerier = (*oracleDriver)(nil)
+	_ core.Transactor   = (*oracleDriver)(nil)
+	_ core.ErrorParser  = (*oracleDriver)(nil)
+)
 
 type oracleDriver struct {
 	c *builders.Client
 }
 
 func (d *oracleDriver) Query(ctx context.Context, query string) (core.ResultStream, error) {
+	return d.QueryWithParams(ctx, query, nil)
+}
+
+// QueryWithParams binds params as positional (:1, :2, ...) or named (:name) bind variables.
+func (d *oracleDriver) QueryWithParams(ctx context.Context, query string, params *core.QueryParams) (core.ResultStream, error) {
+	args := builders.Args(params)
+
 	// Remove the trailing semicolon from the query - for some reason it isn't supported in go_ora
 	query = strings.TrimSuffix(query, ";")
 
@@ -22,10 +38,10 @@ func (d *oracleDriver) Query(ctx context.Context, query string) (core.ResultStre
 	action := strings.ToLower(strings.Split(query, " ")[0])
 	hasReturnValues := strings.Contains(strings.ToLower(query), " returning ")
 	if (action == "update" || action == "delete" || action == "insert") && !hasReturnValues {
-		return d.c.Exec(ctx, query)
+		return d.c.Exec(ctx, query, args...)
 	}
 
-	return d.c.QueryUntilNotEmpty(ctx, query)
+	return d.c.QueryUntilNotEmptyWithArgs(ctx, args, query)
 }
 
 func (d *oracleDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
@@ -86,4 +102,35 @@ func (d *oracleDriver) Structure() ([]*core.Structure, error) {
 	return core.GetGenericStructure(rows, decodeStructureType)
 }
 
+func (d *oracleDriver) ParseError(err error) *core.QueryError {
+	var oraErr *network.OracleError
+	if !errors.As(err, &oraErr) {
+		return nil
+	}
+
+	qe := core.NewQueryError(err)
+	qe.Code = fmt.Sprintf("ORA-%05d", oraErr.ErrCode)
+	// message is prefixed with the code and might contain more lines (e.g. for errors in PL/SQL)
+	message := strings.TrimPrefix(oraErr.Error(), qe.Code+": ")
+	qe.Message, qe.Detail, _ = strings.Cut(strings.TrimSpace(message), "\n")
+
+	return qe
+}
+
 func (d *oracleDriver) Close() { d.c.Close() }
+
+func (d *oracleDriver) BeginTransaction(ctx context.Context) error {
+	return d.c.BeginTransaction(ctx)
+}
+
+func (d *oracleDriver) CommitTransaction() error {
+	return d.c.CommitTransaction()
+}
+
+func (d *oracleDriver) RollbackTransaction() error {
+	return d.c.RollbackTransaction()
+}
+
+func (d *oracleDriver) InTransaction() bool {
+	return d.c.InTransaction()
+}
@@ -48,6 +48,7 @@ func (p *Postgres) Connect(url string) (core.Driver, error) {
 		c: builders.NewClient(db,
 			builders.WithCustomTypeProcessor("json", jsonProcessor),
 			builders.WithCustomTypeProcessor("jsonb", jsonProcessor),
+			builders.WithSessionIDQuery("SELECT pg_backend_pid()"),
 		),
 		url: u,
 	}, nil
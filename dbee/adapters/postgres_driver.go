@@ -6,9 +6,14 @@ import (
 	"database/sql"
 	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"fmt"
 	nurl "net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
@@ -17,6 +22,12 @@ import (
 var (
 	_ core.Driver           = (*postgresDriver)(nil)
 	_ core.DatabaseSwitcher = (*postgresDriver)(nil)
+	_ core.CurrentDatabaser = (*postgresDriver)(nil)
+	_ core.ParamQuerier     = (*postgresDriver)(nil)
+	_ core.Transactor       = (*postgresDriver)(nil)
+	_ core.Canceler         = (*postgresDriver)(nil)
+	_ core.Explainer        = (*postgresDriver)(nil)
+	_ core.ErrorParser      = (*postgresDriver)(nil)
 )
 
 type postgresDriver struct {
@@ -25,14 +36,24 @@ type postgresDriver struct {
 }
 
 func (c *postgresDriver) Query(ctx context.Context, query string) (core.ResultStream, error) {
+	return c.QueryWithParams(ctx, query, nil)
+}
+
+// QueryWithParams binds params as positional ($1, $2, ...) bind variables.
+func (c *postgresDriver) QueryWithParams(ctx context.Context, query string, params *core.QueryParams) (core.ResultStream, error) {
+	args, err := builders.PositionalArgs(params)
+	if err != nil {
+		return nil, err
+	}
+
 	action := strings.ToLower(strings.Split(query, " ")[0])
 	hasReturnValues := strings.Contains(strings.ToLower(query), " returning ")
 
 	if (action == "update" || action == "delete" || action == "insert") && !hasReturnValues {
-		return c.c.Exec(ctx, query)
+		return c.c.Exec(ctx, query, args...)
 	}
 
-	return c.c.QueryUntilNotEmpty(ctx, query)
+	return c.c.QueryUntilNotEmptyWithArgs(ctx, args, query)
 }
 
 func (c *postgresDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
@@ -63,6 +84,15 @@ func (c *postgresDriver) Close() {
 	c.c.Close()
 }
 
+// CurrentDatabase returns the database of the connection URL, since switching databases reconnects.
+// The server is only asked if the URL doesn't name a database.
+func (c *postgresDriver) CurrentDatabase(ctx context.Context) (string, error) {
+	if name := strings.TrimPrefix(c.url.Path, "/"); name != "" {
+		return name, nil
+	}
+	return c.c.QueryText(ctx, "SELECT current_database()", nil, nil)
+}
+
 func (c *postgresDriver) ListDatabases() (current string, available []string, err error) {
 	query := `
 		SELECT current_database(), datname FROM pg_database
@@ -108,6 +138,140 @@ func (c *postgresDriver) SelectDatabase(name string) error {
 	return nil
 }
 
+// CancelQuery cancels the query running on the backend with the provided pid.
+func (c *postgresDriver) CancelQuery(ctx context.Context, queryID string) error {
+	return c.c.ExecOutsideTransaction(ctx, "SELECT pg_cancel_backend($1)", queryID)
+}
+
+func (c *postgresDriver) ParseError(err error) *core.QueryError {
+	return parsePostgresError(err)
+}
+
+// parsePostgresError converts errors reported by the server (also used by redshift).
+func parsePostgresError(err error) *core.QueryError {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return nil
+	}
+
+	qe := core.NewQueryError(err)
+	qe.Severity = pqErr.Severity
+	qe.SQLState = string(pqErr.Code)
+	qe.Code = pqErr.Code.Name()
+	qe.Message = pqErr.Message
+	qe.Detail = pqErr.Detail
+	qe.Hint = pqErr.Hint
+	// position is only reported for errors in the query itself
+	qe.Position, _ = strconv.Atoi(pqErr.Position)
+
+	return qe
+}
+
+// Explain explains the query with EXPLAIN (FORMAT JSON).
+// Analyze executes the query in a transaction which is rolled back, so that explaining
+// statements which modify data doesn't change it.
+func (c *postgresDriver) Explain(ctx context.Context, query string, opts *core.ExplainOptions) (*core.PlanNode, error) {
+	var out string
+	var err error
+	if opts.Analyze {
+		out, err = c.c.QueryTextRolledBack(ctx, "EXPLAIN (ANALYZE, FORMAT JSON) "+query)
+	} else {
+		out, err = c.c.QueryText(ctx, "EXPLAIN (FORMAT JSON) "+query, nil, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePostgresPlan([]byte(out))
+}
+
+// postgresPlan is a node of postgres' JSON plan.
+type postgresPlan struct {
+	NodeType        string          `json:"Node Type"`
+	RelationName    string          `json:"Relation Name"`
+	Alias           string          `json:"Alias"`
+	IndexName       string          `json:"Index Name"`
+	JoinType        string          `json:"Join Type"`
+	IndexCond       string          `json:"Index Cond"`
+	HashCond        string          `json:"Hash Cond"`
+	MergeCond       string          `json:"Merge Cond"`
+	JoinFilter      string          `json:"Join Filter"`
+	Filter          string          `json:"Filter"`
+	SortKey         []string        `json:"Sort Key"`
+	GroupKey        []string        `json:"Group Key"`
+	TotalCost       float64         `json:"Total Cost"`
+	PlanRows        float64         `json:"Plan Rows"`
+	ActualTotalTime float64         `json:"Actual Total Time"`
+	ActualRows      float64         `json:"Actual Rows"`
+	ActualLoops     float64         `json:"Actual Loops"`
+	Plans           []*postgresPlan `json:"Plans"`
+}
+
+// parsePostgresPlan converts output of EXPLAIN (FORMAT JSON) to a plan tree.
+func parsePostgresPlan(data []byte) (*core.PlanNode, error) {
+	var explained []struct {
+		Plan *postgresPlan `json:"Plan"`
+	}
+	err := json.Unmarshal(data, &explained)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	if len(explained) < 1 || explained[0].Plan == nil {
+		return nil, fmt.Errorf("no plan in explain output")
+	}
+
+	return explained[0].Plan.toNode(), nil
+}
+
+func (p *postgresPlan) toNode() *core.PlanNode {
+	var detail []string
+	if p.JoinType != "" {
+		detail = append(detail, p.JoinType)
+	}
+	if p.IndexName != "" {
+		detail = append(detail, "using "+p.IndexName)
+	}
+	if p.RelationName != "" {
+		on := "on " + p.RelationName
+		if p.Alias != "" && p.Alias != p.RelationName {
+			on += " " + p.Alias
+		}
+		detail = append(detail, on)
+	}
+	for _, cond := range []string{p.IndexCond, p.HashCond, p.MergeCond, p.JoinFilter} {
+		if cond != "" {
+			detail = append(detail, "cond: "+cond)
+		}
+	}
+	if p.Filter != "" {
+		detail = append(detail, "filter: "+p.Filter)
+	}
+	if len(p.SortKey) > 0 {
+		detail = append(detail, "key: "+strings.Join(p.SortKey, ", "))
+	}
+	if len(p.GroupKey) > 0 {
+		detail = append(detail, "group: "+strings.Join(p.GroupKey, ", "))
+	}
+
+	// actual values are per loop
+	loops := max(p.ActualLoops, 1)
+
+	node := &core.PlanNode{
+		Operation:  p.NodeType,
+		Detail:     strings.Join(detail, " "),
+		Cost:       p.TotalCost,
+		Rows:       p.PlanRows,
+		ActualRows: p.ActualRows * loops,
+		Time:       time.Duration(p.ActualTotalTime * loops * float64(time.Millisecond)),
+	}
+
+	for _, child := range p.Plans {
+		node.Children = append(node.Children, child.toNode())
+	}
+
+	return node
+}
+
 // getPGStructureType returns the structure type based on the provided string.
 func getPGStructureType(typ string) core.StructureType {
 	switch typ {
@@ -176,3 +340,19 @@ func (pj *postgresJSONResponse) GobDecode(buf []byte) error {
 	}
 	return err
 }
+
+func (c *postgresDriver) BeginTransaction(ctx context.Context) error {
+	return c.c.BeginTransaction(ctx)
+}
+
+func (c *postgresDriver) CommitTransaction() error {
+	return c.c.CommitTransaction()
+}
+
+func (c *postgresDriver) RollbackTransaction() error {
+	return c.c.RollbackTransaction()
+}
+
+func (c *postgresDriver) InTransaction() bool {
+	return c.c.InTransaction()
+}
@@ -0,0 +1,101 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+func Test_parsePostgresPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *core.PlanNode
+		wantErr bool
+	}{
+		{
+			name: "should parse nested plan",
+			input: `[{"Plan": {
+				"Node Type": "Hash Join", "Join Type": "Inner", "Hash Cond": "(o.user_id = u.id)",
+				"Total Cost": 35.5, "Plan Rows": 1000,
+				"Plans": [
+					{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Total Cost": 20, "Plan Rows": 1000},
+					{"Node Type": "Hash", "Total Cost": 10.5, "Plan Rows": 100, "Plans": [
+						{"Node Type": "Index Scan", "Relation Name": "users", "Alias": "users", "Index Name": "users_pkey", "Total Cost": 10.5, "Plan Rows": 100}
+					]}
+				]
+			}}]`,
+			want: &core.PlanNode{
+				Operation: "Hash Join",
+				Detail:    "Inner cond: (o.user_id = u.id)",
+				Cost:      35.5,
+				Rows:      1000,
+				Children: []*core.PlanNode{
+					{Operation: "Seq Scan", Detail: "on orders o", Cost: 20, Rows: 1000},
+					{Operation: "Hash", Cost: 10.5, Rows: 100, Children: []*core.PlanNode{
+						{Operation: "Index Scan", Detail: "using users_pkey on users", Cost: 10.5, Rows: 100},
+					}},
+				},
+			},
+		},
+		{
+			name: "should multiply actual values by loops",
+			input: `[{"Plan": {
+				"Node Type": "Index Scan", "Relation Name": "users", "Total Cost": 8.3, "Plan Rows": 1,
+				"Actual Total Time": 0.5, "Actual Rows": 2, "Actual Loops": 3
+			}, "Execution Time": 2.1}]`,
+			want: &core.PlanNode{
+				Operation:  "Index Scan",
+				Detail:     "on users",
+				Cost:       8.3,
+				Rows:       1,
+				ActualRows: 6,
+				Time:       1500 * time.Microsecond,
+			},
+		},
+		{
+			name:    "should fail on empty output",
+			input:   `[]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePostgresPlan([]byte(tt.input))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parsePostgresError(t *testing.T) {
+	pqErr := &pq.Error{
+		Severity: "ERROR",
+		Code:     "42P01",
+		Message:  `relation "missing" does not exist`,
+		Position: "15",
+	}
+
+	got := parsePostgresError(fmt.Errorf("rows.Next: %w", pqErr))
+	require.NotNil(t, got)
+	assert.Equal(t, "ERROR", got.Severity)
+	assert.Equal(t, "42P01", got.SQLState)
+	assert.Equal(t, "undefined_table", got.Code)
+	assert.Equal(t, pqErr.Message, got.Message)
+	assert.Equal(t, 15, got.Position)
+	assert.ErrorIs(t, got, pqErr)
+
+	assert.Nil(t, parsePostgresError(errors.New("connection refused")))
+}
@@ -40,7 +40,7 @@ func (r *Redshift) Connect(rawURL string) (core.Driver, error) {
 	}
 
 	return &redshiftDriver{
-		c:             builders.NewClient(db),
+		c:             builders.NewClient(db, builders.WithSessionIDQuery("SELECT pg_backend_pid()")),
 		connectionURL: connURL,
 	}, nil
 }
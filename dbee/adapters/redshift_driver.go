@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -16,6 +17,11 @@ import (
 var (
 	_ core.Driver           = (*redshiftDriver)(nil)
 	_ core.DatabaseSwitcher = (*redshiftDriver)(nil)
+	_ core.CurrentDatabaser = (*redshiftDriver)(nil)
+	_ core.ParamQuerier     = (*redshiftDriver)(nil)
+	_ core.Transactor       = (*redshiftDriver)(nil)
+	_ core.Canceler         = (*redshiftDriver)(nil)
+	_ core.ErrorParser      = (*redshiftDriver)(nil)
 )
 
 // redshiftDriver is a sql client for redshiftDriver.
@@ -31,6 +37,16 @@ func (r *redshiftDriver) Query(ctx context.Context, query string) (core.ResultSt
 	return r.c.QueryUntilNotEmpty(ctx, query)
 }
 
+// QueryWithParams binds params as positional ($1, $2, ...) bind variables.
+func (r *redshiftDriver) QueryWithParams(ctx context.Context, query string, params *core.QueryParams) (core.ResultStream, error) {
+	args, err := builders.PositionalArgs(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.c.QueryUntilNotEmptyWithArgs(ctx, args, query)
+}
+
 // Close closes the underlying sql.DB connection.
 func (r *redshiftDriver) Close() {
 	r.c.Close()
@@ -74,6 +90,15 @@ func (r *redshiftDriver) Structure() ([]*core.Structure, error) {
 	return core.GetGenericStructure(rows, getPGStructureType)
 }
 
+// CurrentDatabase returns the database of the connection URL, since switching databases reconnects.
+// The server is only asked if the URL doesn't name a database.
+func (r *redshiftDriver) CurrentDatabase(ctx context.Context) (string, error) {
+	if name := strings.TrimPrefix(r.connectionURL.Path, "/"); name != "" {
+		return name, nil
+	}
+	return r.c.QueryText(ctx, "SELECT current_database()", nil, nil)
+}
+
 func (r *redshiftDriver) ListDatabases() (current string, available []string, err error) {
 	query := `
 		SELECT current_database() AS current, datname
@@ -116,3 +141,28 @@ func (r *redshiftDriver) SelectDatabase(name string) error {
 	r.c.Swap(db)
 	return nil
 }
+
+// CancelQuery cancels the query running on the backend with the provided pid.
+func (r *redshiftDriver) CancelQuery(ctx context.Context, queryID string) error {
+	return r.c.ExecOutsideTransaction(ctx, "SELECT pg_cancel_backend($1)", queryID)
+}
+
+func (r *redshiftDriver) ParseError(err error) *core.QueryError {
+	return parsePostgresError(err)
+}
+
+func (r *redshiftDriver) BeginTransaction(ctx context.Context) error {
+	return r.c.BeginTransaction(ctx)
+}
+
+func (r *redshiftDriver) CommitTransaction() error {
+	return r.c.CommitTransaction()
+}
+
+func (r *redshiftDriver) RollbackTransaction() error {
+	return r.c.RollbackTransaction()
+}
+
+func (r *redshiftDriver) InTransaction() bool {
+	return r.c.InTransaction()
+}
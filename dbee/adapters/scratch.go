@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
+)
+
+// ScratchType is the connection type of the scratch engine.
+const ScratchType = "scratch"
+
+var _ core.Adapter = (*Scratch)(nil)
+
+// Scratch is a pseudo adapter, which runs SQL over results of previous calls.
+// Every result is a table named after its call (see ScratchTableName), which is loaded
+// to an in-memory sqlite database the first time a query references it.
+//
+// The adapter is not registered by default, because it needs access to calls.
+// Register it with Mux.AddAdapter and report deleted calls with ForgetCalls.
+type Scratch struct {
+	calls func() []*core.Call
+
+	// drivers of open connections, whose tables of deleted calls are dropped
+	drivers      map[*scratchDriver]struct{}
+	driversMutex sync.Mutex
+}
+
+// NewScratch returns the scratch adapter, calls provides all calls whose results can be queried.
+func NewScratch(calls func() []*core.Call) *Scratch {
+	return &Scratch{
+		calls:   calls,
+		drivers: make(map[*scratchDriver]struct{}),
+	}
+}
+
+// ForgetCalls drops tables of results of the calls from databases of all open connections,
+// so that results of deleted calls don't stay in memory.
+func (s *Scratch) ForgetCalls(ids ...core.CallID) error {
+	if len(ids) < 1 {
+		return nil
+	}
+
+	s.driversMutex.Lock()
+	drivers := make([]*scratchDriver, 0, len(s.drivers))
+	for d := range s.drivers {
+		drivers = append(drivers, d)
+	}
+	s.driversMutex.Unlock()
+
+	var errs []error
+	for _, d := range drivers {
+		errs = append(errs, d.dropTables(ids))
+	}
+	return errors.Join(errs...)
+}
+
+// ScratchTableName returns the name of the table with the result set of the call,
+// which is "call_<first 8 characters of the id>" with the result set index appended
+// for any but the first result set.
+func ScratchTableName(id core.CallID, resultSet int) string {
+	short := strings.ReplaceAll(string(id), "-", "")
+	short = strings.ToLower(short[:min(8, len(short))])
+
+	name := "call_" + short
+	if resultSet > 0 {
+		name += fmt.Sprintf("_%d", resultSet)
+	}
+	return name
+}
+
+// Connect opens a new scratch database, url is ignored.
+// The sqlite driver is registered on supported platforms only, so connecting fails on the rest.
+func (s *Scratch) Connect(_ string) (core.Driver, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("unable to open scratch database: %v", err)
+	}
+	// every connection of the pool would have its own in-memory database
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	d := &scratchDriver{
+		c:      builders.NewClient(db),
+		db:     db,
+		calls:  s.calls,
+		loaded: make(map[string]core.CallID),
+	}
+	d.onClose = func() {
+		s.driversMutex.Lock()
+		delete(s.drivers, d)
+		s.driversMutex.Unlock()
+	}
+
+	s.driversMutex.Lock()
+	s.drivers[d] = struct{}{}
+	s.driversMutex.Unlock()
+
+	return d, nil
+}
+
+func (*Scratch) GetHelpers(opts *core.TableOptions) map[string]string {
+	return map[string]string{
+		"List":    fmt.Sprintf("SELECT * FROM %q LIMIT 500", opts.Table),
+		"Columns": fmt.Sprintf("PRAGMA table_info('%s')", opts.Table),
+		"Count":   fmt.Sprintf("SELECT COUNT(*) AS count FROM %q", opts.Table),
+	}
+}
@@ -0,0 +1,293 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
+)
+
+var _ core.Driver = (*scratchDriver)(nil)
+
+// scratchBatchSize is the number of rows read from a result and inserted to its table at once.
+const scratchBatchSize = 500
+
+// scratchTableRegex matches table names of call results in queries.
+var scratchTableRegex = regexp.MustCompile(`(?i)\bcall_[0-9a-f]{8}(?:_[0-9]+)?\b`)
+
+type scratchDriver struct {
+	c     *builders.Client
+	db    *sql.DB
+	calls func() []*core.Call
+
+	// tables already loaded to the database, with calls whose results they hold
+	loaded      map[string]core.CallID
+	loadedMutex sync.Mutex
+	// onClose stops the adapter from tracking the driver
+	onClose func()
+}
+
+// scratchTable is a result set of a call which can be queried.
+type scratchTable struct {
+	call      *core.Call
+	resultSet int
+}
+
+func (d *scratchDriver) Query(ctx context.Context, query string) (core.ResultStream, error) {
+	err := d.loadTables(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// run query, fallback to affected rows
+	return d.c.QueryUntilNotEmpty(ctx, query, "select changes() as 'Rows Affected'")
+}
+
+func (d *scratchDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
+	table, ok := d.tables()[opts.Table]
+	if !ok {
+		return nil, fmt.Errorf("unknown table: %q", opts.Table)
+	}
+
+	result, err := table.call.GetResultSet(table.resultSet)
+	if err != nil {
+		return nil, fmt.Errorf("call.GetResultSet: %w", err)
+	}
+
+	columns := make([]*core.Column, 0, len(result.Header()))
+	for _, name := range scratchColumns(result.Header()) {
+		columns = append(columns, &core.Column{Name: name})
+	}
+	return columns, nil
+}
+
+// Structure lists tables of all results that can be queried, whether they are loaded or not.
+func (d *scratchDriver) Structure() ([]*core.Structure, error) {
+	tables := d.tables()
+
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	children := make([]*core.Structure, 0, len(names))
+	for _, name := range names {
+		children = append(children, &core.Structure{
+			Name:   name,
+			Schema: "main",
+			Type:   core.StructureTypeTable,
+		})
+	}
+
+	return []*core.Structure{
+		{
+			Name:     "main",
+			Schema:   "main",
+			Type:     core.StructureTypeSchema,
+			Children: children,
+		},
+	}, nil
+}
+
+func (d *scratchDriver) Close() {
+	if d.onClose != nil {
+		d.onClose()
+	}
+	d.c.Close()
+}
+
+// tables returns finished calls with results by names of their tables.
+func (d *scratchDriver) tables() map[string]scratchTable {
+	tables := make(map[string]scratchTable)
+	for _, call := range d.calls() {
+		switch call.GetState() {
+		case core.CallStateArchived, core.CallStateArchiveFailed, core.CallStateTruncated:
+		default:
+			continue
+		}
+
+		for i := range call.GetResultSetCount() {
+			tables[ScratchTableName(call.GetID(), i)] = scratchTable{call: call, resultSet: i}
+		}
+	}
+
+	return tables
+}
+
+// loadTables loads results of calls referenced in the query to the database.
+// Tables of calls which were deleted (or replaced by other calls with the same table name)
+// are dropped first. Unknown tables are left for the database to report.
+func (d *scratchDriver) loadTables(ctx context.Context, query string) error {
+	names := scratchTableRegex.FindAllString(query, -1)
+	if len(names) < 1 {
+		return nil
+	}
+
+	tables := d.tables()
+
+	d.loadedMutex.Lock()
+	defer d.loadedMutex.Unlock()
+
+	for _, name := range names {
+		name = strings.ToLower(name)
+		table, ok := tables[name]
+
+		if callID, loaded := d.loaded[name]; loaded {
+			if ok && table.call.GetID() == callID {
+				continue
+			}
+			err := d.dropTable(ctx, name)
+			if err != nil {
+				return err
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		err := d.loadTable(ctx, name, table)
+		if err != nil {
+			return fmt.Errorf("failed loading table %q: %w", name, err)
+		}
+		d.loaded[name] = table.call.GetID()
+	}
+
+	return nil
+}
+
+// dropTables drops loaded tables of results of the calls.
+func (d *scratchDriver) dropTables(ids []core.CallID) error {
+	d.loadedMutex.Lock()
+	defer d.loadedMutex.Unlock()
+
+	var errs []error
+	for name, callID := range d.loaded {
+		if slices.Contains(ids, callID) {
+			errs = append(errs, d.dropTable(context.Background(), name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dropTable drops the loaded table. It expects loadedMutex to be held.
+func (d *scratchDriver) dropTable(ctx context.Context, name string) error {
+	_, err := d.db.ExecContext(ctx, "DROP TABLE IF EXISTS "+scratchQuote(name))
+	if err != nil {
+		return fmt.Errorf("failed dropping table %q: %w", name, err)
+	}
+	delete(d.loaded, name)
+	return nil
+}
+
+// loadTable creates the table and inserts all rows of the result in a single transaction.
+func (d *scratchDriver) loadTable(ctx context.Context, name string, table scratchTable) error {
+	result, err := table.call.GetResultSet(table.resultSet)
+	if err != nil {
+		return fmt.Errorf("call.GetResultSet: %w", err)
+	}
+
+	columns := scratchColumns(result.Header())
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = scratchQuote(column)
+		placeholders[i] = "?"
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("d.db.BeginTx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", scratchQuote(name), strings.Join(quoted, ", ")))
+	if err != nil {
+		return fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)", scratchQuote(name), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return fmt.Errorf("tx.PrepareContext: %w", err)
+	}
+	defer stmt.Close()
+
+	args := make([]any, len(columns))
+	for from := 0; ; from += scratchBatchSize {
+		rows, err := result.Rows(from, from+scratchBatchSize)
+		if err != nil {
+			return fmt.Errorf("result.Rows: %w", err)
+		}
+		if len(rows) < 1 {
+			break
+		}
+
+		for _, row := range rows {
+			for i := range args {
+				args[i] = nil
+				if i < len(row) {
+					args[i] = scratchValue(row[i])
+				}
+			}
+
+			_, err = stmt.ExecContext(ctx, args...)
+			if err != nil {
+				return fmt.Errorf("stmt.ExecContext: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// scratchColumns returns column names of the header, with duplicates suffixed with their
+// occurrence (e.g. "id", "id_2"), since table columns have to be unique.
+func scratchColumns(header core.Header) []string {
+	seen := make(map[string]int, len(header))
+	columns := make([]string, len(header))
+	for i, name := range header {
+		if name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		seen[strings.ToLower(name)]++
+		if n := seen[strings.ToLower(name)]; n > 1 {
+			name = fmt.Sprintf("%s_%d", name, n)
+		}
+		columns[i] = name
+	}
+	return columns
+}
+
+// scratchQuote quotes an identifier.
+func scratchQuote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// scratchValue converts a value of a result to a value sqlite can store,
+// values of other types are stored as text.
+func scratchValue(value any) any {
+	switch v := value.(type) {
+	case nil, bool, string, []byte,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32,
+		float32, float64:
+		return v
+	case uint64:
+		if v > 1<<63-1 {
+			return fmt.Sprint(v)
+		}
+		return int64(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
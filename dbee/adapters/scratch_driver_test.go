@@ -0,0 +1,118 @@
+//go:build (darwin && (amd64 || arm64)) || (freebsd && (386 || amd64 || arm || arm64)) || (linux && (386 || amd64 || arm || arm64 || ppc64le || riscv64 || s390x)) || (netbsd && amd64) || (openbsd && (amd64 || arm64)) || (windows && (amd64 || arm64))
+
+package adapters
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
+)
+
+// waitCall waits for the call to finish and returns rows of its result.
+func waitCall(t *testing.T, call *core.Call) ([]core.Row, error) {
+	t.Helper()
+
+	select {
+	case <-call.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not finish in expected time")
+	}
+	if err := call.Err(); err != nil {
+		return nil, err
+	}
+
+	result, err := call.GetResult()
+	require.NoError(t, err)
+	return result.Rows(0, -1)
+}
+
+func TestScratch_Query(t *testing.T) {
+	r := require.New(t)
+
+	core.SetArchiveDir(t.TempDir())
+
+	// call whose result is queried
+	source, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(
+		[]core.Row{{1, "a"}, {2, "b"}, {3, nil}},
+		mock.AdapterWithResultStreamOpts(mock.ResultStreamWithHeader(core.Header{"id", "name"})),
+	))
+	r.NoError(err)
+	r.NoError(source.Connect())
+	call := source.Execute("_", nil)
+	_, err = waitCall(t, call)
+	r.NoError(err)
+	// wait a bit for state to stabilize
+	time.Sleep(100 * time.Millisecond)
+
+	var callsMutex sync.Mutex
+	calls := []*core.Call{call}
+	setCalls := func(c ...*core.Call) {
+		callsMutex.Lock()
+		defer callsMutex.Unlock()
+		calls = c
+	}
+
+	scratch := NewScratch(func() []*core.Call {
+		callsMutex.Lock()
+		defer callsMutex.Unlock()
+		return calls
+	})
+	connection, err := core.NewConnection(&core.ConnectionParams{Type: ScratchType}, scratch)
+	r.NoError(err)
+	r.NoError(connection.Connect())
+	defer connection.Close()
+
+	table := ScratchTableName(call.GetID(), 0)
+	query := func(query string) ([]core.Row, error) {
+		return waitCall(t, connection.Execute(query, nil))
+	}
+
+	// the table is loaded the first time it's referenced
+	rows, err := query("SELECT name FROM " + table + " WHERE id >= 2 ORDER BY id")
+	r.NoError(err)
+	r.Equal([]core.Row{{"b"}, {nil}}, rows)
+
+	rows, err = query("SELECT COUNT(*) FROM " + table)
+	r.NoError(err)
+	r.Equal([]core.Row{{int64(3)}}, rows)
+
+	// tables of forgotten calls are dropped
+	r.NoError(scratch.ForgetCalls(call.GetID()))
+	scratchDriverOf(t, scratch).loadedMutex.Lock()
+	r.Empty(scratchDriverOf(t, scratch).loaded)
+	scratchDriverOf(t, scratch).loadedMutex.Unlock()
+
+	// and loaded again while the call exists
+	_, err = query("SELECT * FROM " + table)
+	r.NoError(err)
+
+	// tables of calls which are gone are dropped once they are referenced
+	setCalls()
+	_, err = query("SELECT * FROM " + table)
+	r.Error(err)
+
+	// closed connections are not tracked anymore
+	connection.Close()
+	scratch.driversMutex.Lock()
+	r.Empty(scratch.drivers)
+	scratch.driversMutex.Unlock()
+}
+
+// scratchDriverOf returns the only open driver of the adapter.
+func scratchDriverOf(t *testing.T, scratch *Scratch) *scratchDriver {
+	t.Helper()
+
+	scratch.driversMutex.Lock()
+	defer scratch.driversMutex.Unlock()
+
+	require.Len(t, scratch.drivers, 1)
+	for d := range scratch.drivers {
+		return d
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+func TestScratchTableName(t *testing.T) {
+	id := core.CallID("0A1B2C3D-4e5f-6789-abcd-ef0123456789")
+
+	assert.Equal(t, "call_0a1b2c3d", ScratchTableName(id, 0))
+	assert.Equal(t, "call_0a1b2c3d_2", ScratchTableName(id, 2))
+	assert.Equal(t, "call_0a1b", ScratchTableName("0a1b", 0))
+
+	query := `SELECT * FROM call_0a1b2c3d a JOIN "CALL_0A1B2C3D_2" b ON a.id = b.id WHERE note = 'recall_x'`
+	assert.Equal(t, []string{"call_0a1b2c3d", "CALL_0A1B2C3D_2"}, scratchTableRegex.FindAllString(query, -1))
+}
+
+func Test_scratchColumns(t *testing.T) {
+	assert.Equal(t,
+		[]string{"id", "name", "ID_2", "column_4", "id_3"},
+		scratchColumns(core.Header{"id", "name", "ID", "", "id"}),
+	)
+}
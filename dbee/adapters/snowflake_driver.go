@@ -5,10 +5,14 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"database/sql"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
@@ -25,6 +29,11 @@ type snowflakeDriver struct {
 var (
 	_ core.Driver           = (*snowflakeDriver)(nil)
 	_ core.DatabaseSwitcher = (*snowflakeDriver)(nil)
+	_ core.CurrentDatabaser = (*snowflakeDriver)(nil)
+	_ core.Transactor       = (*snowflakeDriver)(nil)
+	_ core.Canceler         = (*snowflakeDriver)(nil)
+	_ core.Estimator        = (*snowflakeDriver)(nil)
+	_ core.ErrorParser      = (*snowflakeDriver)(nil)
 )
 
 func newSnowflakeDriver(dsn string, params url.Values) (*snowflakeDriver, error) {
@@ -164,9 +173,103 @@ func loadPrivateKey(path, passphrase string) (*rsa.PrivateKey, error) {
 }
 
 func (d *snowflakeDriver) Query(ctx context.Context, query string) (core.ResultStream, error) {
+	// allow batches with any number of statements - each of them produces its own result set
+	ctx, err := gosnowflake.WithMultiStatement(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("gosnowflake.WithMultiStatement: %w", err)
+	}
+
+	if core.WantsQueryID(ctx) {
+		// query id is sent by the driver once the query is submitted
+		queryIDs := make(chan string, 1)
+		go func() {
+			select {
+			case id, ok := <-queryIDs:
+				if ok {
+					core.ReportQueryID(ctx, id)
+				}
+			case <-ctx.Done():
+			}
+		}()
+		ctx = gosnowflake.WithQueryIDChan(ctx, queryIDs)
+	}
+
 	return d.c.Query(ctx, query)
 }
 
+// CancelQuery cancels the query with the provided query id.
+func (d *snowflakeDriver) CancelQuery(ctx context.Context, queryID string) error {
+	return d.c.ExecOutsideTransaction(ctx, "SELECT SYSTEM$CANCEL_QUERY(?)", queryID)
+}
+
+// snowflakeErrorLocationRegex matches the location in compilation errors
+// ("syntax error line 1 at position 9 unexpected 'FORM'."), position is 0-based.
+var snowflakeErrorLocationRegex = regexp.MustCompile(`line (\d+) at position (\d+)`)
+
+func (d *snowflakeDriver) ParseError(err error) *core.QueryError {
+	var sfErr *gosnowflake.SnowflakeError
+	if !errors.As(err, &sfErr) {
+		return nil
+	}
+
+	message := sfErr.Message
+	if len(sfErr.MessageArgs) > 0 {
+		message = fmt.Sprintf(sfErr.Message, sfErr.MessageArgs...)
+	}
+
+	qe := core.NewQueryError(err)
+	qe.Code = fmt.Sprintf("%06d", sfErr.Number)
+	qe.SQLState = sfErr.SQLState
+	// first line is often just a summary (e.g. "SQL compilation error:")
+	qe.Message = strings.ReplaceAll(strings.TrimSpace(message), "\n", " ")
+	if sfErr.QueryID != "" {
+		qe.Detail = "query id: " + sfErr.QueryID
+	}
+	if match := snowflakeErrorLocationRegex.FindStringSubmatch(message); match != nil {
+		qe.Line, _ = strconv.Atoi(match[1])
+		column, _ := strconv.Atoi(match[2])
+		qe.Column = column + 1
+	}
+
+	return qe
+}
+
+// Estimate returns the number of bytes assigned to the query by the compiled plan.
+// Only statements which read or modify data are estimated.
+func (d *snowflakeDriver) Estimate(ctx context.Context, query string, _ *core.QueryParams) (*core.Estimate, error) {
+	if !isDataQuery(query) {
+		return nil, nil
+	}
+
+	plan, err := d.c.QueryText(ctx, "EXPLAIN USING JSON "+query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSnowflakeEstimate([]byte(plan))
+}
+
+// parseSnowflakeEstimate parses global stats of a plan returned by "EXPLAIN USING JSON".
+func parseSnowflakeEstimate(plan []byte) (*core.Estimate, error) {
+	var explain struct {
+		GlobalStats struct {
+			PartitionsTotal    int64 `json:"partitionsTotal"`
+			PartitionsAssigned int64 `json:"partitionsAssigned"`
+			BytesAssigned      int64 `json:"bytesAssigned"`
+		} `json:"GlobalStats"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	stats := explain.GlobalStats
+	return &core.Estimate{
+		Bytes: stats.BytesAssigned,
+		Detail: fmt.Sprintf("%d bytes in %d of %d partitions assigned",
+			stats.BytesAssigned, stats.PartitionsAssigned, stats.PartitionsTotal),
+	}, nil
+}
+
 func (d *snowflakeDriver) Structure() ([]*core.Structure, error) {
 	// Use SHOW OBJECTS to avoid waking warehouse
 	query := `SHOW TERSE OBJECTS`
@@ -265,6 +368,11 @@ func (d *snowflakeDriver) SelectDatabase(name string) error {
 	return nil
 }
 
+// CurrentDatabase asks the server, since the database can be changed with a USE statement.
+func (d *snowflakeDriver) CurrentDatabase(ctx context.Context) (string, error) {
+	return d.c.QueryText(ctx, "SELECT CURRENT_DATABASE()", nil, nil)
+}
+
 func (d *snowflakeDriver) ListDatabases() (current string, available []string, err error) {
 	// Get current database
 	result, err := d.c.Query(context.Background(), "SELECT CURRENT_DATABASE()")
@@ -309,3 +417,19 @@ func (d *snowflakeDriver) ListDatabases() (current string, available []string, e
 func (d *snowflakeDriver) Close() {
 	d.c.Close()
 }
+
+func (d *snowflakeDriver) BeginTransaction(ctx context.Context) error {
+	return d.c.BeginTransaction(ctx)
+}
+
+func (d *snowflakeDriver) CommitTransaction() error {
+	return d.c.CommitTransaction()
+}
+
+func (d *snowflakeDriver) RollbackTransaction() error {
+	return d.c.RollbackTransaction()
+}
+
+func (d *snowflakeDriver) InTransaction() bool {
+	return d.c.InTransaction()
+}
@@ -1,9 +1,12 @@
 package adapters
 
 import (
+	"errors"
 	"net/url"
 	"testing"
 
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/snowflakedb/gosnowflake"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,7 +38,7 @@ func TestSnowflake_buildPasswordDSN(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			u, err := url.Parse(tt.inputURL)
 			assert.NoError(t, err)
-			
+
 			params := u.Query()
 			result := s.buildPasswordDSN(u, params)
 			assert.Equal(t, tt.expectedDSN, result)
@@ -66,7 +69,7 @@ func TestSnowflake_buildKeypairDSN(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			u, err := url.Parse(tt.inputURL)
 			assert.NoError(t, err)
-			
+
 			params := u.Query()
 			result := s.buildKeypairDSN(u, params)
 			assert.Equal(t, tt.expectedDSN, result)
@@ -92,7 +95,7 @@ func TestSnowflake_buildMFADSN(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			u, err := url.Parse(tt.inputURL)
 			assert.NoError(t, err)
-			
+
 			params := u.Query()
 			result := s.buildMFADSN(u, params)
 			assert.Equal(t, tt.expectedDSN, result)
@@ -102,21 +105,53 @@ func TestSnowflake_buildMFADSN(t *testing.T) {
 
 func TestSnowflake_GetHelpers(t *testing.T) {
 	s := &Snowflake{}
-	
+
 	helpers := s.GetHelpers(nil)
-	
+
 	// Check that essential helpers are present
 	assert.Contains(t, helpers, "list")
 	assert.Contains(t, helpers, "columns")
 	assert.Contains(t, helpers, "constraints")
 	assert.Contains(t, helpers, "primary-keys")
 	assert.Contains(t, helpers, "foreign-keys")
-	
+
 	// show-columns should not be present when opts is nil
 	assert.NotContains(t, helpers, "show-columns")
-	
+
 	// Check that list query contains expected elements
 	assert.Contains(t, helpers["list"], "information_schema.tables")
 	assert.Contains(t, helpers["columns"], "information_schema.columns")
 }
 
+func Test_parseSnowflakeEstimate(t *testing.T) {
+	plan := `{"GlobalStats":{"partitionsTotal":120,"partitionsAssigned":30,"bytesAssigned":1048576},"Operations":[[{"id":0,"operation":"Result"}]]}`
+
+	got, err := parseSnowflakeEstimate([]byte(plan))
+	assert.NoError(t, err)
+	assert.Equal(t, &core.Estimate{
+		Bytes:  1048576,
+		Detail: "1048576 bytes in 30 of 120 partitions assigned",
+	}, got)
+
+	_, err = parseSnowflakeEstimate([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestSnowflake_ParseError(t *testing.T) {
+	d := &snowflakeDriver{}
+
+	got := d.ParseError(&gosnowflake.SnowflakeError{
+		Number:   1003,
+		SQLState: "42000",
+		QueryID:  "01b2c3d4",
+		Message:  "SQL compilation error:\nsyntax error line 2 at position 4 unexpected 'FORM'.",
+	})
+	assert.Equal(t, "001003", got.Code)
+	assert.Equal(t, "42000", got.SQLState)
+	assert.Equal(t, "SQL compilation error: syntax error line 2 at position 4 unexpected 'FORM'.", got.Message)
+	assert.Equal(t, "query id: 01b2c3d4", got.Detail)
+	assert.Equal(t, 2, got.Line)
+	assert.Equal(t, 5, got.Column)
+
+	assert.Nil(t, d.ParseError(errors.New("connection refused")))
+}
@@ -10,6 +10,9 @@ import (
 var (
 	_ core.Driver           = (*sqliteDriver)(nil)
 	_ core.DatabaseSwitcher = (*sqliteDriver)(nil)
+	_ core.CurrentDatabaser = (*sqliteDriver)(nil)
+	_ core.ParamQuerier     = (*sqliteDriver)(nil)
+	_ core.Transactor       = (*sqliteDriver)(nil)
 )
 
 type sqliteDriver struct {
@@ -22,6 +25,12 @@ func (d *sqliteDriver) Query(ctx context.Context, query string) (core.ResultStre
 	return d.c.QueryUntilNotEmpty(ctx, query, "select changes() as 'Rows Affected'")
 }
 
+// QueryWithParams binds params as positional (?) or named (:name) bind variables.
+func (d *sqliteDriver) QueryWithParams(ctx context.Context, query string, params *core.QueryParams) (core.ResultStream, error) {
+	// run query, fallback to affected rows
+	return d.c.QueryUntilNotEmptyWithArgs(ctx, builders.Args(params), query, "select changes() as 'Rows Affected'")
+}
+
 func (d *sqliteDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
 	return d.c.ColumnsFromQuery("SELECT name, type FROM pragma_table_info('%s')", opts.Table)
 }
@@ -50,9 +59,29 @@ func (d *sqliteDriver) Structure() ([]*core.Structure, error) {
 
 func (d *sqliteDriver) Close() { d.c.Close() }
 
+func (d *sqliteDriver) CurrentDatabase(context.Context) (string, error) {
+	return d.currentDatabase, nil
+}
+
 func (d *sqliteDriver) ListDatabases() (string, []string, error) {
 	return d.currentDatabase, []string{"not supported yet"}, nil
 }
 
 // SelectDatabase is a no-op, added to make the UI more pleasent.
 func (d *sqliteDriver) SelectDatabase(name string) error { return nil }
+
+func (d *sqliteDriver) BeginTransaction(ctx context.Context) error {
+	return d.c.BeginTransaction(ctx)
+}
+
+func (d *sqliteDriver) CommitTransaction() error {
+	return d.c.CommitTransaction()
+}
+
+func (d *sqliteDriver) RollbackTransaction() error {
+	return d.c.RollbackTransaction()
+}
+
+func (d *sqliteDriver) InTransaction() bool {
+	return d.c.InTransaction()
+}
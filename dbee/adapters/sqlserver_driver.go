@@ -1,12 +1,20 @@
 package adapters
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	nurl "net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	mssql "github.com/microsoft/go-mssqldb"
+
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
 )
@@ -14,6 +22,11 @@ import (
 var (
 	_ core.Driver           = (*sqlServerDriver)(nil)
 	_ core.DatabaseSwitcher = (*sqlServerDriver)(nil)
+	_ core.CurrentDatabaser = (*sqlServerDriver)(nil)
+	_ core.ParamQuerier     = (*sqlServerDriver)(nil)
+	_ core.Transactor       = (*sqlServerDriver)(nil)
+	_ core.Explainer        = (*sqlServerDriver)(nil)
+	_ core.ErrorParser      = (*sqlServerDriver)(nil)
 )
 
 type sqlServerDriver struct {
@@ -26,6 +39,12 @@ func (c *sqlServerDriver) Query(ctx context.Context, query string) (core.ResultS
 	return c.c.QueryUntilNotEmpty(ctx, query, "select @@ROWCOUNT as 'Rows Affected'")
 }
 
+// QueryWithParams binds params as positional (@p1, @p2, ...) or named (@name) bind variables.
+func (c *sqlServerDriver) QueryWithParams(ctx context.Context, query string, params *core.QueryParams) (core.ResultStream, error) {
+	// run query, fallback to affected rows
+	return c.c.QueryUntilNotEmptyWithArgs(ctx, builders.Args(params), query, "select @@ROWCOUNT as 'Rows Affected'")
+}
+
 func (c *sqlServerDriver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
 	return c.c.ColumnsFromQuery(`
 		SELECT
@@ -52,10 +71,37 @@ func (c *sqlServerDriver) Structure() ([]*core.Structure, error) {
 	return core.GetGenericStructure(rows, getPGStructureType)
 }
 
+func (c *sqlServerDriver) ParseError(err error) *core.QueryError {
+	var msErr mssql.Error
+	if !errors.As(err, &msErr) {
+		return nil
+	}
+
+	qe := core.NewQueryError(err)
+	// class is the severity level (11-16 are errors which can be corrected by the user)
+	qe.Severity = strconv.Itoa(int(msErr.Class))
+	qe.Code = strconv.Itoa(int(msErr.Number))
+	qe.Message = msErr.Message
+	if msErr.ProcName != "" {
+		qe.Detail = "procedure: " + msErr.ProcName
+	}
+	// line is relative to the batch (or the procedure)
+	if msErr.ProcName == "" {
+		qe.Line = int(msErr.LineNo)
+	}
+
+	return qe
+}
+
 func (c *sqlServerDriver) Close() {
 	c.c.Close()
 }
 
+// CurrentDatabase asks the server, since the database can be changed with a USE statement.
+func (c *sqlServerDriver) CurrentDatabase(ctx context.Context) (string, error) {
+	return c.c.QueryText(ctx, "SELECT DB_NAME()", nil, nil)
+}
+
 func (c *sqlServerDriver) ListDatabases() (current string, available []string, err error) {
 	query := `
 		SELECT DB_NAME(), name
@@ -103,3 +149,154 @@ func (c *sqlServerDriver) SelectDatabase(name string) error {
 
 	return nil
 }
+
+func (c *sqlServerDriver) BeginTransaction(ctx context.Context) error {
+	return c.c.BeginTransaction(ctx)
+}
+
+func (c *sqlServerDriver) CommitTransaction() error {
+	return c.c.CommitTransaction()
+}
+
+func (c *sqlServerDriver) RollbackTransaction() error {
+	return c.c.RollbackTransaction()
+}
+
+func (c *sqlServerDriver) InTransaction() bool {
+	return c.c.InTransaction()
+}
+
+// Explain explains the query with estimated showplan XML.
+func (c *sqlServerDriver) Explain(ctx context.Context, query string, opts *core.ExplainOptions) (*core.PlanNode, error) {
+	// actual plans are only returned along with results of the query
+	if opts.Analyze {
+		return nil, core.ErrExplainAnalyzeNotSupported
+	}
+
+	// showplan has to be the only statement in its batch
+	out, err := c.c.QueryText(ctx, query, []string{"SET SHOWPLAN_XML ON"}, []string{"SET SHOWPLAN_XML OFF"})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSQLServerPlan([]byte(out))
+}
+
+// sqlServerXMLNode is a generic element of showplan XML.
+type sqlServerXMLNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr         `xml:",any,attr"`
+	Children []sqlServerXMLNode `xml:",any"`
+}
+
+func (n *sqlServerXMLNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *sqlServerXMLNode) number(name string) float64 {
+	f, _ := strconv.ParseFloat(n.attr(name), 64)
+	return f
+}
+
+// find returns the closest descendants with the provided name, without descending into elements named stop.
+func (n *sqlServerXMLNode) find(name, stop string) []*sqlServerXMLNode {
+	var found []*sqlServerXMLNode
+	for i := range n.Children {
+		child := &n.Children[i]
+		switch child.XMLName.Local {
+		case name:
+			found = append(found, child)
+		case stop:
+		default:
+			found = append(found, child.find(name, stop)...)
+		}
+	}
+	return found
+}
+
+// parseSQLServerPlan converts showplan XML to a plan tree.
+// Each statement of the batch is a node with operators (RelOp elements) as children.
+func parseSQLServerPlan(data []byte) (*core.PlanNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// text is already decoded by the driver, so the declared encoding (utf-16) is ignored
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var root sqlServerXMLNode
+	err := decoder.Decode(&root)
+	if err != nil {
+		return nil, fmt.Errorf("decoder.Decode: %w", err)
+	}
+
+	var statements []*core.PlanNode
+	for _, stmt := range root.find("StmtSimple", "") {
+		node := &core.PlanNode{
+			Operation: strings.TrimSpace("Statement " + stmt.attr("StatementType")),
+			Detail:    stmt.attr("StatementText"),
+			Cost:      stmt.number("StatementSubTreeCost"),
+			Rows:      stmt.number("StatementEstRows"),
+		}
+		for _, op := range stmt.find("RelOp", "") {
+			node.Children = append(node.Children, sqlServerRelOpNode(op))
+		}
+		statements = append(statements, node)
+	}
+
+	switch len(statements) {
+	case 0:
+		return nil, fmt.Errorf("no statements in showplan")
+	case 1:
+		return statements[0], nil
+	default:
+		return &core.PlanNode{
+			Operation: "Batch",
+			Children:  statements,
+		}, nil
+	}
+}
+
+func sqlServerRelOpNode(op *sqlServerXMLNode) *core.PlanNode {
+	operation := op.attr("PhysicalOp")
+	if logical := op.attr("LogicalOp"); logical != "" && logical != operation {
+		operation += " (" + logical + ")"
+	}
+
+	var detail []string
+	for _, obj := range op.find("Object", "RelOp") {
+		var name []string
+		for _, part := range []string{"Schema", "Table", "Index"} {
+			if v := obj.attr(part); v != "" {
+				name = append(name, v)
+			}
+		}
+		if len(name) > 0 {
+			detail = append(detail, "on "+strings.Join(name, "."))
+		}
+	}
+
+	node := &core.PlanNode{
+		Operation: operation,
+		Detail:    strings.Join(detail, " "),
+		Cost:      op.number("EstimatedTotalSubtreeCost"),
+		Rows:      op.number("EstimateRows"),
+	}
+
+	// actual values are present in plans collected while executing the query
+	for _, counters := range op.find("RunTimeCountersPerThread", "RelOp") {
+		node.ActualRows += counters.number("ActualRows")
+		elapsed := time.Duration(counters.number("ActualElapsedms") * float64(time.Millisecond))
+		node.Time = max(node.Time, elapsed)
+	}
+
+	for _, child := range op.find("RelOp", "RelOp") {
+		node.Children = append(node.Children, sqlServerRelOpNode(child))
+	}
+
+	return node
+}
@@ -0,0 +1,63 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+func Test_parseSQLServerPlan(t *testing.T) {
+	input := `<?xml version="1.0" encoding="utf-16"?>
+<ShowPlanXML xmlns="http://schemas.microsoft.com/sqlserver/2004/07/showplan" Version="1.5" Build="15.0.2000.5">
+  <BatchSequence>
+    <Batch>
+      <Statements>
+        <StmtSimple StatementText="SELECT * FROM users u JOIN orders o ON o.user_id = u.id" StatementType="SELECT" StatementSubTreeCost="0.5" StatementEstRows="100">
+          <QueryPlan>
+            <RelOp NodeId="0" PhysicalOp="Hash Match" LogicalOp="Inner Join" EstimateRows="100" EstimatedTotalSubtreeCost="0.5">
+              <OutputList />
+              <Hash>
+                <RelOp NodeId="1" PhysicalOp="Clustered Index Scan" LogicalOp="Clustered Index Scan" EstimateRows="10" EstimatedTotalSubtreeCost="0.1">
+                  <IndexScan>
+                    <Object Database="[db]" Schema="[dbo]" Table="[users]" Index="[PK_users]" />
+                  </IndexScan>
+                </RelOp>
+                <RelOp NodeId="2" PhysicalOp="Table Scan" LogicalOp="Table Scan" EstimateRows="100" EstimatedTotalSubtreeCost="0.3">
+                  <TableScan>
+                    <Object Database="[db]" Schema="[dbo]" Table="[orders]" />
+                  </TableScan>
+                </RelOp>
+              </Hash>
+            </RelOp>
+          </QueryPlan>
+        </StmtSimple>
+      </Statements>
+    </Batch>
+  </BatchSequence>
+</ShowPlanXML>`
+
+	want := &core.PlanNode{
+		Operation: "Statement SELECT",
+		Detail:    "SELECT * FROM users u JOIN orders o ON o.user_id = u.id",
+		Cost:      0.5,
+		Rows:      100,
+		Children: []*core.PlanNode{
+			{
+				Operation: "Hash Match (Inner Join)",
+				Cost:      0.5,
+				Rows:      100,
+				Children: []*core.PlanNode{
+					{Operation: "Clustered Index Scan", Detail: "on [dbo].[users].[PK_users]", Cost: 0.1, Rows: 10},
+					{Operation: "Table Scan", Detail: "on [dbo].[orders]", Cost: 0.3, Rows: 100},
+				},
+			},
+		},
+	}
+
+	got, err := parseSQLServerPlan([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
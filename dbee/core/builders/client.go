@@ -3,17 +3,43 @@ package builders
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 )
 
+var (
+	ErrTransactionInProgress = errors.New("transaction already in progress")
+	ErrNoTransaction         = errors.New("no transaction in progress")
+	ErrTransactionAborted    = errors.New("transaction was rolled back")
+	ErrTransactionBusy       = errors.New("transaction is busy with another query")
+)
+
+// querier is implemented by sql.DB, sql.Conn and sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // default sql client used by other specific implementations
 type Client struct {
 	db             *sql.DB
 	typeProcessors map[string]func(any) any
+	sessionIDQuery string
+
+	// open transaction - while it's set, all queries are executed in it.
+	// It runs on its own connection, so that the connection can be discarded.
+	tx      *sql.Tx
+	txConn  *sql.Conn
+	txMutex sync.RWMutex
+	// txSlot is held while the transaction is used by a query (see acquireTx)
+	txSlot chan struct{}
 }
 
 func NewClient(db *sql.DB, opts ...ClientOption) *Client {
@@ -27,20 +53,190 @@ func NewClient(db *sql.DB, opts ...ClientOption) *Client {
 	return &Client{
 		db:             db,
 		typeProcessors: config.typeProcessors,
+		sessionIDQuery: config.sessionIDQuery,
+		txSlot:         make(chan struct{}, 1),
 	}
 }
 
+// Close closes the database. Any open transaction is rolled back once it's not used anymore.
 func (c *Client) Close() {
+	c.txSlot <- struct{}{}
+	_ = c.rollbackTransaction()
+	<-c.txSlot
 	c.db.Close()
 }
 
 // Swap swaps current database connection for another one
-// and closes the old one.
+// and closes the old one. Any open transaction is rolled back once it's not used anymore.
 func (c *Client) Swap(db *sql.DB) {
+	c.txSlot <- struct{}{}
+	_ = c.rollbackTransaction()
+	<-c.txSlot
 	c.db.Close()
 	c.db = db
 }
 
+// BeginTransaction starts a transaction on a single dedicated connection.
+// Until it's committed or rolled back, all queries of the client are executed in it.
+func (c *Client) BeginTransaction(ctx context.Context) error {
+	c.txMutex.Lock()
+	defer c.txMutex.Unlock()
+
+	if c.tx != nil {
+		return ErrTransactionInProgress
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("c.db.Conn: %w", err)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("conn.BeginTx: %w", err)
+	}
+	c.tx = tx
+	c.txConn = conn
+
+	return nil
+}
+
+// CommitTransaction commits the open transaction.
+// It fails with ErrTransactionBusy if a query is still using the transaction.
+func (c *Client) CommitTransaction() error {
+	select {
+	case c.txSlot <- struct{}{}:
+		defer func() { <-c.txSlot }()
+	default:
+		return ErrTransactionBusy
+	}
+
+	c.txMutex.Lock()
+	defer c.txMutex.Unlock()
+
+	if c.tx == nil {
+		return ErrNoTransaction
+	}
+
+	// transaction is finished even if commit fails
+	err := c.tx.Commit()
+	c.endTransaction()
+	if err != nil {
+		return fmt.Errorf("c.tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+// RollbackTransaction rolls back the open transaction.
+// It fails with ErrTransactionBusy if a query is still using the transaction.
+func (c *Client) RollbackTransaction() error {
+	select {
+	case c.txSlot <- struct{}{}:
+		defer func() { <-c.txSlot }()
+	default:
+		return ErrTransactionBusy
+	}
+
+	return c.rollbackTransaction()
+}
+
+// rollbackTransaction rolls back the open transaction. It's called with txSlot held.
+func (c *Client) rollbackTransaction() error {
+	c.txMutex.Lock()
+	defer c.txMutex.Unlock()
+
+	if c.tx == nil {
+		return ErrNoTransaction
+	}
+
+	err := c.tx.Rollback()
+	c.endTransaction()
+	if err != nil {
+		return fmt.Errorf("c.tx.Rollback: %w", err)
+	}
+
+	return nil
+}
+
+// endTransaction returns the connection of the finished transaction to the pool. It's called with txMutex held.
+func (c *Client) endTransaction() {
+	_ = c.txConn.Close()
+	c.tx = nil
+	c.txConn = nil
+}
+
+// discard closes the connection of conn instead of returning it to the pool, so that a session
+// left in an unknown state (e.g. with changed session options) isn't reused by other queries.
+// If conn is the open transaction, it's rolled back first.
+func (c *Client) discard(conn querier) {
+	switch conn := conn.(type) {
+	case *sql.Conn:
+		discardConn(conn)
+	case *sql.Tx:
+		c.txMutex.Lock()
+		defer c.txMutex.Unlock()
+
+		if c.tx != conn {
+			return
+		}
+		_ = c.tx.Rollback()
+		discardConn(c.txConn)
+		c.tx = nil
+		c.txConn = nil
+	}
+}
+
+// discardConn closes the connection, so that it's not returned to the pool.
+func discardConn(conn *sql.Conn) {
+	// database/sql closes connections which report driver.ErrBadConn
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
+
+// InTransaction reports if there is an open transaction.
+func (c *Client) InTransaction() bool {
+	c.txMutex.RLock()
+	defer c.txMutex.RUnlock()
+
+	return c.tx != nil
+}
+
+// acquireTx reserves the open transaction for a single query, as a transaction can't execute queries
+// concurrently (e.g. while rows of another query are still being read). It waits until the transaction
+// is released by the previous query or ctx is done. It returns a nil transaction if there is none.
+func (c *Client) acquireTx(ctx context.Context) (tx *sql.Tx, release func(), err error) {
+	c.txMutex.RLock()
+	tx = c.tx
+	c.txMutex.RUnlock()
+
+	if tx == nil {
+		return nil, func() {}, nil
+	}
+
+	select {
+	case c.txSlot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, fmt.Errorf("%w: %w", ErrTransactionBusy, ctx.Err())
+	}
+
+	// transaction might have finished in the meantime
+	c.txMutex.RLock()
+	tx = c.tx
+	c.txMutex.RUnlock()
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() { <-c.txSlot })
+	}
+	if tx == nil {
+		release()
+		return nil, func() {}, nil
+	}
+
+	return tx, release, nil
+}
+
 // ColumnsFromQuery executes a given query on a new connection and
 // converts the results to columns. A query should return a result that is
 // at least 2 columns wide and have the following structure:
@@ -59,71 +255,121 @@ func (c *Client) ColumnsFromQuery(query string, args ...any) ([]*core.Column, er
 }
 
 // Exec executes a query and returns a stream with single row (number of affected results).
-func (c *Client) Exec(ctx context.Context, query string) (*ResultStream, error) {
-	res, err := c.db.ExecContext(ctx, query)
+// Args are bound to the query as bind variables.
+func (c *Client) Exec(ctx context.Context, query string, args ...any) (*ResultStream, error) {
+	conn, closeConn, err := c.reportingConn(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	res, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		closeConn()
+		return nil, err
+	}
+
 	affected, err := res.RowsAffected()
 	if err != nil {
+		closeConn()
 		return nil, err
 	}
+	core.ReportRowsAffected(ctx, affected)
 
 	rows := NewResultStreamBuilder().
 		WithNextFunc(NextSingle(affected)).
 		WithHeader(core.Header{"Rows Affected"}).
 		Build()
 
+	// connection is held until the result is closed, so that the query id stays valid
+	rows.AddCallback(closeConn)
+
 	return rows, nil
 }
 
+// ExecOutsideTransaction executes a statement on a separate connection from the pool,
+// even if a transaction is open. Useful for statements which control other sessions
+// (e.g. canceling their queries).
+func (c *Client) ExecOutsideTransaction(ctx context.Context, query string, args ...any) error {
+	_, err := c.db.ExecContext(ctx, query, args...)
+	return err
+}
+
 // Query executes a query on a connection and returns a result stream.
-func (c *Client) Query(ctx context.Context, query string) (*ResultStream, error) {
-	rows, err := c.db.QueryContext(ctx, query)
+// Args are bound to the query as bind variables.
+func (c *Client) Query(ctx context.Context, query string, args ...any) (*ResultStream, error) {
+	conn, closeConn, err := c.reportingConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, query, args...)
 	if err != nil {
+		closeConn()
 		return nil, err
 	}
 
-	return c.parseRows(rows)
+	result, err := c.parseRows(rows)
+	if err != nil {
+		closeConn()
+		return nil, err
+	}
+	result.AddCallback(closeConn)
+
+	return result, nil
 }
 
 // QueryUntilNotEmpty executes given queries on a single connection and returns when one of them
 // has a nonempty result.
 // Useful for specifying "fallback" queries like "ROWCOUNT()" when there are no results in query.
+// Single value rows of fallback queries are reported as the number of affected rows (see core.ReportRowsAffected).
 func (c *Client) QueryUntilNotEmpty(ctx context.Context, queries ...string) (*ResultStream, error) {
+	return c.QueryUntilNotEmptyWithArgs(ctx, nil, queries...)
+}
+
+// QueryUntilNotEmptyWithArgs is the same as QueryUntilNotEmpty, but binds args to the first query.
+// Fallback queries are executed without args.
+func (c *Client) QueryUntilNotEmptyWithArgs(ctx context.Context, args []any, queries ...string) (*ResultStream, error) {
 	if len(queries) < 1 {
 		return nil, errors.New("no queries provided")
 	}
 
-	conn, err := c.db.Conn(ctx)
+	conn, closeConn, err := c.singleConn(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("c.db.Conn: %w", err)
+		return nil, err
 	}
+	c.reportSessionID(ctx, conn)
+
+	for i, query := range queries {
+		var queryArgs []any
+		if i == 0 {
+			queryArgs = args
+		}
 
-	for _, query := range queries {
-		rows, err := conn.QueryContext(ctx, query)
+		rows, err := conn.QueryContext(ctx, query, queryArgs...)
 		if err != nil {
-			_ = conn.Close()
+			closeConn()
 			return nil, fmt.Errorf("conn.QueryContext: %w", err)
 		}
 
 		result, err := c.parseRows(rows)
 		if err != nil {
-			_ = conn.Close()
+			closeConn()
 			return nil, err
 		}
 
 		// has result
 		if len(result.Header()) > 0 {
-			result.AddCallback(func() { _ = conn.Close() })
+			if i > 0 {
+				result.next = reportingRowsAffected(ctx, result.next)
+			}
+			result.AddCallback(closeConn)
 			return result, nil
 		}
 
 		result.Close()
 	}
 
-	_ = conn.Close()
+	closeConn()
 
 	// return an empty result
 	return NewResultStreamBuilder().
@@ -132,6 +378,220 @@ func (c *Client) QueryUntilNotEmpty(ctx context.Context, queries ...string) (*Re
 		Build(), nil
 }
 
+// QueryText executes a query on a single connection and returns values of the first column of all rows,
+// joined with newlines. Useful for queries which return a document (e.g. a query plan) as text.
+// Optional "before" statements are executed on the same connection before the query and "after"
+// statements after it, even if the query fails or is canceled (e.g. to toggle session options).
+// If the after statements fail, the connection is discarded (see ErrTransactionAborted).
+func (c *Client) QueryText(ctx context.Context, query string, before, after []string) (text string, err error) {
+	conn, closeConn, err := c.singleConn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer closeConn()
+
+	defer func() {
+		// the session mustn't be left with changed options, not even for the transaction
+		for _, stmt := range after {
+			_, afterErr := conn.ExecContext(context.Background(), stmt)
+			if afterErr == nil {
+				continue
+			}
+
+			afterErr = fmt.Errorf("conn.ExecContext: %w", afterErr)
+			if _, ok := conn.(*sql.Tx); ok {
+				afterErr = fmt.Errorf("%w: %w", ErrTransactionAborted, afterErr)
+			}
+			c.discard(conn)
+			text, err = "", errors.Join(err, afterErr)
+			return
+		}
+	}()
+
+	for _, stmt := range before {
+		_, err := conn.ExecContext(ctx, stmt)
+		if err != nil {
+			return "", fmt.Errorf("conn.ExecContext: %w", err)
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return "", fmt.Errorf("conn.QueryContext: %w", err)
+	}
+
+	return readText(rows)
+}
+
+// rollbackSavepoint is the savepoint of QueryTextRolledBack in an open transaction.
+const rollbackSavepoint = "dbee_rollback"
+
+// QueryTextRolledBack is like QueryText, but the query is executed in a transaction which is always
+// rolled back, so that statements which modify data (e.g. explained with analyze) have no effect.
+// While a transaction is open, the query is executed in a savepoint of it instead.
+func (c *Client) QueryTextRolledBack(ctx context.Context, query string) (string, error) {
+	openTx, release, err := c.acquireTx(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	if openTx != nil {
+		_, err := openTx.ExecContext(ctx, "SAVEPOINT "+rollbackSavepoint)
+		if err != nil {
+			return "", fmt.Errorf("openTx.ExecContext: %w", err)
+		}
+		defer func() {
+			// the savepoint is rolled back even if the query was canceled
+			_, _ = openTx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+rollbackSavepoint)
+			_, _ = openTx.ExecContext(context.Background(), "RELEASE SAVEPOINT "+rollbackSavepoint)
+		}()
+
+		rows, err := openTx.QueryContext(ctx, query)
+		if err != nil {
+			return "", fmt.Errorf("openTx.QueryContext: %w", err)
+		}
+		return readText(rows)
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("c.db.BeginTx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return "", fmt.Errorf("tx.QueryContext: %w", err)
+	}
+	return readText(rows)
+}
+
+// readText returns values of the first column of all rows joined with newlines and closes the rows.
+func readText(rows *sql.Rows) (string, error) {
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		columns, err := rows.Columns()
+		if err != nil {
+			return "", fmt.Errorf("rows.Columns: %w", err)
+		}
+		values := make([]any, len(columns))
+		for i := range values {
+			values[i] = new(any)
+		}
+		if err := rows.Scan(values...); err != nil {
+			return "", fmt.Errorf("rows.Scan: %w", err)
+		}
+		if len(values) < 1 {
+			continue
+		}
+
+		switch v := (*values[0].(*any)).(type) {
+		case nil:
+		case []byte:
+			lines = append(lines, string(v))
+		default:
+			lines = append(lines, fmt.Sprint(v))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// singleConn returns a querier which executes all queries on the same connection:
+// either the open transaction (see acquireTx) or a new connection from the pool.
+// The connection has to be closed after use.
+func (c *Client) singleConn(ctx context.Context) (conn querier, closeConn func(), err error) {
+	tx, release, err := c.acquireTx(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the session can execute other queries once it's released, so the query
+	// mustn't be canceled anymore (see core.ReportSessionID)
+	if tx != nil {
+		return tx, func() {
+			core.FinishQuery(ctx)
+			release()
+		}, nil
+	}
+
+	dbConn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("c.db.Conn: %w", err)
+	}
+
+	return dbConn, func() {
+		core.FinishQuery(ctx)
+		_ = dbConn.Close()
+	}, nil
+}
+
+// reportingConn returns a querier for a single query, which has to be closed after use.
+// If the session id should be reported, the query is executed on a single connection (see singleConn),
+// otherwise in the open transaction (see acquireTx) or on the pool.
+func (c *Client) reportingConn(ctx context.Context) (conn querier, closeConn func(), err error) {
+	if c.sessionIDQuery == "" || !core.WantsQueryID(ctx) {
+		tx, release, err := c.acquireTx(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tx != nil {
+			return tx, release, nil
+		}
+		return c.db, func() {}, nil
+	}
+
+	conn, closeConn, err = c.singleConn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.reportSessionID(ctx, conn)
+
+	return conn, closeConn, nil
+}
+
+// reportingRowsAffected wraps next, so that the value of a single value row
+// is reported as the number of affected rows.
+func reportingRowsAffected(ctx context.Context, next func() (core.Row, error)) func() (core.Row, error) {
+	return func() (core.Row, error) {
+		row, err := next()
+		if err != nil || len(row) != 1 {
+			return row, err
+		}
+
+		affected, err := strconv.ParseInt(fmt.Sprint(row[0]), 10, 64)
+		if err == nil {
+			core.ReportRowsAffected(ctx, affected)
+		}
+		return row, nil
+	}
+}
+
+// reportSessionID reports the session identifier of the connection (see WithSessionIDQuery).
+// Failing to get the identifier only means that the query can't be canceled on the server.
+func (c *Client) reportSessionID(ctx context.Context, conn querier) {
+	if c.sessionIDQuery == "" || !core.WantsQueryID(ctx) {
+		return
+	}
+
+	var id any
+	err := conn.QueryRowContext(ctx, c.sessionIDQuery).Scan(&id)
+	if err != nil || id == nil {
+		return
+	}
+	if b, ok := id.([]byte); ok {
+		id = string(b)
+	}
+
+	core.ReportSessionID(ctx, fmt.Sprint(id))
+}
+
 func (c *Client) getTypeProcessor(typ string) func(any) any {
 	proc, ok := c.typeProcessors[strings.ToLower(typ)]
 	if ok {
@@ -148,6 +608,8 @@ func (c *Client) getTypeProcessor(typ string) func(any) any {
 }
 
 // parseRows transforms sql rows to result stream.
+// Every result set of rows is exposed as a separate set of the stream.
+// Result sets without any columns (e.g. from statements which don't return rows) are skipped.
 func (c *Client) parseRows(rows *sql.Rows) (*ResultStream, error) {
 	// create new rows
 	header, err := rows.Columns()
@@ -155,16 +617,28 @@ func (c *Client) parseRows(rows *sql.Rows) (*ResultStream, error) {
 		return nil, err
 	}
 
-	hasNextFunc := func() bool {
-		// TODO: do we even support multiple result sets?
-		// if not next result, check for any new sets
-		if !rows.Next() {
-			if !rows.NextResultSet() {
-				return false
+	nextSetFunc := func() (core.Header, bool) {
+		for rows.NextResultSet() {
+			header, err := rows.Columns()
+			if err != nil {
+				return nil, false
 			}
-			return rows.Next()
+			if len(header) > 0 {
+				return header, true
+			}
+		}
+		return nil, false
+	}
+
+	// skip to the first set with columns
+	if len(header) < 1 {
+		if h, ok := nextSetFunc(); ok {
+			header = h
 		}
-		return true
+	}
+
+	hasNextFunc := func() bool {
+		return rows.Next()
 	}
 
 	nextFunc := func() (core.Row, error) {
@@ -197,6 +671,7 @@ func (c *Client) parseRows(rows *sql.Rows) (*ResultStream, error) {
 
 	result := NewResultStreamBuilder().
 		WithNextFunc(nextFunc, hasNextFunc).
+		WithNextSetFunc(nextSetFunc).
 		WithHeader(header).
 		WithCloseFunc(func() {
 			_ = rows.Close()
@@ -4,6 +4,7 @@ import "strings"
 
 type clientConfig struct {
 	typeProcessors map[string]func(any) any
+	sessionIDQuery string
 }
 
 type ClientOption func(*clientConfig)
@@ -20,3 +21,12 @@ func WithCustomTypeProcessor(typ string, fn func(any) any) ClientOption {
 		cc.typeProcessors[t] = fn
 	}
 }
+
+// WithSessionIDQuery sets a query which returns the identifier of the current database session
+// (e.g. "SELECT pg_backend_pid()"). If a call is interested in it (see core.WantsQueryID),
+// the session identifier is reported (see core.ReportSessionID) before executing the query on the same connection.
+func WithSessionIDQuery(query string) ClientOption {
+	return func(cc *clientConfig) {
+		cc.sessionIDQuery = query
+	}
+}
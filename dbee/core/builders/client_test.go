@@ -0,0 +1,336 @@
+package builders_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
+)
+
+// testConnector connects to a fake database, whose sessions have a plan mode (like SHOWPLAN_XML
+// of SQL Server): while it's on, queries return "plan" instead of the query.
+type testConnector struct {
+	mu     sync.Mutex
+	opened int
+	// failPlanOff fails statements which turn the plan mode off
+	failPlanOff bool
+}
+
+func (tc *testConnector) Connect(context.Context) (driver.Conn, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.opened++
+	return &testConn{connector: tc}, nil
+}
+
+func (tc *testConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (tc *testConnector) setFailPlanOff(fail bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.failPlanOff = fail
+}
+
+func (tc *testConnector) openedConns() int {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	return tc.opened
+}
+
+type testConn struct {
+	connector *testConnector
+	plan      bool
+}
+
+func (c *testConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *testConn) Close() error {
+	return nil
+}
+
+func (c *testConn) Begin() (driver.Tx, error) {
+	return testTx{}, nil
+}
+
+func (c *testConn) ExecContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	switch query {
+	case "plan on":
+		c.plan = true
+	case "plan off":
+		c.connector.mu.Lock()
+		fail := c.connector.failPlanOff
+		c.connector.mu.Unlock()
+		if fail {
+			return nil, errors.New("plan mode can't be turned off")
+		}
+		c.plan = false
+	}
+
+	return driver.RowsAffected(0), nil
+}
+
+// QueryContext returns a single row with the query (or "plan" in plan mode).
+// Query "slow" runs until ctx is canceled and query "fail" fails.
+func (c *testConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	switch query {
+	case "slow":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case "fail":
+		return nil, errors.New("query failed")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if c.plan {
+		query = "plan"
+	}
+	return &testRows{values: []string{query}}, nil
+}
+
+type testTx struct{}
+
+func (testTx) Commit() error   { return nil }
+func (testTx) Rollback() error { return nil }
+
+type testRows struct {
+	values []string
+}
+
+func (r *testRows) Columns() []string {
+	return []string{"text"}
+}
+
+func (r *testRows) Close() error {
+	return nil
+}
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) < 1 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+// newTestClient returns a client of the fake database with a single connection in the pool.
+func newTestClient(t *testing.T) (*builders.Client, *testConnector) {
+	t.Helper()
+
+	connector := &testConnector{}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(1)
+
+	client := builders.NewClient(db)
+	t.Cleanup(client.Close)
+
+	return client, connector
+}
+
+func TestClient_QueryTextCanceled(t *testing.T) {
+	for _, inTransaction := range []bool{false, true} {
+		t.Run(map[bool]string{false: "pool", true: "transaction"}[inTransaction], func(t *testing.T) {
+			r := require.New(t)
+
+			client, connector := newTestClient(t)
+			if inTransaction {
+				r.NoError(client.BeginTransaction(context.Background()))
+			}
+
+			// query is canceled while the plan mode is on
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			time.AfterFunc(50*time.Millisecond, cancel)
+			_, err := client.QueryText(ctx, "slow", []string{"plan on"}, []string{"plan off"})
+			r.ErrorIs(err, context.Canceled)
+
+			// session is back to normal
+			text, err := client.QueryText(context.Background(), "select", nil, nil)
+			r.NoError(err)
+			r.Equal("select", text)
+			r.Equal(1, connector.openedConns())
+			r.Equal(inTransaction, client.InTransaction())
+		})
+	}
+}
+
+func TestClient_QueryTextDiscardsSession(t *testing.T) {
+	for _, inTransaction := range []bool{false, true} {
+		t.Run(map[bool]string{false: "pool", true: "transaction"}[inTransaction], func(t *testing.T) {
+			r := require.New(t)
+
+			client, connector := newTestClient(t)
+			if inTransaction {
+				r.NoError(client.BeginTransaction(context.Background()))
+			}
+
+			// plan mode can't be turned off
+			connector.setFailPlanOff(true)
+			_, err := client.QueryText(context.Background(), "select", []string{"plan on"}, []string{"plan off"})
+			r.Error(err)
+			if inTransaction {
+				r.ErrorIs(err, builders.ErrTransactionAborted)
+			}
+			connector.setFailPlanOff(false)
+
+			// session isn't reused
+			text, err := client.QueryText(context.Background(), "select", nil, nil)
+			r.NoError(err)
+			r.Equal("select", text)
+			r.Equal(2, connector.openedConns())
+			r.False(client.InTransaction())
+		})
+	}
+}
+
+var (
+	_ core.Adapter  = (*testAdapter)(nil)
+	_ core.Driver   = (*testDriver)(nil)
+	_ core.Canceler = (*testDriver)(nil)
+)
+
+// testAdapter connects drivers which execute queries with the client.
+type testAdapter struct {
+	driver *testDriver
+}
+
+func (a *testAdapter) Connect(string) (core.Driver, error) {
+	return a.driver, nil
+}
+
+func (a *testAdapter) GetHelpers(*core.TableOptions) map[string]string {
+	return nil
+}
+
+type testDriver struct {
+	client   *builders.Client
+	canceled atomic.Bool
+}
+
+func (d *testDriver) Query(ctx context.Context, query string) (core.ResultStream, error) {
+	result, err := d.client.Query(ctx, query)
+	if err != nil {
+		// call times out while the error is returned
+		time.Sleep(200 * time.Millisecond)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *testDriver) Structure() ([]*core.Structure, error) {
+	return nil, nil
+}
+
+func (d *testDriver) Columns(*core.TableOptions) ([]*core.Column, error) {
+	return nil, nil
+}
+
+func (d *testDriver) CancelQuery(context.Context, string) error {
+	d.canceled.Store(true)
+	return nil
+}
+
+func (d *testDriver) Close() {}
+
+func TestClient_FailedQueryNotCanceled(t *testing.T) {
+	r := require.New(t)
+
+	db := sql.OpenDB(&testConnector{})
+	client := builders.NewClient(db, builders.WithSessionIDQuery("session"))
+	t.Cleanup(client.Close)
+
+	driver := &testDriver{client: client}
+	connection, err := core.NewConnection(&core.ConnectionParams{}, &testAdapter{driver: driver})
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.ExecuteWithOptions("fail", nil, &core.CallOptions{Timeout: 50 * time.Millisecond}, nil, nil)
+	select {
+	case <-call.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not finish in expected time")
+	}
+
+	// session was returned to the pool before the call timed out, so it mustn't be canceled
+	r.Error(call.Err())
+	r.False(driver.canceled.Load())
+}
+
+func TestClient_TransactionBusy(t *testing.T) {
+	r := require.New(t)
+
+	client, _ := newTestClient(t)
+	r.NoError(client.BeginTransaction(context.Background()))
+
+	// rows of the first query are still being read
+	result, err := client.Query(context.Background(), "first")
+	r.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.Query(ctx, "second")
+	r.ErrorIs(err, builders.ErrTransactionBusy)
+	r.ErrorIs(err, context.DeadlineExceeded)
+	_, err = client.QueryText(ctx, "second", nil, nil)
+	r.ErrorIs(err, builders.ErrTransactionBusy)
+	r.ErrorIs(client.CommitTransaction(), builders.ErrTransactionBusy)
+	r.ErrorIs(client.RollbackTransaction(), builders.ErrTransactionBusy)
+
+	// waiting query is executed once the first one is closed
+	type queryResult struct {
+		text string
+		err  error
+	}
+	waiting := make(chan queryResult, 1)
+	go func() {
+		text, err := client.QueryText(context.Background(), "second", nil, nil)
+		waiting <- queryResult{text: text, err: err}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-waiting:
+		t.Fatal("query was executed while the transaction was busy")
+	default:
+	}
+
+	r.True(result.HasNext())
+	row, err := result.Next()
+	r.NoError(err)
+	r.Equal(core.Row{"first"}, row)
+	result.Close()
+
+	select {
+	case res := <-waiting:
+		r.NoError(res.err)
+		r.Equal("second", res.text)
+	case <-time.After(5 * time.Second):
+		t.Fatal("query was not executed after the transaction was released")
+	}
+
+	r.NoError(client.CommitTransaction())
+	r.False(client.InTransaction())
+}
@@ -0,0 +1,48 @@
+package builders
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+var ErrNamedParamsNotSupported = errors.New("named query parameters not supported, use positional ones instead")
+
+// Args converts query params to arguments of database/sql queries.
+// Named params are passed as sql.NamedArg, so the driver has to support them.
+func Args(params *core.QueryParams) []any {
+	if params.IsEmpty() {
+		return nil
+	}
+
+	args := make([]any, 0, len(params.Positional)+len(params.Named))
+	args = append(args, params.Positional...)
+
+	// sort names for deterministic order of arguments
+	names := make([]string, 0, len(params.Named))
+	for name := range params.Named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		args = append(args, sql.Named(name, params.Named[name]))
+	}
+
+	return args
+}
+
+// PositionalArgs converts query params to arguments of database/sql queries
+// for drivers which only support positional bind variables.
+func PositionalArgs(params *core.QueryParams) ([]any, error) {
+	if params.IsEmpty() {
+		return nil, nil
+	}
+	if len(params.Named) > 0 {
+		return nil, ErrNamedParamsNotSupported
+	}
+
+	return params.Positional, nil
+}
@@ -0,0 +1,33 @@
+package builders_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/builders"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgs(t *testing.T) {
+	r := require.New(t)
+
+	r.Nil(builders.Args(nil))
+
+	args := builders.Args(&core.QueryParams{
+		Positional: []any{1, "two"},
+		Named:      map[string]any{"b": 2, "a": 1},
+	})
+	r.Equal([]any{1, "two", sql.Named("a", 1), sql.Named("b", 2)}, args)
+}
+
+func TestPositionalArgs(t *testing.T) {
+	r := require.New(t)
+
+	args, err := builders.PositionalArgs(&core.QueryParams{Positional: []any{1, "two"}})
+	r.NoError(err)
+	r.Equal([]any{1, "two"}, args)
+
+	_, err = builders.PositionalArgs(&core.QueryParams{Named: map[string]any{"a": 1}})
+	r.ErrorIs(err, builders.ErrNamedParamsNotSupported)
+}
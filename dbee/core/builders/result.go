@@ -7,11 +7,12 @@ import (
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 )
 
-var _ core.ResultStream = (*ResultStream)(nil)
+var _ core.MultiResultStream = (*ResultStream)(nil)
 
 type ResultStream struct {
 	next    func() (core.Row, error)
 	hasNext func() bool
+	nextSet func() (core.Header, bool)
 	closes  []func()
 	meta    *core.Meta
 	header  core.Header
@@ -43,6 +44,20 @@ func (r *ResultStream) Next() (core.Row, error) {
 	return rows, nil
 }
 
+// NextResultSet advances the stream to the next result set and replaces the header.
+func (r *ResultStream) NextResultSet() bool {
+	if r.nextSet == nil {
+		return false
+	}
+
+	header, ok := r.nextSet()
+	if !ok {
+		return false
+	}
+	r.header = header
+	return true
+}
+
 func (r *ResultStream) Close() {
 	r.once.Do(func() {
 		for _, fn := range r.closes {
@@ -55,12 +70,14 @@ func (r *ResultStream) Close() {
 	r.hasNext = func() bool {
 		return false
 	}
+	r.nextSet = nil
 }
 
 // ResultStreamBuilder builds the rows
 type ResultStreamBuilder struct {
 	next    func() (core.Row, error)
 	hasNext func() bool
+	nextSet func() (core.Header, bool)
 	header  core.Header
 	closes  []func()
 	meta    *core.Meta
@@ -81,6 +98,13 @@ func (b *ResultStreamBuilder) WithNextFunc(fn func() (core.Row, error), has func
 	return b
 }
 
+// WithNextSetFunc sets a function which advances the stream to the next result set
+// and returns its header. It should return false if there are no more sets.
+func (b *ResultStreamBuilder) WithNextSetFunc(fn func() (core.Header, bool)) *ResultStreamBuilder {
+	b.nextSet = fn
+	return b
+}
+
 func (b *ResultStreamBuilder) WithHeader(header core.Header) *ResultStreamBuilder {
 	b.header = header
 	return b
@@ -100,6 +124,7 @@ func (b *ResultStreamBuilder) Build() *ResultStream {
 	return &ResultStream{
 		next:    b.next,
 		hasNext: b.hasNext,
+		nextSet: b.nextSet,
 		header:  b.header,
 		closes:  b.closes,
 		meta:    b.meta,
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,9 +22,31 @@ type (
 		timeTaken time.Duration
 		timestamp time.Time
 
-		result     *Result
-		archive    *archive
-		cancelFunc func()
+		// connection which executed the call and the database the call was sent to
+		connID   ConnectionID
+		connName string
+		kind     CallKind
+		database string
+		// guards state, time taken, error, database and estimate, which are set while the call is running
+		metaMutex sync.RWMutex
+		// rowCount is the number of rows of a restored call, whose results are only loaded on demand
+		rowCount int
+		// rowsAffected is reported by the driver, -1 if unknown
+		rowsAffected atomic.Int64
+		// call which was rerun by this call (if any) and params of the query
+		parentID CallID
+		params   *QueryParams
+
+		// results of result sets, in order of retrieval, and the archive they are persisted to
+		results      []*Result
+		archive      *archive
+		resultsMutex sync.RWMutex
+		cancelFunc   func()
+		// query running on the server (nil if driver can't cancel queries)
+		serverQuery *serverQuery
+		// estimate of the query and decision of the user if it needs confirmation
+		estimate  *Estimate
+		confirmCh chan bool
 
 		// any error that might occur during execution
 		err  error
@@ -38,21 +62,82 @@ type callPersistent struct {
 	TimeTaken int64  `json:"time_taken_us"`
 	Timestamp int64  `json:"timestamp_us"`
 	Error     string `json:"error,omitempty"`
+
+	QueryError *queryErrorPersistent `json:"query_error,omitempty"`
+
+	ConnectionID   string `json:"conn_id,omitempty"`
+	ConnectionName string `json:"conn_name,omitempty"`
+	Kind           string `json:"kind,omitempty"`
+	Database       string `json:"database,omitempty"`
+	RowCount       int    `json:"row_count"`
+	RowsAffected   *int64 `json:"rows_affected,omitempty"`
+	ArchiveSize    int64  `json:"archive_size"`
+	QueryID        string `json:"query_id,omitempty"`
+
+	ParentID string                 `json:"parent_call_id,omitempty"`
+	Params   *queryParamsPersistent `json:"params,omitempty"`
+}
+
+// queryErrorPersistent is used for marshaling and unmarshaling the query error of a call
+type queryErrorPersistent struct {
+	Severity string `json:"severity,omitempty"`
+	SQLState string `json:"sqlstate,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message"`
+	Detail   string `json:"detail,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+	Position int    `json:"position,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
 }
 
 func (c *Call) toPersistent() *callPersistent {
+	callErr := c.Err()
 	errMsg := ""
-	if c.err != nil {
-		errMsg = c.err.Error()
+	if callErr != nil {
+		errMsg = callErr.Error()
+	}
+
+	var queryErr *queryErrorPersistent
+	if qe := AsQueryError(callErr); qe != nil {
+		queryErr = &queryErrorPersistent{
+			Severity: qe.Severity,
+			SQLState: qe.SQLState,
+			Code:     qe.Code,
+			Message:  qe.Message,
+			Detail:   qe.Detail,
+			Hint:     qe.Hint,
+			Position: qe.Position,
+			Line:     qe.Line,
+			Column:   qe.Column,
+		}
+	}
+
+	var rowsAffected *int64
+	if n := c.GetRowsAffected(); n >= 0 {
+		rowsAffected = &n
 	}
 
 	return &callPersistent{
-		ID:        string(c.id),
-		Query:     c.query,
-		State:     c.state.String(),
-		TimeTaken: c.timeTaken.Microseconds(),
-		Timestamp: c.timestamp.UnixMicro(),
-		Error:     errMsg,
+		ID:         string(c.id),
+		Query:      c.query,
+		State:      c.GetState().String(),
+		TimeTaken:  c.GetTimeTaken().Microseconds(),
+		Timestamp:  c.timestamp.UnixMicro(),
+		Error:      errMsg,
+		QueryError: queryErr,
+
+		ConnectionID:   string(c.connID),
+		ConnectionName: c.connName,
+		Kind:           string(c.kind),
+		Database:       c.GetDatabase(),
+		RowCount:       c.GetRowCount(),
+		RowsAffected:   rowsAffected,
+		ArchiveSize:    c.ArchiveSize(),
+		QueryID:        c.GetQueryID(),
+
+		ParentID: string(c.parentID),
+		Params:   c.params.toPersistent(),
 	}
 }
 
@@ -70,16 +155,42 @@ func (c *Call) UnmarshalJSON(data []byte) error {
 	done := make(chan struct{})
 	close(done)
 
-	archive := newArchive(CallID(alias.ID))
+	archive := restoreArchive(CallID(alias.ID))
 	state := CallStateFromString(alias.State)
-	if state == CallStateArchived && archive.isEmpty() {
+	switch state {
+	case CallStateArchived:
+		if archive.isEmpty() {
+			state = CallStateUnknown
+		}
+	case CallStateExecuting, CallStateRetrieving, CallStateAwaitingConfirmation:
+		// restored calls can't be running anymore (e.g. the process exited while they were)
 		state = CallStateUnknown
 	}
 
+	// the first result is always present, even if it's empty
+	results := make([]*Result, max(archive.setCount(), 1))
+	for i := range results {
+		results[i] = newResult(archive.resultSet(i), int(resultWindowSize.Load()))
+	}
+
 	var callErr error
 	if alias.Error != "" {
 		callErr = errors.New(alias.Error)
 	}
+	if qe := alias.QueryError; qe != nil {
+		callErr = &QueryError{
+			Severity: qe.Severity,
+			SQLState: qe.SQLState,
+			Code:     qe.Code,
+			Message:  qe.Message,
+			Detail:   qe.Detail,
+			Hint:     qe.Hint,
+			Position: qe.Position,
+			Line:     qe.Line,
+			Column:   qe.Column,
+			err:      callErr,
+		}
+	}
 
 	*c = Call{
 		id:        CallID(alias.ID),
@@ -89,47 +200,103 @@ func (c *Call) UnmarshalJSON(data []byte) error {
 		timestamp: time.UnixMicro(alias.Timestamp),
 		err:       callErr,
 
-		result:  new(Result),
-		archive: newArchive(CallID(alias.ID)),
+		connID:   ConnectionID(alias.ConnectionID),
+		connName: alias.ConnectionName,
+		kind:     callKindFromString(alias.Kind),
+		database: alias.Database,
+		rowCount: alias.RowCount,
+		parentID: CallID(alias.ParentID),
+		params:   alias.Params.restore(),
+
+		results:     results,
+		archive:     archive,
+		serverQuery: &serverQuery{queryID: alias.QueryID, finished: true},
 
 		done: done,
 	}
 
+	c.rowsAffected.Store(-1)
+	if alias.RowsAffected != nil {
+		c.rowsAffected.Store(*alias.RowsAffected)
+	}
+
 	return nil
 }
 
-func newCallFromExecutor(executor func(context.Context) (ResultStream, error), query string, onEvent func(CallState, *Call)) *Call {
+// callDriver holds optional capabilities of the driver used by a call. Nil fields are unsupported.
+type callDriver struct {
+	// estimator estimates the query of the call before it's executed.
+	estimator func(context.Context) (*Estimate, error)
+	canceler  Canceler
+	parser    ErrorParser
+}
+
+// newCallFromExecutor starts a call of executor. If estimator of the driver is provided, the query is
+// estimated first and if the estimate is over the ConfirmAboveBytes option, the call waits for confirmation.
+func newCallFromExecutor(executor func(context.Context) (ResultStream, error), query string, opts CallOptions, source callSource, driver callDriver, onEvent func(CallState, *Call), onProgress func(CallProgress, *Call)) *Call {
 	id := CallID(uuid.New().String())
+	archive := newArchive(id)
 	c := &Call{
 		id:    id,
 		query: query,
 		state: CallStateUnknown,
 
-		result:  new(Result),
-		archive: newArchive(id),
+		connID:   source.connID,
+		connName: source.connName,
+		kind:     callKindFromString(string(source.kind)),
+		parentID: source.parentID,
+		params:   source.params,
+
+		results:     []*Result{newResult(archive.resultSet(0), int(resultWindowSize.Load()))},
+		archive:     archive,
+		serverQuery: newServerQuery(driver.canceler),
+		confirmCh:   make(chan bool, 1),
 
 		done: make(chan struct{}),
 	}
+	c.rowsAffected.Store(-1)
 
 	eventsCh := make(chan CallState, 10)
+	// cancel can be requested while the call finishes, so sending
+	// events has to be synchronized with closing the channel
+	var eventsMutex sync.Mutex
+	eventsClosed := false
+	sendEvent := func(state CallState) {
+		eventsMutex.Lock()
+		defer eventsMutex.Unlock()
+		if !eventsClosed {
+			eventsCh <- state
+		}
+	}
+	closeEvents := func() {
+		eventsMutex.Lock()
+		defer eventsMutex.Unlock()
+		eventsClosed = true
+		close(eventsCh)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	c.timestamp = time.Now()
 	c.cancelFunc = func() {
-		cancel()
-		c.timeTaken = time.Since(c.timestamp)
-		eventsCh <- CallStateCanceled
+		c.setOutcome(nil)
+		sendEvent(CallStateCanceled)
+		go func() {
+			// stop the query on the server first - some drivers only
+			// close their connection once the context is canceled
+			c.serverQuery.cancel()
+			cancel()
+		}()
 	}
 
-	// event function handler
+	// event function handler - the call is done once its last event is handled,
+	// so that its final state is known when it's done
 	go func() {
+		defer close(c.done)
+
 		for state := range eventsCh {
-			if c.state == CallStateExecutingFailed ||
-				c.state == CallStateRetrievingFailed ||
-				c.state == CallStateCanceled {
-				return
+			if !c.setState(state) {
+				continue
 			}
-			c.state = state
 
 			// trigger event callback
 			if onEvent != nil {
@@ -139,47 +306,195 @@ func newCallFromExecutor(executor func(context.Context) (ResultStream, error), q
 	}()
 
 	go func() {
-		defer close(eventsCh)
+		defer closeEvents()
+
+		// report progress until the call is done
+		progress := newCallProgress(c.timestamp)
+		if onProgress != nil {
+			go progress.watch(c.done, func(p CallProgress) { onProgress(p, c) })
+		}
+
+		sendEvent(CallStateExecuting)
+
+		// waiting for confirmation doesn't count towards the timeout
+		if driver.estimator != nil && opts.ConfirmAboveBytes > 0 {
+			if !c.confirm(ctx, driver.estimator, opts.ConfirmAboveBytes, opts.Timeout, sendEvent) {
+				return
+			}
+		}
+
+		if source.database != nil {
+			database := source.database(ctx)
+			c.metaMutex.Lock()
+			c.database = database
+			c.metaMutex.Unlock()
+		}
+
+		// limit the duration of execution and retrieval
+		reportCtx := context.WithValue(c.serverQuery.context(ctx), rowsAffectedKey{}, &c.rowsAffected)
+		callCtx, cancelCall := context.WithCancel(progress.context(reportCtx))
+		defer cancelCall()
+
+		var timedOut atomic.Bool
+		if opts.Timeout > 0 {
+			timer := time.AfterFunc(opts.Timeout, func() {
+				timedOut.Store(true)
+				c.serverQuery.cancel()
+				cancelCall()
+			})
+			defer timer.Stop()
+		}
+
+		errTimedOut := fmt.Errorf("call timed out after %s", opts.Timeout)
 
 		// execute the function
-		eventsCh <- CallStateExecuting
-		iter, err := executor(ctx)
+		iter, err := executor(callCtx)
 		if err != nil {
-			c.timeTaken = time.Since(c.timestamp)
-			c.err = err
-			eventsCh <- CallStateExecutingFailed
-			close(c.done)
+			c.serverQuery.finish()
+			switch {
+			case ctx.Err() != nil:
+				// canceled - state was already reported
+				c.setOutcome(nil)
+			case timedOut.Load():
+				c.setOutcome(errTimedOut)
+				sendEvent(CallStateTimedOut)
+			default:
+				c.setOutcome(toQueryError(err, driver.parser))
+				sendEvent(CallStateExecutingFailed)
+			}
 			return
 		}
 
-		// set iterator to result
-		err = c.result.SetIter(iter, func() { eventsCh <- CallStateRetrieving })
+		// set iterator to results - rows are archived while they are retrieved
+		limits := newCallLimits(opts)
+		stop := func() {
+			c.serverQuery.cancel()
+			cancelCall()
+		}
+		err = c.retrieveResults(iter, limits, progress, stop, func() { sendEvent(CallStateRetrieving) })
+		if ctx.Err() != nil {
+			// canceled - remove partially archived rows, state was already reported
+			_ = c.archive.clear()
+			return
+		}
 		if err != nil {
-			c.timeTaken = time.Since(c.timestamp)
-			c.err = err
-			eventsCh <- CallStateRetrievingFailed
-			close(c.done)
+			// remove partially archived rows
+			_ = c.archive.clear()
+			if timedOut.Load() {
+				c.setOutcome(errTimedOut)
+				sendEvent(CallStateTimedOut)
+			} else {
+				c.setOutcome(toQueryError(err, driver.parser))
+				sendEvent(CallStateRetrievingFailed)
+			}
 			return
 		}
 
-		// archive the result
-		err = c.archive.setResult(c.result)
+		// finish the archive
+		err = c.finishArchive()
 		if err != nil {
-			c.timeTaken = time.Since(c.timestamp)
-			c.err = err
-			eventsCh <- CallStateArchiveFailed
-			close(c.done)
+			c.setOutcome(err)
+			sendEvent(CallStateArchiveFailed)
 			return
 		}
 
-		c.timeTaken = time.Since(c.timestamp)
-		eventsCh <- CallStateArchived
-		close(c.done)
+		switch {
+		case timedOut.Load():
+			// rows retrieved before the timeout are kept
+			c.setOutcome(errTimedOut)
+			sendEvent(CallStateTimedOut)
+		case limits.truncated:
+			c.setOutcome(nil)
+			sendEvent(CallStateTruncated)
+		default:
+			c.setOutcome(nil)
+			sendEvent(CallStateArchived)
+		}
 	}()
 
 	return c
 }
 
+// retrieveResults drains every result set of the iterator to a separate result.
+// Once the limits are reached, the query is stopped with stop and the remaining rows
+// and result sets are discarded.
+func (c *Call) retrieveResults(iter ResultStream, limits *callLimits, progress *callProgress, stop func(), onFillStart func()) error {
+	defer func() {
+		// drivers read the remaining rows when the iterator is closed,
+		// unless the query is stopped first
+		if limits.truncated {
+			stop()
+		}
+		// the query mustn't be canceled on the server once its connection is released
+		c.serverQuery.finish()
+		iter.Close()
+	}()
+
+	multi, isMulti := iter.(MultiResultStream)
+
+	for set := 0; ; set++ {
+		c.resultsMutex.RLock()
+		result := c.results[set]
+		c.resultsMutex.RUnlock()
+
+		err := result.SetIter(resultSetStream{ResultStream: iter, limits: limits, progress: progress}, onFillStart)
+		if err != nil {
+			return err
+		}
+		// fill start is only reported for the first set
+		onFillStart = nil
+
+		if limits.truncated || !isMulti || !multi.NextResultSet() {
+			return nil
+		}
+
+		c.resultsMutex.Lock()
+		c.results = append(c.results, newResult(c.archive.resultSet(set+1), int(resultWindowSize.Load())))
+		c.resultsMutex.Unlock()
+	}
+}
+
+// finishArchive finishes the archive with all result sets.
+func (c *Call) finishArchive() error {
+	c.resultsMutex.RLock()
+	defer c.resultsMutex.RUnlock()
+
+	err := c.archive.setResults(c.results)
+	if err != nil {
+		return fmt.Errorf("c.archive.setResults: %w", err)
+	}
+	return nil
+}
+
+// resultSetStream limits a stream to its current result set and to the row and byte limits of the call.
+// It also counts rows for the progress of the call. Closing it is left to the call.
+type resultSetStream struct {
+	ResultStream
+	limits   *callLimits
+	progress *callProgress
+}
+
+func (s resultSetStream) HasNext() bool {
+	if s.limits.reached() {
+		if s.ResultStream.HasNext() {
+			s.limits.truncated = true
+		}
+		return false
+	}
+	return s.ResultStream.HasNext()
+}
+
+func (s resultSetStream) Next() (Row, error) {
+	row, err := s.ResultStream.Next()
+	if err == nil {
+		s.limits.add(row)
+		s.progress.addRow()
+	}
+	return row, err
+}
+
+func (resultSetStream) Close() {}
+
 func (c *Call) GetID() CallID {
 	return c.id
 }
@@ -189,29 +504,125 @@ func (c *Call) GetQuery() string {
 }
 
 func (c *Call) GetState() CallState {
+	c.metaMutex.RLock()
+	defer c.metaMutex.RUnlock()
+
 	return c.state
 }
 
+// setState changes the state of the call, unless the call has already failed, was canceled
+// or timed out. It returns false if the state wasn't changed.
+func (c *Call) setState(state CallState) bool {
+	c.metaMutex.Lock()
+	defer c.metaMutex.Unlock()
+
+	switch c.state {
+	case CallStateExecutingFailed, CallStateRetrievingFailed, CallStateCanceled, CallStateTimedOut:
+		return false
+	}
+	c.state = state
+	return true
+}
+
 func (c *Call) GetTimeTaken() time.Duration {
+	c.metaMutex.RLock()
+	defer c.metaMutex.RUnlock()
+
 	return c.timeTaken
 }
 
+// setOutcome records the duration of the call, which stopped with err (nil if it succeeded).
+func (c *Call) setOutcome(err error) {
+	c.metaMutex.Lock()
+	defer c.metaMutex.Unlock()
+
+	c.timeTaken = time.Since(c.timestamp)
+	c.err = err
+}
+
 func (c *Call) GetTimestamp() time.Time {
 	return c.timestamp
 }
 
+// GetConnectionID returns the ID of the connection which executed the call.
+// It's empty for calls restored from history which predates it.
+func (c *Call) GetConnectionID() ConnectionID {
+	return c.connID
+}
+
+// GetConnectionName returns the name the connection had when it executed the call.
+func (c *Call) GetConnectionName() string {
+	return c.connName
+}
+
+// GetDatabase returns the database (or schema) the call was sent to.
+// It's empty if the driver can't tell (see CurrentDatabaser).
+func (c *Call) GetDatabase() string {
+	c.metaMutex.RLock()
+	defer c.metaMutex.RUnlock()
+
+	return c.database
+}
+
+// GetRowCount returns the number of rows retrieved in all result sets.
+func (c *Call) GetRowCount() int {
+	c.resultsMutex.RLock()
+	defer c.resultsMutex.RUnlock()
+
+	count := 0
+	for _, result := range c.results {
+		count += result.Len()
+	}
+
+	return max(count, c.rowCount)
+}
+
+// GetRowsAffected returns the number of rows affected by the query as reported by the driver,
+// or -1 if it's unknown.
+func (c *Call) GetRowsAffected() int64 {
+	return c.rowsAffected.Load()
+}
+
+// GetKind returns the kind of the call, which tells how its result is produced.
+func (c *Call) GetKind() CallKind {
+	return c.kind
+}
+
+// GetParentID returns the ID of the call which was rerun by this call,
+// or an empty ID if the call isn't a rerun.
+func (c *Call) GetParentID() CallID {
+	return c.parentID
+}
+
+// GetParams returns params bound to the query of the call.
+func (c *Call) GetParams() *QueryParams {
+	return c.params
+}
+
+// GetQueryID returns the server side identifier of the query (e.g. query id or job id),
+// if the driver reported it.
+func (c *Call) GetQueryID() string {
+	return c.serverQuery.getQueryID()
+}
+
 func (c *Call) Err() error {
+	c.metaMutex.RLock()
+	defer c.metaMutex.RUnlock()
+
 	return c.err
 }
 
 // Done returns a non-buffered channel that is closed when
-// call finishes.
+// call finishes, after the event of its final state was handled.
 func (c *Call) Done() chan struct{} {
 	return c.done
 }
 
+// Cancel cancels the call while it's executing, retrieving or awaiting confirmation.
+// If the driver supports it, the query is canceled on the server as well.
 func (c *Call) Cancel() {
-	if c.state > CallStateExecuting {
+	state := c.GetState()
+	if (state > CallStateRetrieving && state != CallStateAwaitingConfirmation) || state == CallStateExecutingFailed {
 		return
 	}
 	if c.cancelFunc != nil {
@@ -219,17 +630,72 @@ func (c *Call) Cancel() {
 	}
 }
 
+// GetResult returns the result of the first result set.
 func (c *Call) GetResult() (*Result, error) {
-	if c.result.IsEmpty() {
-		iter, err := c.archive.getResult()
+	return c.GetResultSet(0)
+}
+
+// GetResultSet returns the result of the result set with the provided index.
+func (c *Call) GetResultSet(index int) (*Result, error) {
+	c.resultsMutex.RLock()
+	defer c.resultsMutex.RUnlock()
+
+	if index < 0 || index >= len(c.results) {
+		return nil, fmt.Errorf("result set index out of range: %d (number of result sets: %d)", index, len(c.results))
+	}
+
+	result := c.results[index]
+	if result.IsEmpty() {
+		err := c.archive.restoreResult(index, result)
 		if err != nil {
-			return nil, fmt.Errorf("c.archive.getResult: %w", err)
+			return nil, fmt.Errorf("c.archive.restoreResult: %w", err)
 		}
-		err = c.result.SetIter(iter, nil)
+	}
+
+	return result, nil
+}
+
+// GetResults returns results of all result sets in order.
+func (c *Call) GetResults() ([]*Result, error) {
+	results := make([]*Result, c.GetResultSetCount())
+	for i := range results {
+		result, err := c.GetResultSet(i)
 		if err != nil {
-			return nil, fmt.Errorf("c.result.setIter: %w", err)
+			return nil, err
 		}
+		results[i] = result
 	}
 
-	return c.result, nil
+	return results, nil
+}
+
+// GetResultSetCount returns the number of result sets of the call.
+// While the call is retrieving, more sets might still be added.
+func (c *Call) GetResultSetCount() int {
+	c.resultsMutex.RLock()
+	defer c.resultsMutex.RUnlock()
+
+	return len(c.results)
+}
+
+// ArchiveSize returns the number of bytes archived results of the call take on disk.
+func (c *Call) ArchiveSize() int64 {
+	return c.archive.diskSize()
+}
+
+// ClearArchive removes archived results of the call from disk.
+// Unfinished calls are canceled first and their archive is removed once they finish.
+func (c *Call) ClearArchive() error {
+	select {
+	case <-c.done:
+	default:
+		c.Cancel()
+		<-c.done
+	}
+
+	err := c.archive.clear()
+	if err != nil {
+		return fmt.Errorf("c.archive.clear: %w", err)
+	}
+	return nil
 }
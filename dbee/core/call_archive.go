@@ -1,365 +1,593 @@
 package core
 
 import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
-
-	"golang.org/x/sync/errgroup"
 )
 
 func init() {
 	// gob doesn't know how to encode/decode time otherwise
 	gob.Register(time.Time{})
+
+	archiveDir.Store(filepath.Join(os.TempDir(), "dbee-history"))
 }
 
-const archiveBasePath = "/tmp/dbee-history/"
+var archiveDir atomic.Value
 
-// these variables create a file name for a specified type
-var (
-	archiveDir = func(callID CallID) string {
-		return filepath.Join(archiveBasePath, string(callID))
-	}
+// SetArchiveDir sets the directory where archives of calls are stored.
+// Archives are only readable by the current user.
+func SetArchiveDir(dir string) {
+	archiveDir.Store(dir)
+}
 
-	metaFile = func(callID CallID) string {
-		return filepath.Join(archiveDir(callID), "meta.gob")
-	}
-	headerFile = func(callID CallID) string {
-		return filepath.Join(archiveDir(callID), "header.gob")
-	}
-	rowFile = func(callID CallID, i int) string {
-		return filepath.Join(archiveDir(callID), fmt.Sprintf("row_%d.gob", i))
-	}
-)
+// archiveExtension is the extension of archive files, named by call ID.
+const archiveExtension = ".archive"
+
+// archiveChunkSize is the number of rows stored in a single block of the archive.
+const archiveChunkSize = 500
+
+// archiveMagic marks the start and the end of an archive file.
+var archiveMagic = [8]byte{'D', 'B', 'E', 'E', 'A', 'R', 'C', '1'}
+
+// archiveTrailerSize is the size of the trailer at the end of an archive file:
+// offset and size of the index, followed by the magic.
+const archiveTrailerSize = 8 + 8 + len(archiveMagic)
+
+var archiveFile = func(callID CallID) string {
+	return filepath.Join(archiveDir.Load().(string), string(callID)+archiveExtension)
+}
 
+// archiveIndex describes contents of the archive file.
+type archiveIndex struct {
+	Sets []archiveSet
+}
+
+// archiveSet is the index of a single result set.
+type archiveSet struct {
+	Header Header
+	Meta   Meta
+	Length int
+	Blocks []archiveBlock
+}
+
+// archiveBlock is a compressed chunk of rows in the archive file.
+type archiveBlock struct {
+	// index of the first row in the block
+	First  int
+	Rows   int
+	Offset int64
+	Size   int64
+}
+
+// archive persists all result sets of a call to a single file.
+//
+// The file starts with a magic, followed by blocks of at most archiveChunkSize
+// gob encoded rows, each compressed separately. Once the call finishes, index of
+// all result sets (header, meta and offsets of blocks) is appended and the file
+// ends with a trailer which points to the index. Any range of rows can then be
+// read by decoding only the blocks which contain it.
 type archive struct {
-	id       CallID
+	path string
+	// directory of the archive in the legacy format, which is migrated on first read
+	legacyDir  string
+	legacySets int
+
+	mu       sync.RWMutex
+	file     *os.File // open while the archive is being written
+	size     int64
+	index    archiveIndex
 	isFilled bool
 }
 
+// newArchive returns an empty archive of a new call.
 func newArchive(id CallID) *archive {
-	isFilled := true
-	_, err := os.Stat(archiveDir(id))
-	if os.IsNotExist(err) {
-		isFilled = false
-	}
 	return &archive{
-		id:       id,
-		isFilled: isFilled,
+		path: archiveFile(id),
 	}
 }
 
+// restoreArchive returns the archive of the call found on disk.
+// If the archive doesn't exist or can't be read, it's empty.
+func restoreArchive(id CallID) *archive {
+	a := newArchive(id)
+
+	index, err := readArchiveIndex(a.path)
+	if err == nil {
+		a.index = *index
+		a.isFilled = true
+		return a
+	}
+
+	if sets := legacyArchiveSets(legacyArchiveDir(id)); sets > 0 {
+		a.legacyDir = legacyArchiveDir(id)
+		a.legacySets = sets
+		a.isFilled = true
+	}
+
+	return a
+}
+
 func (a *archive) isEmpty() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	return !a.isFilled
 }
 
-// archive stores the cache record to disk as a set of gob files
-func (a *archive) setResult(result *Result) error {
-	if a.isFilled {
-		return nil
+// setCount returns the number of result sets in the archive.
+func (a *archive) setCount() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.legacyDir != "" {
+		return a.legacySets
 	}
+	return len(a.index.Sets)
+}
 
-	// create the directory for the history record
-	err := os.MkdirAll(archiveDir(a.id), os.ModePerm)
-	if err != nil {
-		return fmt.Errorf("os.MkdirAll: %w", err)
+// resultSet returns the spill of result set with the provided index.
+func (a *archive) resultSet(set int) resultSpill {
+	return archiveSpill{archive: a, set: set}
+}
+
+// indexSet returns the index of the result set, adding empty sets up to it if needed.
+// It expects the lock to be held.
+func (a *archive) indexSet(set int) *archiveSet {
+	for len(a.index.Sets) <= set {
+		a.index.Sets = append(a.index.Sets, archiveSet{})
 	}
+	return &a.index.Sets[set]
+}
 
-	// serialize the data
-	// files inside the directory ..../call_id/:
-	// header.gob - header
-	// meta.gob - meta
-	// row_0.gob - first row
-	// row_n.gob - n-th row
+// open creates the archive file if it's not open yet.
+// It expects the lock to be held.
+func (a *archive) open() error {
+	if a.file != nil {
+		return nil
+	}
 
-	// header
-	file, err := os.Create(headerFile(a.id))
+	// archives are only readable by the current user, even if the directory already existed
+	err := os.MkdirAll(filepath.Dir(a.path), 0o700)
 	if err != nil {
-		return fmt.Errorf("os.Create: %w", err)
+		return fmt.Errorf("os.MkdirAll: %w", err)
 	}
-	defer file.Close()
-
-	encoder := gob.NewEncoder(file)
-	err = encoder.Encode(result.Header())
+	err = os.Chmod(filepath.Dir(a.path), 0o700)
 	if err != nil {
-		return fmt.Errorf("encoder.Encode: %w", err)
+		return fmt.Errorf("os.Chmod: %w", err)
 	}
 
-	// meta
-	file, err = os.Create(metaFile(a.id))
+	file, err := os.OpenFile(a.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
-		return err
+		return fmt.Errorf("os.OpenFile: %w", err)
 	}
-	defer file.Close()
 
-	encoder = gob.NewEncoder(file)
-	err = encoder.Encode(*result.Meta())
+	_, err = file.Write(archiveMagic[:])
 	if err != nil {
-		return err
+		file.Close()
+		return fmt.Errorf("file.Write: %w", err)
 	}
 
-	// rows
-	chunkSize := 500
-	length := len(result.rows)
-
-	// write chunks concurrently
-	g := &errgroup.Group{}
-	g.SetLimit(10)
-	for i := 0; i <= length/chunkSize; i++ {
-		i := i
-		g.Go(func() error {
-			// get chunk
-			chunkStart := chunkSize * i
-			chunkEnd := chunkSize * (i + 1)
-			if chunkEnd > length {
-				chunkEnd = length
-			}
-			chunk, err := result.Rows(chunkStart, chunkEnd)
-			if err != nil {
-				return err
-			}
-			if len(chunk) == 0 {
-				return nil
-			}
+	a.file = file
+	a.size = int64(len(archiveMagic))
 
-			file, err := os.Create(rowFile(a.id, i))
-			if err != nil {
-				return fmt.Errorf("os.Create: %w", err)
-			}
-			defer file.Close()
+	return nil
+}
 
-			encoder := gob.NewEncoder(file)
-			err = encoder.Encode(chunk)
-			if err != nil {
-				return fmt.Errorf("encoder.Encode: %w", err)
-			}
+// writeRows appends rows of the result set to the archive file in blocks of archiveChunkSize rows.
+// Rows have to be written in order, so "from" is expected to match the number of archived rows.
+func (a *archive) writeRows(set, from int, rows []Row) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-			return nil
-		})
+	if a.isFilled {
+		return errors.New("archive is already finished")
 	}
-	if err := g.Wait(); err != nil {
+
+	err := a.open()
+	if err != nil {
 		return err
 	}
 
-	a.isFilled = true
+	s := a.indexSet(set)
+	if from != s.Length {
+		return fmt.Errorf("rows have to be appended to the end of the archive: %d (archived rows: %d)", from, s.Length)
+	}
 
-	return nil
-}
+	for start := 0; start < len(rows); start += archiveChunkSize {
+		end := min(start+archiveChunkSize, len(rows))
 
-// unarchive loads result from archive in form of an iterator
-func (a *archive) getResult() (*archiveRows, error) {
-	if !a.isFilled {
-		return nil, errors.New("archive does not contain a result")
+		block, err := encodeArchiveBlock(rows[start:end])
+		if err != nil {
+			return err
+		}
+
+		_, err = a.file.Write(block)
+		if err != nil {
+			return fmt.Errorf("file.Write: %w", err)
+		}
+
+		s.Blocks = append(s.Blocks, archiveBlock{
+			First:  s.Length,
+			Rows:   end - start,
+			Offset: a.size,
+			Size:   int64(len(block)),
+		})
+		s.Length += end - start
+		a.size += int64(len(block))
 	}
-	return newArchiveRows(a.id)
-}
 
-type archiveRows struct {
-	id      CallID
-	header  Header
-	meta    *Meta
-	iter    func() (Row, error)
-	hasNext func() bool
+	return nil
 }
 
-func newArchiveRows(id CallID) (*archiveRows, error) {
-	r := &archiveRows{
-		id: id,
+// readRows reads rows of the result set in range [from, to).
+// Only the blocks which contain the range are read and decoded.
+func (a *archive) readRows(set, from, to int) ([]Row, error) {
+	if from >= to {
+		return []Row{}, nil
 	}
 
-	err := r.readHeader()
+	err := a.load()
 	if err != nil {
 		return nil, err
 	}
-	err = r.readMeta()
+
+	a.mu.RLock()
+	if set >= len(a.index.Sets) {
+		a.mu.RUnlock()
+		return nil, fmt.Errorf("archive does not contain result set: %d", set)
+	}
+	// blocks are only ever appended, so the snapshot stays valid
+	blocks := a.index.Sets[set].Blocks
+	a.mu.RUnlock()
+
+	file, err := os.Open(a.path)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer file.Close()
+
+	first := sort.Search(len(blocks), func(i int) bool {
+		return blocks[i].First+blocks[i].Rows > from
+	})
+
+	rows := make([]Row, 0, to-from)
+	for _, block := range blocks[first:] {
+		if block.First >= to {
+			break
+		}
+
+		var chunk []Row
+		err := decodeArchiveBlock(file, block.Offset, block.Size, &chunk)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk) != block.Rows {
+			return nil, fmt.Errorf("archive block at offset %d is corrupted", block.Offset)
+		}
+
+		start := max(from-block.First, 0)
+		end := min(to-block.First, len(chunk))
+		rows = append(rows, chunk[start:end]...)
 	}
 
-	r.readIter()
+	if len(rows) != to-from {
+		return nil, fmt.Errorf("archive does not contain rows in range: %d ... %d", from, to)
+	}
 
-	return r, nil
+	return rows, nil
 }
 
-func (r *archiveRows) readHeader() error {
-	// header
-	var header Header
-	file, err := os.Open(headerFile(r.id))
+// setResults finishes the archive by appending the index with headers and meta
+// of all results. Rows themselves are written by results while they are being retrieved.
+func (a *archive) setResults(results []*Result) error {
+	for i, result := range results {
+		err := result.spillError()
+		if err != nil {
+			return fmt.Errorf("result set %d: result.spillError: %w", i, err)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.isFilled {
+		return nil
+	}
+
+	err := a.open()
 	if err != nil {
-		return fmt.Errorf("os.Open: %w", err)
+		return err
+	}
+
+	for i, result := range results {
+		s := a.indexSet(i)
+		s.Header = result.Header()
+		s.Meta = *result.Meta()
 	}
-	defer file.Close()
 
-	decoder := gob.NewDecoder(file)
-	err = decoder.Decode(&header)
+	index, err := encodeArchiveBlock(a.index)
 	if err != nil {
-		return fmt.Errorf("decoder.Decode: %w", err)
+		return err
 	}
 
-	r.header = header
+	trailer := make([]byte, archiveTrailerSize)
+	binary.LittleEndian.PutUint64(trailer[0:8], uint64(a.size))
+	binary.LittleEndian.PutUint64(trailer[8:16], uint64(len(index)))
+	copy(trailer[16:], archiveMagic[:])
+
+	_, err = a.file.Write(append(index, trailer...))
+	if err != nil {
+		return fmt.Errorf("file.Write: %w", err)
+	}
+
+	err = a.file.Close()
+	a.file = nil
+	if err != nil {
+		return fmt.Errorf("file.Close: %w", err)
+	}
+
+	a.isFilled = true
 
 	return nil
 }
 
-func (r *archiveRows) readMeta() error {
-	// meta
-	var meta Meta
-	file, err := os.Open(metaFile(r.id))
+// load migrates the archive if it's stored in the legacy format.
+func (a *archive) load() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.legacyDir == "" {
+		return nil
+	}
+
+	err := migrateLegacyArchive(a.legacyDir, a.path)
 	if err != nil {
-		return fmt.Errorf("os.Open: %w", err)
+		return fmt.Errorf("migrateLegacyArchive: %w", err)
 	}
-	defer file.Close()
 
-	decoder := gob.NewDecoder(file)
-	err = decoder.Decode(&meta)
+	index, err := readArchiveIndex(a.path)
 	if err != nil {
-		return fmt.Errorf("decoder.Decode: %w", err)
+		return err
 	}
 
-	r.meta = &meta
+	a.index = *index
+	a.legacyDir = ""
+	a.legacySets = 0
 
 	return nil
 }
 
-// closeOnce closes the channel if it isn't already closed.
-func closeOnce[T any](ch chan T) {
-	select {
-	case <-ch:
-	default:
-		close(ch)
+// restoreResult fills the result with archived header and meta of the result set.
+// Rows are read from the archive when they are requested.
+func (a *archive) restoreResult(set int, result *Result) error {
+	if a.isEmpty() {
+		return errors.New("archive does not contain a result")
 	}
+
+	err := a.load()
+	if err != nil {
+		return err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if set >= len(a.index.Sets) {
+		return fmt.Errorf("archive does not contain result set: %d", set)
+	}
+	s := a.index.Sets[set]
+	meta := s.Meta
+
+	result.setSpilled(s.Header, &meta, s.Length)
+
+	return nil
 }
 
-// readIter creates next and hasNext functions.
-// This method is basically the same as builders/NextYield, but is copy-pasted
-// because of import cycles.
-func (r *archiveRows) readIter() {
-	// open the first file if it exists,
-	// loop through its contents and try the next file
-	fileExists := func(rowIndex int) bool {
-		_, err := os.Stat(rowFile(r.id, rowIndex))
-		return err == nil
+// diskSize returns the number of bytes the archive takes on disk.
+func (a *archive) diskSize() int64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.legacyDir != "" {
+		var size int64
+		_ = filepath.WalkDir(a.legacyDir, func(_ string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+			return nil
+		})
+		return size
 	}
 
-	// openFile returns rows of the file
-	openFile := func(i int) ([]Row, error) {
-		file, err := os.Open(rowFile(r.id, i))
-		if err != nil {
-			return nil, fmt.Errorf("os.Open: %w", err)
-		}
-		defer file.Close()
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
 
-		var rows []Row
+// clear removes the archive from disk.
+func (a *archive) clear() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-		decoder := gob.NewDecoder(file)
-		err = decoder.Decode(&rows)
+	if a.file != nil {
+		_ = a.file.Close()
+		a.file = nil
+	}
+	a.size = 0
+	a.index = archiveIndex{}
+	a.isFilled = false
+
+	if a.legacyDir != "" {
+		err := os.RemoveAll(a.legacyDir)
 		if err != nil {
-			return nil, fmt.Errorf("decoder.Decode: %w", err)
+			return fmt.Errorf("os.RemoveAll: %w", err)
 		}
-
-		return rows, nil
+		a.legacyDir = ""
+		a.legacySets = 0
 	}
 
-	resultsCh := make(chan []any, 10)
-	errorsCh := make(chan error, 1)
-	readyCh := make(chan struct{})
-	doneCh := make(chan struct{})
+	err := os.Remove(a.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("os.Remove: %w", err)
+	}
+	return nil
+}
 
-	// spawn channel function
-	go func() {
-		defer func() {
-			close(doneCh)
-			closeOnce(readyCh)
-			close(resultsCh)
-			close(errorsCh)
-		}()
+// archiveSpill is the spill of a single result set of the archive.
+type archiveSpill struct {
+	archive *archive
+	set     int
+}
 
-		file := 0
-		for {
-			if !fileExists(file) {
-				return
-			}
-			rows, err := openFile(file)
-			if err != nil {
-				errorsCh <- err
-				return
-			}
+var _ resultSpill = archiveSpill{}
 
-			for _, row := range rows {
-				resultsCh <- row
-				closeOnce(readyCh)
-			}
+func (s archiveSpill) writeRows(from int, rows []Row) error {
+	return s.archive.writeRows(s.set, from, rows)
+}
 
-			file++
-		}
-	}()
+func (s archiveSpill) readRows(from, to int) ([]Row, error) {
+	return s.archive.readRows(s.set, from, to)
+}
 
-	<-readyCh
+// readArchiveIndex reads the index of a finished archive file.
+func readArchiveIndex(path string) (*archiveIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer file.Close()
 
-	var nextVal atomic.Value
-	var nextErr atomic.Value
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("file.Stat: %w", err)
+	}
 
-	r.hasNext = func() bool {
-		select {
-		case vals, ok := <-resultsCh:
-			if !ok {
-				return false
-			}
-			nextVal.Store(vals)
-			return true
-		case err := <-errorsCh:
-			if err != nil {
-				nextErr.Store(err)
-				return false
-			}
-		case <-doneCh:
-			if len(resultsCh) < 1 {
-				return false
-			}
-		case <-time.After(5 * time.Second):
-			nextErr.Store(errors.New("next row timeout"))
-			return false
-		}
+	magic := make([]byte, len(archiveMagic))
+	trailer := make([]byte, archiveTrailerSize)
+	if info.Size() < int64(len(magic)+len(trailer)) {
+		return nil, errors.New("archive is not finished")
+	}
 
-		return r.hasNext()
+	_, err = file.ReadAt(magic, 0)
+	if err != nil {
+		return nil, fmt.Errorf("file.ReadAt: %w", err)
+	}
+	_, err = file.ReadAt(trailer, info.Size()-int64(len(trailer)))
+	if err != nil {
+		return nil, fmt.Errorf("file.ReadAt: %w", err)
+	}
+	if !bytes.Equal(magic, archiveMagic[:]) || !bytes.Equal(trailer[16:], archiveMagic[:]) {
+		return nil, errors.New("archive is not finished")
 	}
 
-	r.iter = func() (Row, error) {
-		var val Row
-		var err error
+	offset := int64(binary.LittleEndian.Uint64(trailer[0:8]))
+	size := int64(binary.LittleEndian.Uint64(trailer[8:16]))
 
-		nval := nextVal.Load()
-		if nval != nil {
-			val = nval.([]any)
-		}
-		nerr := nextErr.Load()
-		if nerr != nil {
-			err = nerr.(error)
-		}
-		return val, err
+	var index archiveIndex
+	err = decodeArchiveBlock(file, offset, size, &index)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func (r *archiveRows) Meta() *Meta {
-	return r.meta
+	return &index, nil
 }
 
-func (r *archiveRows) Header() Header {
-	return r.header
+// encodeArchiveBlock encodes value with gob and compresses it.
+// Every block has its own encoder, so that it can be decoded on its own.
+func encodeArchiveBlock(value any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, fmt.Errorf("flate.NewWriter: %w", err)
+	}
+
+	err = gob.NewEncoder(writer).Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("encoder.Encode: %w", err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("writer.Close: %w", err)
+	}
+
+	return buf.Bytes(), nil
 }
 
-func (r *archiveRows) Next() (Row, error) {
-	return r.iter()
+// decodeArchiveBlock decodes a block of size bytes at offset of file to value.
+func decodeArchiveBlock(file io.ReaderAt, offset, size int64, value any) error {
+	reader := flate.NewReader(io.NewSectionReader(file, offset, size))
+	defer reader.Close()
+
+	err := gob.NewDecoder(reader).Decode(value)
+	if err != nil {
+		return fmt.Errorf("decoder.Decode: %w", err)
+	}
+
+	return nil
 }
 
-func (r *archiveRows) HasNext() bool {
-	return r.hasNext()
+// ArchiveExists reports whether the archive of the call (in either format) is on disk.
+func ArchiveExists(callID CallID) bool {
+	_, err := os.Stat(archiveFile(callID))
+	if err == nil {
+		return true
+	}
+	return legacyArchiveSets(legacyArchiveDir(callID)) > 0
 }
 
-func (r *archiveRows) Close() {
-	// no-op
+// RemoveOrphanedArchives removes archives in the archive directory which were not modified for
+// at least minAge and don't belong to any of the known calls (e.g. leftovers of crashed sessions).
+func RemoveOrphanedArchives(isKnown func(CallID) bool, minAge time.Duration) error {
+	dir := archiveDir.Load().(string)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("os.ReadDir: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.Contains(name, archiveExtension) {
+			continue
+		}
+
+		id := CallID(name[:strings.Index(name, archiveExtension)])
+		if isKnown(id) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < minAge {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("os.Remove: %w", err)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Archives were previously stored as a directory of gob files per call:
+//
+// ..../call_id/ (or ..../call_id/set_n/ for n-th result set):
+// header.gob - header
+// meta.gob - meta
+// row_0.gob - first chunk of archiveChunkSize rows
+// row_n.gob - n-th chunk of rows
+//
+// These archives are migrated to the single file format when they are first read.
+const legacyArchiveBasePath = "/tmp/dbee-history/"
+
+var (
+	legacyArchiveDir = func(callID CallID) string {
+		return filepath.Join(legacyArchiveBasePath, string(callID))
+	}
+	legacyResultSetDir = func(dir string, set int) string {
+		if set == 0 {
+			return dir
+		}
+		return filepath.Join(dir, fmt.Sprintf("set_%d", set))
+	}
+
+	legacyMetaFile = func(dir string) string {
+		return filepath.Join(dir, "meta.gob")
+	}
+	legacyHeaderFile = func(dir string) string {
+		return filepath.Join(dir, "header.gob")
+	}
+	legacyRowFile = func(dir string, i int) string {
+		return filepath.Join(dir, fmt.Sprintf("row_%d.gob", i))
+	}
+)
+
+// legacyArchiveSets returns the number of finished result sets in the legacy archive directory.
+func legacyArchiveSets(dir string) int {
+	sets := 0
+	for {
+		_, err := os.Stat(legacyHeaderFile(legacyResultSetDir(dir, sets)))
+		if err != nil {
+			return sets
+		}
+		sets++
+	}
+}
+
+// migrateLegacyArchive converts the legacy archive directory to an archive file at path
+// and removes the directory. Rows are copied chunk by chunk, so that only a single
+// chunk is held in memory at a time.
+func migrateLegacyArchive(dir, path string) error {
+	sets := legacyArchiveSets(dir)
+	if sets == 0 {
+		return errors.New("legacy archive does not contain a result")
+	}
+
+	tmp := &archive{path: path + ".tmp"}
+
+	results := make([]*Result, sets)
+	for set := range results {
+		setDir := legacyResultSetDir(dir, set)
+
+		var header Header
+		err := readGob(legacyHeaderFile(setDir), &header)
+		if err != nil {
+			_ = tmp.clear()
+			return err
+		}
+
+		var meta Meta
+		err = readGob(legacyMetaFile(setDir), &meta)
+		if err != nil {
+			_ = tmp.clear()
+			return err
+		}
+
+		length := 0
+		for i := 0; ; i++ {
+			_, err := os.Stat(legacyRowFile(setDir, i))
+			if err != nil {
+				break
+			}
+
+			var chunk []Row
+			err = readGob(legacyRowFile(setDir, i), &chunk)
+			if err != nil {
+				_ = tmp.clear()
+				return err
+			}
+
+			err = tmp.writeRows(set, length, chunk)
+			if err != nil {
+				_ = tmp.clear()
+				return err
+			}
+			length += len(chunk)
+		}
+
+		results[set] = new(Result)
+		results[set].setSpilled(header, &meta, length)
+	}
+
+	err := tmp.setResults(results)
+	if err != nil {
+		_ = tmp.clear()
+		return err
+	}
+
+	err = os.Rename(tmp.path, path)
+	if err != nil {
+		_ = tmp.clear()
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+
+	err = os.RemoveAll(dir)
+	if err != nil {
+		return fmt.Errorf("os.RemoveAll: %w", err)
+	}
+
+	return nil
+}
+
+func readGob(path string, value any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("os.Open: %w", err)
+	}
+	defer file.Close()
+
+	err = gob.NewDecoder(file).Decode(value)
+	if err != nil {
+		return fmt.Errorf("decoder.Decode: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// serverCancelTimeout limits the duration of canceling a query on the server.
+const serverCancelTimeout = 10 * time.Second
+
+type queryIDKey struct{}
+
+// ReportQueryID reports the server side identifier of the query executed with ctx
+// (e.g. query id or job id), which is recorded with the call and later passed to
+// Canceler.CancelQuery. Drivers call it from Query, as soon as the identifier is known.
+// It's a no-op if ctx doesn't belong to a call.
+func ReportQueryID(ctx context.Context, id string) {
+	q, ok := ctx.Value(queryIDKey{}).(*serverQuery)
+	if !ok {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queryID = id
+	if !q.finished {
+		q.id = id
+	}
+}
+
+// ReportSessionID reports the server side identifier of the session which executes the query
+// with ctx (e.g. backend pid or connection id). Unlike ReportQueryID, the identifier isn't recorded
+// with the call, it's only passed to Canceler.CancelQuery.
+func ReportSessionID(ctx context.Context, id string) {
+	q, ok := ctx.Value(queryIDKey{}).(*serverQuery)
+	if !ok {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.finished {
+		q.id = id
+	}
+}
+
+// WantsQueryID reports if the query executed with ctx belongs to a call, which is interested
+// in the server side identifier of the query. Drivers can use it to skip additional round trips
+// which are needed to obtain the identifier (e.g. for internal queries).
+func WantsQueryID(ctx context.Context) bool {
+	_, ok := ctx.Value(queryIDKey{}).(*serverQuery)
+	return ok
+}
+
+// FinishQuery marks the query executed with ctx as finished, so that it's not canceled on the server
+// anymore. Drivers which report the identifier of a session (see ReportSessionID) call it before the
+// connection of the query is released, as the session can execute other queries afterwards.
+// If the query is being canceled, FinishQuery waits for the cancellation to complete.
+// It's a no-op if ctx doesn't belong to a call.
+func FinishQuery(ctx context.Context) {
+	q, ok := ctx.Value(queryIDKey{}).(*serverQuery)
+	if !ok {
+		return
+	}
+
+	q.finish()
+}
+
+// serverQuery tracks the query of a call running on the server, so that it can be canceled there.
+type serverQuery struct {
+	// canceler is nil if driver can't cancel queries
+	canceler Canceler
+
+	mu sync.Mutex
+	// id is the identifier passed to canceler and queryID the one recorded with the call
+	id       string
+	queryID  string
+	finished bool
+}
+
+func newServerQuery(canceler Canceler) *serverQuery {
+	return &serverQuery{
+		canceler: canceler,
+	}
+}
+
+// context returns a context on which the driver can report the query id.
+func (q *serverQuery) context(ctx context.Context) context.Context {
+	if q == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, queryIDKey{}, q)
+}
+
+// cancel cancels the query on the server if it's still running. It's best effort:
+// errors are ignored, as the client side of the call is canceled anyway.
+func (q *serverQuery) cancel() {
+	if q == nil || q.canceler == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.finished || q.id == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverCancelTimeout)
+	defer cancel()
+	_ = q.canceler.CancelQuery(ctx, q.id)
+}
+
+// finish marks the query as finished, so it's not canceled anymore.
+// It has to be called before the connection of the query is released, otherwise some other
+// query executed on the same connection could be canceled instead. If the query is being
+// canceled, finish waits for the cancellation to complete.
+func (q *serverQuery) finish() {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.finished = true
+}
+
+// getQueryID returns the identifier of the query reported by the driver.
+func (q *serverQuery) getQueryID() string {
+	if q == nil {
+		return ""
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.queryID
+}
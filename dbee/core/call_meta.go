@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type rowsAffectedKey struct{}
+
+// ReportRowsAffected reports the number of rows affected by the query executed with ctx
+// (e.g. by an insert or an update), which is recorded with the call.
+// It's a no-op if ctx doesn't belong to a call.
+func ReportRowsAffected(ctx context.Context, n int64) {
+	affected, ok := ctx.Value(rowsAffectedKey{}).(*atomic.Int64)
+	if !ok {
+		return
+	}
+	affected.Store(n)
+}
+
+// CallKind tells how the result of a call is produced.
+type CallKind string
+
+const (
+	// CallKindQuery is the kind of calls whose query is executed by the driver.
+	CallKindQuery CallKind = "query"
+	// CallKindDiff is the kind of calls comparing results of two calls (see DiffResults).
+	CallKindDiff CallKind = "diff"
+	// CallKindColumnStats is the kind of calls computing stats of a result (see ColumnStats).
+	CallKindColumnStats CallKind = "column_stats"
+	// CallKindFanOut is the kind of calls combining results of the query executed on many connections.
+	CallKindFanOut CallKind = "fan_out"
+)
+
+// IsDerived reports whether results of the calls of the kind are derived from other results,
+// instead of being returned by the driver. Derived calls can't be rerun.
+func (k CallKind) IsDerived() bool {
+	return k != CallKindQuery
+}
+
+// callKindFromString returns the kind from its persisted form. Calls persisted before
+// kinds were recorded are queries.
+func callKindFromString(s string) CallKind {
+	if s == "" {
+		return CallKindQuery
+	}
+	return CallKind(s)
+}
+
+// callSource describes where a call comes from: the connection which executes it
+// and the call it reruns.
+type callSource struct {
+	connID   ConnectionID
+	connName string
+	// kind of the call, zero value is CallKindQuery.
+	kind CallKind
+	// database returns the database the query is sent to. It's called right
+	// before the query is executed and returns an empty string if unknown.
+	database func(context.Context) string
+	// parentID is the ID of the rerun call, empty if the call isn't a rerun.
+	parentID CallID
+	// params are bound to the query, they are kept with the call so that it can be rerun.
+	params *QueryParams
+}
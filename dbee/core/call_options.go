@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// CallOptions limit the execution of a single call.
+// Non-positive values mean no limit.
+type CallOptions struct {
+	// Timeout is the maximum duration of execution and retrieval of the call.
+	Timeout time.Duration
+	// MaxRows is the maximum number of rows retrieved by the call (across all result sets).
+	MaxRows int
+	// MaxBytes is the maximum (approximate) size of rows retrieved by the call.
+	MaxBytes int
+	// ConfirmAboveBytes is the estimated number of bytes processed by the query above which
+	// the call waits for confirmation before it's executed (only if the driver can estimate queries).
+	ConfirmAboveBytes int
+}
+
+// Override returns a copy of options with non-zero values of overrides applied.
+// Negative override values can be used to disable a limit.
+func (o CallOptions) Override(overrides *CallOptions) CallOptions {
+	if overrides == nil {
+		return o
+	}
+
+	if overrides.Timeout != 0 {
+		o.Timeout = overrides.Timeout
+	}
+	if overrides.MaxRows != 0 {
+		o.MaxRows = overrides.MaxRows
+	}
+	if overrides.MaxBytes != 0 {
+		o.MaxBytes = overrides.MaxBytes
+	}
+	if overrides.ConfirmAboveBytes != 0 {
+		o.ConfirmAboveBytes = overrides.ConfirmAboveBytes
+	}
+
+	return o
+}
+
+// callLimits tracks rows and bytes retrieved by a call.
+type callLimits struct {
+	maxRows  int
+	maxBytes int
+
+	rows      int
+	bytes     int
+	truncated bool
+}
+
+func newCallLimits(opts CallOptions) *callLimits {
+	return &callLimits{
+		maxRows:  opts.MaxRows,
+		maxBytes: opts.MaxBytes,
+	}
+}
+
+// reached reports if any of the limits is reached.
+func (l *callLimits) reached() bool {
+	return (l.maxRows > 0 && l.rows >= l.maxRows) ||
+		(l.maxBytes > 0 && l.bytes >= l.maxBytes)
+}
+
+func (l *callLimits) add(row Row) {
+	l.rows++
+	l.bytes += rowSize(row)
+}
+
+// rowSize returns the approximate size of row's values in bytes.
+func rowSize(row Row) int {
+	size := 0
+	for _, value := range row {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			size += len(v)
+		case []byte:
+			size += len(v)
+		case bool, int8, uint8:
+			size++
+		case int16, uint16:
+			size += 2
+		case int32, uint32, float32:
+			size += 4
+		case int, uint, int64, uint64, float64, time.Time:
+			size += 8
+		default:
+			size += len(fmt.Sprint(v))
+		}
+	}
+	return size
+}
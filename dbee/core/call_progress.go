@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is the minimum interval between two progress reports of a call.
+const progressInterval = 250 * time.Millisecond
+
+// CallProgress is the progress of a call which is executing or retrieving.
+type CallProgress struct {
+	// Rows is the number of rows retrieved so far (across all result sets).
+	Rows int64
+	// EstimatedRows is the estimated total number of rows (0 if unknown).
+	EstimatedRows int64
+	// RowsRead and BytesRead are processed by the server so far (0 if not reported by the driver).
+	RowsRead  int64
+	BytesRead int64
+	// Elapsed is the time since the start of the call.
+	Elapsed time.Duration
+}
+
+// QueryProgress is progress of a query as reported by the driver.
+// All values are increments since the previous report.
+type QueryProgress struct {
+	RowsRead  int64
+	BytesRead int64
+	// TotalRows is an estimate of the total number of rows.
+	TotalRows int64
+}
+
+type queryProgressKey struct{}
+
+// ReportQueryProgress adds progress of the query executed with ctx to the progress of the call.
+// Drivers which know more about the progress than the number of retrieved rows (e.g. rows read by
+// the server or the estimated total) can call it while executing the query or retrieving its rows.
+func ReportQueryProgress(ctx context.Context, progress QueryProgress) {
+	p, ok := ctx.Value(queryProgressKey{}).(*callProgress)
+	if !ok {
+		return
+	}
+
+	p.rowsRead.Add(progress.RowsRead)
+	p.bytesRead.Add(progress.BytesRead)
+	p.totalRows.Add(progress.TotalRows)
+}
+
+// callProgress collects progress of a call from the result and the driver.
+type callProgress struct {
+	start time.Time
+
+	rows      atomic.Int64
+	rowsRead  atomic.Int64
+	bytesRead atomic.Int64
+	totalRows atomic.Int64
+}
+
+func newCallProgress(start time.Time) *callProgress {
+	return &callProgress{
+		start: start,
+	}
+}
+
+// context returns a context on which the driver can report the query progress.
+func (p *callProgress) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryProgressKey{}, p)
+}
+
+// addRow is called for every retrieved row.
+func (p *callProgress) addRow() {
+	p.rows.Add(1)
+}
+
+func (p *callProgress) snapshot() CallProgress {
+	return CallProgress{
+		Rows:          p.rows.Load(),
+		EstimatedRows: p.totalRows.Load(),
+		RowsRead:      p.rowsRead.Load(),
+		BytesRead:     p.bytesRead.Load(),
+		Elapsed:       time.Since(p.start),
+	}
+}
+
+// watch calls report every progressInterval if the progress changed, until done is closed.
+func (p *callProgress) watch(done <-chan struct{}, report func(CallProgress)) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	var last CallProgress
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			current := p.snapshot()
+
+			// elapsed time alone is not worth reporting
+			changed := current
+			changed.Elapsed = last.Elapsed
+			if changed == last {
+				continue
+			}
+			last = current
+
+			report(current)
+		}
+	}
+}
@@ -11,6 +11,9 @@ const (
 	CallStateArchived
 	CallStateArchiveFailed
 	CallStateCanceled
+	CallStateTruncated
+	CallStateTimedOut
+	CallStateAwaitingConfirmation
 )
 
 func CallStateFromString(s string) CallState {
@@ -36,6 +39,14 @@ func CallStateFromString(s string) CallState {
 	case CallStateCanceled.String():
 		return CallStateCanceled
 
+	case CallStateTruncated.String():
+		return CallStateTruncated
+	case CallStateTimedOut.String():
+		return CallStateTimedOut
+
+	case CallStateAwaitingConfirmation.String():
+		return CallStateAwaitingConfirmation
+
 	default:
 		return CallStateUnknown
 	}
@@ -64,6 +75,14 @@ func (s CallState) String() string {
 	case CallStateCanceled:
 		return "canceled"
 
+	case CallStateTruncated:
+		return "truncated"
+	case CallStateTimedOut:
+		return "timed_out"
+
+	case CallStateAwaitingConfirmation:
+		return "awaiting_confirmation"
+
 	default:
 		return "unknown"
 	}
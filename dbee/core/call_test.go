@@ -2,11 +2,18 @@ package core_test
 
 import (
 	"context"
+	"encoding/gob"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
@@ -22,6 +29,9 @@ func TestCall_Success(t *testing.T) {
 		mock.AdapterWithResultStreamOpts(mock.ResultStreamWithNextSleep(300*time.Millisecond)),
 	))
 	r.NoError(err)
+	// calls of a connection which isn't connected fail with "connection not established",
+	// so every test connects before executing
+	r.NoError(connection.Connect())
 
 	expectedEvents := []core.CallState{
 		core.CallStateExecuting,
@@ -78,6 +88,7 @@ func TestCall_Cancel(t *testing.T) {
 
 	connection, err := core.NewConnection(&core.ConnectionParams{}, adapter)
 	r.NoError(err)
+	r.NoError(connection.Connect())
 
 	expectedEvents := []core.CallState{
 		core.CallStateExecuting,
@@ -106,6 +117,103 @@ func TestCall_Cancel(t *testing.T) {
 	r.Equal(len(expectedEvents), eventIndex)
 }
 
+func TestCall_CancelRetrieving(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 10)
+
+	canceledQueries := make(chan string, 1)
+	adapter := mock.NewAdapter(rows,
+		mock.AdapterWithResultStreamOpts(mock.ResultStreamWithNextSleep(100*time.Millisecond)),
+		mock.AdapterWithCancelQuery(func(queryID string) {
+			canceledQueries <- queryID
+		}),
+	)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, adapter)
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.Execute("select", func(state core.CallState, c *core.Call) {
+		if state == core.CallStateRetrieving {
+			c.Cancel()
+		}
+	})
+
+	// query should be canceled on the server
+	select {
+	case queryID := <-canceledQueries:
+		r.Equal("select", queryID)
+	case <-time.After(5 * time.Second):
+		t.Error("query was not canceled on the server")
+	}
+
+	select {
+	case <-call.Done():
+		time.Sleep(100 * time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+	r.Equal(core.CallStateCanceled, call.GetState())
+}
+
+func TestCall_CancelReleasedQuery(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 10)
+
+	var canceled atomic.Bool
+	cancelStarted := make(chan struct{})
+	var startCancel sync.Once
+	var releasedAfterCancel atomic.Bool
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(rows,
+		// connection is released before the error is returned and the call times out in between
+		mock.AdapterWithQuerySideEffect("released", func(ctx context.Context) error {
+			core.FinishQuery(ctx)
+			time.Sleep(300 * time.Millisecond)
+			return errors.New("query failed")
+		}),
+		// connection is released while the query is being canceled
+		mock.AdapterWithQuerySideEffect("canceling", func(ctx context.Context) error {
+			<-cancelStarted
+			core.FinishQuery(ctx)
+			releasedAfterCancel.Store(canceled.Load())
+			return errors.New("query failed")
+		}),
+		mock.AdapterWithCancelQuery(func(queryID string) {
+			if queryID == "canceling" {
+				startCancel.Do(func() { close(cancelStarted) })
+				time.Sleep(200 * time.Millisecond)
+			}
+			canceled.Store(true)
+		}),
+	))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	execute := func(query string) *core.Call {
+		call := connection.ExecuteWithOptions(query, nil, &core.CallOptions{Timeout: 50 * time.Millisecond}, nil, nil)
+		select {
+		case <-call.Done():
+			time.Sleep(100 * time.Millisecond)
+		case <-time.After(5 * time.Second):
+			t.Fatal("call did not finish in expected time")
+		}
+		return call
+	}
+
+	// session of the released connection might already execute another query
+	call := execute("released")
+	r.Equal(core.CallStateTimedOut, call.GetState())
+	r.False(canceled.Load())
+
+	// cancellation in flight is completed before the connection is released
+	call = execute("canceling")
+	r.Equal(core.CallStateTimedOut, call.GetState())
+	r.True(releasedAfterCancel.Load())
+}
+
 func TestCall_FailedQuery(t *testing.T) {
 	r := require.New(t)
 
@@ -120,6 +228,7 @@ func TestCall_FailedQuery(t *testing.T) {
 
 	connection, err := core.NewConnection(&core.ConnectionParams{}, adapter)
 	r.NoError(err)
+	r.NoError(connection.Connect())
 
 	expectedEvents := []core.CallState{
 		core.CallStateExecuting,
@@ -159,6 +268,7 @@ func TestCall_Archive(t *testing.T) {
 		mock.AdapterWithResultStreamOpts(mock.ResultStreamWithNextSleep(300*time.Millisecond)),
 	))
 	r.NoError(err)
+	r.NoError(connection.Connect())
 
 	call := connection.Execute("_", nil)
 
@@ -194,3 +304,737 @@ func TestCall_Archive(t *testing.T) {
 	r.NoError(err)
 	r.Equal(rows, actualRows)
 }
+
+func TestCall_ResultWindow(t *testing.T) {
+	r := require.New(t)
+
+	// keep only a small part of the result in memory
+	core.SetResultWindowSize(100)
+	defer core.SetResultWindowSize(core.DefaultResultWindowSize)
+
+	rows := mock.NewRows(0, 2345)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(rows))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.Execute("_", nil)
+
+	// wait for call to finish
+	select {
+	case <-call.Done():
+		// wait a bit for state to stabilize
+		time.Sleep(100 * time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+	r.Equal(core.CallStateArchived, call.GetState())
+
+	result, err := call.GetResult()
+	r.NoError(err)
+	r.Equal(len(rows), result.Len())
+
+	// pages are read transparently from memory and disk
+	for _, page := range [][2]int{{0, 100}, {480, 520}, {1999, 2300}, {2300, 2345}, {0, -1}} {
+		actualRows, err := result.Rows(page[0], page[1])
+		r.NoError(err)
+
+		to := page[1]
+		if to < 0 {
+			to = len(rows)
+		}
+		r.Equal(rows[page[0]:to], actualRows)
+	}
+
+	// restored call reads the result straight from the archive
+	b, err := json.Marshal(call)
+	r.NoError(err)
+	restoredCall := new(core.Call)
+	r.NoError(json.Unmarshal(b, restoredCall))
+
+	result, err = restoredCall.GetResult()
+	r.NoError(err)
+	r.Equal(len(rows), result.Len())
+	actualRows, err := result.Rows(1200, 1800)
+	r.NoError(err)
+	r.Equal(rows[1200:1800], actualRows)
+
+	// views are computed over rows on disk as well
+	view := &core.ResultViewOptions{
+		Sort:    []core.ResultSort{{Column: "header_0", Descending: true}},
+		Filters: []core.ResultFilter{{Column: "header_0", Operator: core.FilterGreaterOrEqual, Value: "400"}},
+	}
+	var expected []core.Row
+	for i := len(rows) - 1; i >= 400; i-- {
+		expected = append(expected, rows[i])
+	}
+	for _, page := range [][2]int{{0, 100}, {1500, -1}, {0, -1}} {
+		b, length, err := result.FormatView(rowsFormatter{}, view, page[0], page[1])
+		r.NoError(err)
+		r.Equal(len(expected), length)
+
+		to := page[1]
+		if to < 0 {
+			to = len(expected)
+		}
+		expectedPage, err := json.Marshal(expected[page[0]:to])
+		r.NoError(err)
+		r.JSONEq(string(expectedPage), string(b))
+	}
+}
+
+func TestCall_MultipleResultSets(t *testing.T) {
+	r := require.New(t)
+
+	sets := [][]core.Row{
+		mock.NewRows(0, 10),
+		{{"a", "b", "c"}, {"d", "e", "f"}},
+		mock.NewRows(0, 1200),
+	}
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(sets[0],
+		mock.AdapterWithResultStreamOpts(mock.ResultStreamWithResultSets(sets[1:]...)),
+	))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.Execute("_", nil)
+
+	// wait for call to finish
+	select {
+	case <-call.Done():
+		// wait a bit for state to stabilize
+		time.Sleep(100 * time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+	r.Equal(core.CallStateArchived, call.GetState())
+
+	checkSets := func(call *core.Call) {
+		r.Equal(len(sets), call.GetResultSetCount())
+
+		results, err := call.GetResults()
+		r.NoError(err)
+		r.Len(results, len(sets))
+
+		for i, result := range results {
+			r.Len(result.Header(), len(sets[i][0]))
+			actualRows, err := result.Rows(0, -1)
+			r.NoError(err)
+			r.Equal(sets[i], actualRows)
+		}
+
+		_, err = call.GetResultSet(len(sets))
+		r.Error(err)
+	}
+
+	checkSets(call)
+
+	// every set is restored from the archive
+	b, err := json.Marshal(call)
+	r.NoError(err)
+	restoredCall := new(core.Call)
+	r.NoError(json.Unmarshal(b, restoredCall))
+
+	checkSets(restoredCall)
+}
+
+func TestCall_LegacyArchive(t *testing.T) {
+	r := require.New(t)
+
+	core.SetArchiveDir(t.TempDir())
+
+	// legacy archives are read from a temporary directory instead of their old location
+	legacyDir := t.TempDir()
+	defer core.SetLegacyArchiveDir(legacyDir)()
+
+	id := uuid.New().String()
+	dir := filepath.Join(legacyDir, id)
+
+	writeGob := func(path string, value any) {
+		file, err := os.Create(path)
+		r.NoError(err)
+		defer file.Close()
+		r.NoError(gob.NewEncoder(file).Encode(value))
+	}
+
+	// archive in the old layout: a directory of gob files per result set
+	sets := [][]core.Row{
+		mock.NewRows(0, 1200),
+		{{"a", "b", "c"}, {"d", "e", "f"}},
+	}
+	for i, rows := range sets {
+		setDir := dir
+		if i > 0 {
+			setDir = filepath.Join(dir, fmt.Sprintf("set_%d", i))
+		}
+		r.NoError(os.MkdirAll(setDir, os.ModePerm))
+
+		writeGob(filepath.Join(setDir, "header.gob"), core.Header{"1", "2", "3"})
+		writeGob(filepath.Join(setDir, "meta.gob"), core.Meta{SchemaType: core.SchemaFul})
+		for chunk := 0; chunk*500 < len(rows); chunk++ {
+			writeGob(filepath.Join(setDir, fmt.Sprintf("row_%d.gob", chunk)), rows[chunk*500:min((chunk+1)*500, len(rows))])
+		}
+	}
+
+	call := new(core.Call)
+	r.NoError(json.Unmarshal([]byte(fmt.Sprintf(`{"id": %q, "state": "archived"}`, id)), call))
+	r.Equal(core.CallStateArchived, call.GetState())
+	r.Equal(len(sets), call.GetResultSetCount())
+
+	// the archive is migrated on first read
+	for i, rows := range sets {
+		result, err := call.GetResultSet(i)
+		r.NoError(err)
+		r.Equal(len(rows), result.Len())
+
+		actualRows, err := result.Rows(0, -1)
+		r.NoError(err)
+		r.Equal(rows, actualRows)
+	}
+	_, err := os.Stat(dir)
+	r.True(os.IsNotExist(err))
+
+	// migrated archive is restored as well
+	b, err := json.Marshal(call)
+	r.NoError(err)
+	restoredCall := new(core.Call)
+	r.NoError(json.Unmarshal(b, restoredCall))
+	r.Equal(len(sets), restoredCall.GetResultSetCount())
+
+	result, err := restoredCall.GetResult()
+	r.NoError(err)
+	actualRows, err := result.Rows(700, 1100)
+	r.NoError(err)
+	r.Equal(sets[0][700:1100], actualRows)
+}
+
+func TestCall_ClearArchive(t *testing.T) {
+	r := require.New(t)
+
+	core.SetArchiveDir(t.TempDir())
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(mock.NewRows(0, 1000)))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.Execute("_", nil)
+
+	select {
+	case <-call.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+	r.Positive(call.ArchiveSize())
+
+	b, err := json.Marshal(call)
+	r.NoError(err)
+
+	r.NoError(call.ClearArchive())
+	r.Zero(call.ArchiveSize())
+
+	// call without an archive can't be restored
+	restoredCall := new(core.Call)
+	r.NoError(json.Unmarshal(b, restoredCall))
+	r.Equal(core.CallStateUnknown, restoredCall.GetState())
+	_, err = restoredCall.GetResult()
+	r.Error(err)
+}
+
+func TestCall_ArchiveDirPermissions(t *testing.T) {
+	r := require.New(t)
+
+	// directory which already exists is only made readable by the current user
+	dir := filepath.Join(t.TempDir(), "archives")
+	r.NoError(os.Mkdir(dir, 0o755))
+	core.SetArchiveDir(dir)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(mock.NewRows(0, 10)))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.Execute("_", nil)
+
+	select {
+	case <-call.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+	r.Positive(call.ArchiveSize())
+
+	info, err := os.Stat(dir)
+	r.NoError(err)
+	r.Equal(os.FileMode(0o700), info.Mode().Perm())
+}
+
+func TestRemoveOrphanedArchives(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	core.SetArchiveDir(dir)
+
+	old := time.Now().Add(-2 * time.Hour)
+	files := []struct {
+		name     string
+		modified time.Time
+		known    bool
+		removed  bool
+	}{
+		{name: "orphan.archive", modified: old, removed: true},
+		{name: "recent.archive", modified: time.Now()},
+		{name: "known.archive", modified: old, known: true},
+		{name: "other.txt", modified: old},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		r.NoError(os.WriteFile(path, []byte("_"), 0o600))
+		r.NoError(os.Chtimes(path, f.modified, f.modified))
+	}
+
+	err := core.RemoveOrphanedArchives(func(id core.CallID) bool {
+		return id == "known"
+	}, time.Hour)
+	r.NoError(err)
+
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(dir, f.name))
+		if f.removed {
+			r.True(os.IsNotExist(err), f.name)
+		} else {
+			r.NoError(err, f.name)
+		}
+	}
+
+	// missing archive directory is not an error
+	core.SetArchiveDir(filepath.Join(dir, "missing"))
+	r.NoError(core.RemoveOrphanedArchives(func(core.CallID) bool { return false }, time.Hour))
+}
+
+func TestCall_RestoreInterrupted(t *testing.T) {
+	r := require.New(t)
+
+	for _, state := range []core.CallState{
+		core.CallStateExecuting,
+		core.CallStateRetrieving,
+		core.CallStateAwaitingConfirmation,
+	} {
+		call := new(core.Call)
+		r.NoError(json.Unmarshal([]byte(fmt.Sprintf(`{"id": %q, "state": %q}`, uuid.New().String(), state)), call))
+
+		// calls which were running when the log was written can't be resumed
+		r.Equal(core.CallStateUnknown, call.GetState())
+
+		select {
+		case <-call.Done():
+		default:
+			t.Error("restored call is not done")
+		}
+	}
+}
+
+func TestCall_Metadata(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 10)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{ID: "conn_id", Name: "conn_name"}, mock.NewAdapter(rows))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.Execute("query_id", nil)
+
+	select {
+	case <-call.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+
+	r.Equal(core.ConnectionID("conn_id"), call.GetConnectionID())
+	r.Equal("conn_name", call.GetConnectionName())
+	r.Equal(len(rows), call.GetRowCount())
+	// mock driver reports the query as its id
+	r.Equal("query_id", call.GetQueryID())
+	r.Equal(int64(-1), call.GetRowsAffected())
+	r.Positive(call.ArchiveSize())
+
+	b, err := json.Marshal(call)
+	r.NoError(err)
+
+	// metadata is known before results of the restored call are loaded
+	restoredCall := new(core.Call)
+	r.NoError(json.Unmarshal(b, restoredCall))
+
+	r.Equal(call.GetConnectionID(), restoredCall.GetConnectionID())
+	r.Equal(call.GetConnectionName(), restoredCall.GetConnectionName())
+	r.Equal(call.GetRowCount(), restoredCall.GetRowCount())
+	r.Equal(call.GetQueryID(), restoredCall.GetQueryID())
+	r.Equal(call.GetRowsAffected(), restoredCall.GetRowsAffected())
+	r.Equal(call.ArchiveSize(), restoredCall.ArchiveSize())
+}
+
+func TestCall_Rerun(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 10)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(rows))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	params := &core.QueryParams{Positional: []any{int64(1), "a"}}
+	call := connection.ExecuteWithParams("query", params, nil)
+	<-call.Done()
+
+	// params survive restoring the call
+	b, err := json.Marshal(call)
+	r.NoError(err)
+	restoredCall := new(core.Call)
+	r.NoError(json.Unmarshal(b, restoredCall))
+	r.Empty(restoredCall.GetParentID())
+	r.Equal(params, restoredCall.GetParams())
+
+	rerun := connection.Rerun(restoredCall, nil, nil, nil)
+	<-rerun.Done()
+
+	r.NotEqual(call.GetID(), rerun.GetID())
+	r.Equal(call.GetID(), rerun.GetParentID())
+	r.Equal(call.GetQuery(), rerun.GetQuery())
+	r.Equal(params, rerun.GetParams())
+
+	b, err = json.Marshal(rerun)
+	r.NoError(err)
+	restoredRerun := new(core.Call)
+	r.NoError(json.Unmarshal(b, restoredRerun))
+	r.Equal(call.GetID(), restoredRerun.GetParentID())
+	r.Equal(core.CallKindQuery, restoredRerun.GetKind())
+}
+
+func TestCall_RerunDerived(t *testing.T) {
+	r := require.New(t)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(mock.NewRows(0, 10)))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	derived := connection.ExecuteStream(core.CallKindDiff, "-- diff", func(context.Context) (core.ResultStream, error) {
+		return mock.NewResultStream(mock.NewRows(0, 3)), nil
+	}, nil)
+	<-derived.Done()
+	r.NoError(derived.Err())
+	r.Equal(core.CallKindDiff, derived.GetKind())
+
+	// kind survives restoring the call
+	b, err := json.Marshal(derived)
+	r.NoError(err)
+	restored := new(core.Call)
+	r.NoError(json.Unmarshal(b, restored))
+	r.Equal(core.CallKindDiff, restored.GetKind())
+
+	// query of a derived call isn't sent to the driver
+	rerun := connection.Rerun(restored, nil, nil, nil)
+	<-rerun.Done()
+	r.ErrorIs(rerun.Err(), core.ErrDerivedCallRerun)
+	r.Equal(derived.GetID(), rerun.GetParentID())
+
+	// calls persisted before kinds were recorded are queries
+	legacy := new(core.Call)
+	r.NoError(json.Unmarshal([]byte(`{"id":"legacy","query":"_","state":"archived"}`), legacy))
+	r.Equal(core.CallKindQuery, legacy.GetKind())
+}
+
+func TestCall_Limits(t *testing.T) {
+	r := require.New(t)
+
+	sets := [][]core.Row{
+		mock.NewRows(0, 10),
+		mock.NewRows(0, 10),
+		mock.NewRows(0, 10),
+	}
+
+	connection, err := core.NewConnection(&core.ConnectionParams{
+		CallOptions: core.CallOptions{MaxRows: 15},
+	}, mock.NewAdapter(sets[0],
+		mock.AdapterWithResultStreamOpts(mock.ResultStreamWithResultSets(sets[1:]...)),
+	))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	testCases := []struct {
+		opts          *core.CallOptions
+		expectedState core.CallState
+		expectedRows  []int
+	}{
+		// connection defaults
+		{nil, core.CallStateTruncated, []int{10, 5}},
+		// overrides
+		{&core.CallOptions{MaxRows: 25}, core.CallStateTruncated, []int{10, 10, 5}},
+		{&core.CallOptions{MaxRows: -1}, core.CallStateArchived, []int{10, 10, 10}},
+		{&core.CallOptions{MaxRows: 30}, core.CallStateArchived, []int{10, 10, 10}},
+		// each row is 13 bytes (int + "row_x")
+		{&core.CallOptions{MaxRows: -1, MaxBytes: 39}, core.CallStateTruncated, []int{3}},
+	}
+
+	for _, tc := range testCases {
+		call := connection.ExecuteWithOptions("_", nil, tc.opts, nil, nil)
+
+		select {
+		case <-call.Done():
+			time.Sleep(100 * time.Millisecond)
+		case <-time.After(5 * time.Second):
+			t.Error("call did not finish in expected time")
+		}
+		r.Equal(tc.expectedState, call.GetState())
+
+		results, err := call.GetResults()
+		r.NoError(err)
+		r.Len(results, len(tc.expectedRows))
+		for i, result := range results {
+			r.Equal(tc.expectedRows[i], result.Len())
+		}
+	}
+}
+
+func TestCall_LimitsStopQuery(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 10000)
+
+	var read atomic.Int64
+	var canceled atomic.Bool
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(rows,
+		mock.AdapterWithResultStreamOpts(mock.ResultStreamWithDrainOnClose(), mock.ResultStreamWithReadCounter(&read)),
+		mock.AdapterWithCancelQuery(func(string) { canceled.Store(true) }),
+	))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.ExecuteWithOptions("_", nil, &core.CallOptions{MaxRows: 100}, nil, nil)
+
+	select {
+	case <-call.Done():
+		time.Sleep(100 * time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not finish in expected time")
+	}
+	r.Equal(core.CallStateTruncated, call.GetState())
+	r.Equal(100, call.GetRowCount())
+
+	// the query is stopped instead of draining the remaining rows on close
+	r.True(canceled.Load())
+	r.Less(read.Load(), int64(len(rows)))
+}
+
+func TestCall_Timeout(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 10)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(rows,
+		mock.AdapterWithQuerySideEffect("timeout_execute", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.ExecuteWithOptions("timeout_execute", nil, &core.CallOptions{Timeout: 200 * time.Millisecond}, nil, nil)
+
+	select {
+	case <-call.Done():
+		time.Sleep(100 * time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+	r.Equal(core.CallStateTimedOut, call.GetState())
+	r.Error(call.Err())
+}
+
+func TestCall_Progress(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 10)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(rows,
+		mock.AdapterWithQuerySideEffect("progress", func(ctx context.Context) error {
+			core.ReportQueryProgress(ctx, core.QueryProgress{RowsRead: 100, BytesRead: 1000, TotalRows: 10})
+			return nil
+		}),
+		mock.AdapterWithResultStreamOpts(mock.ResultStreamWithNextSleep(100*time.Millisecond)),
+	))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	progressCh := make(chan core.CallProgress, 100)
+	call := connection.ExecuteWithOptions("progress", nil, nil, nil, func(progress core.CallProgress, _ *core.Call) {
+		progressCh <- progress
+	})
+
+	select {
+	case <-call.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+	var reports []core.CallProgress
+	for len(progressCh) > 0 {
+		reports = append(reports, <-progressCh)
+	}
+	r.NotEmpty(reports)
+
+	// rows are only increasing
+	for i := 1; i < len(reports); i++ {
+		r.GreaterOrEqual(reports[i].Rows, reports[i-1].Rows)
+	}
+
+	// driver progress is included
+	last := reports[len(reports)-1]
+	r.Equal(int64(100), last.RowsRead)
+	r.Equal(int64(1000), last.BytesRead)
+	r.Equal(int64(10), last.EstimatedRows)
+	r.Positive(last.Rows)
+}
+
+func TestCall_Confirmation(t *testing.T) {
+	rows := mock.NewRows(0, 10)
+
+	adapter := mock.NewAdapter(rows,
+		mock.AdapterWithEstimate("small", &core.Estimate{Bytes: 10}),
+		mock.AdapterWithEstimate("large", &core.Estimate{Bytes: 1000}),
+		mock.AdapterWithEstimateError("unestimated", errors.New("dry run failed")),
+	)
+
+	testCases := []struct {
+		name           string
+		query          string
+		approve        bool
+		expectedEvents []core.CallState
+		expectedState  core.CallState
+	}{
+		{
+			name:           "under threshold",
+			query:          "small",
+			expectedEvents: []core.CallState{core.CallStateExecuting, core.CallStateRetrieving, core.CallStateArchived},
+			expectedState:  core.CallStateArchived,
+		},
+		{
+			name:    "approved",
+			query:   "large",
+			approve: true,
+			expectedEvents: []core.CallState{
+				core.CallStateExecuting,
+				core.CallStateAwaitingConfirmation,
+				core.CallStateExecuting,
+				core.CallStateRetrieving,
+				core.CallStateArchived,
+			},
+			expectedState: core.CallStateArchived,
+		},
+		{
+			name:           "rejected",
+			query:          "large",
+			approve:        false,
+			expectedEvents: []core.CallState{core.CallStateExecuting, core.CallStateAwaitingConfirmation, core.CallStateCanceled},
+			expectedState:  core.CallStateCanceled,
+		},
+		{
+			// failed estimate is recorded, but doesn't block the call
+			name:           "estimate failed",
+			query:          "unestimated",
+			expectedEvents: []core.CallState{core.CallStateExecuting, core.CallStateRetrieving, core.CallStateArchived},
+			expectedState:  core.CallStateArchived,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			connection, err := core.NewConnection(&core.ConnectionParams{
+				CallOptions: core.CallOptions{ConfirmAboveBytes: 100},
+			}, adapter)
+			r.NoError(err)
+			r.NoError(connection.Connect())
+
+			var events []core.CallState
+			call := connection.Execute(tc.query, func(state core.CallState, c *core.Call) {
+				events = append(events, state)
+				if state == core.CallStateAwaitingConfirmation {
+					r.NoError(c.Confirm(tc.approve))
+				}
+			})
+
+			select {
+			case <-call.Done():
+				time.Sleep(100 * time.Millisecond)
+			case <-time.After(5 * time.Second):
+				t.Error("call did not finish in expected time")
+			}
+
+			r.Equal(tc.expectedEvents, events)
+			r.Equal(tc.expectedState, call.GetState())
+			r.NotNil(call.GetEstimate())
+			if !tc.approve && tc.expectedState == core.CallStateCanceled {
+				r.ErrorIs(call.Err(), core.ErrCallRejected)
+			}
+		})
+	}
+}
+
+func TestCall_QueryError(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 10)
+
+	errMissingTable := errors.New("relation \"missing\" does not exist")
+	adapter := mock.NewAdapter(rows,
+		mock.AdapterWithQuerySideEffect("select * from missing", func(ctx context.Context) error {
+			return errMissingTable
+		}),
+		mock.AdapterWithErrorParser(func(err error) *core.QueryError {
+			if !errors.Is(err, errMissingTable) {
+				return nil
+			}
+			qe := core.NewQueryError(err)
+			qe.Severity = "ERROR"
+			qe.SQLState = "42P01"
+			qe.Message = errMissingTable.Error()
+			qe.Position = 15
+			return qe
+		}),
+	)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, adapter)
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	call := connection.Execute("select * from missing", nil)
+
+	select {
+	case <-call.Done():
+		time.Sleep(100 * time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Error("call did not finish in expected time")
+	}
+	r.Equal(core.CallStateExecutingFailed, call.GetState())
+
+	// original error is kept in the chain
+	r.ErrorIs(call.Err(), errMissingTable)
+	qe := core.AsQueryError(call.Err())
+	r.NotNil(qe)
+	r.Equal("42P01", qe.SQLState)
+	r.Equal(15, qe.Position)
+
+	// query error is persisted
+	b, err := json.Marshal(call)
+	r.NoError(err)
+	restoredCall := &core.Call{}
+	r.NoError(json.Unmarshal(b, restoredCall))
+
+	restored := core.AsQueryError(restoredCall.Err())
+	r.NotNil(restored)
+	r.Equal("ERROR", restored.Severity)
+	r.Equal("42P01", restored.SQLState)
+	r.Equal(errMissingTable.Error(), restored.Message)
+	r.Equal(15, restored.Position)
+	r.Equal(call.Err().Error(), restoredCall.Err().Error())
+}
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultColumnStatsTop is the default number of most frequent values in column stats.
+const DefaultColumnStatsTop = 5
+
+// columnStatsBatchSize is the number of rows read from the result at once.
+const columnStatsBatchSize = archiveChunkSize
+
+// ColumnStatsHeader is the header of column stats, every row describes a single column.
+// Min and max are numbers or times for numeric and time columns and are compared
+// as text otherwise. Mean is only computed for numbers and lengths for text.
+var ColumnStatsHeader = Header{"column", "type", "count", "nulls", "distinct", "min", "max", "mean", "min_length", "max_length", "top"}
+
+// ColumnStatsOptions configure column stats.
+type ColumnStatsOptions struct {
+	// Columns to compute stats of, all columns if empty.
+	Columns []string
+	// Top is the number of most frequent values, DefaultColumnStatsTop if not positive.
+	Top int
+}
+
+// columnStats accumulates stats of a single column.
+type columnStats struct {
+	name  string
+	index int
+
+	count int
+	nulls int
+	// occurrences of values by their text
+	counts map[string]int
+
+	// all values are numbers or times (as long as there are any values)
+	numeric  bool
+	temporal bool
+	sum      float64
+	min, max any
+
+	minLength, maxLength int
+}
+
+// ColumnStats computes stats of columns of the result in a single pass over its rows,
+// which are read in batches, so the result can be archived. See ColumnStatsHeader for the stats.
+func ColumnStats(result *Result, opts *ColumnStatsOptions) (ResultStream, error) {
+	if opts == nil {
+		opts = &ColumnStatsOptions{}
+	}
+	top := opts.Top
+	if top <= 0 {
+		top = DefaultColumnStatsTop
+	}
+
+	header := result.Header()
+	names := opts.Columns
+	if len(names) < 1 {
+		names = header
+	}
+
+	stats := make([]*columnStats, 0, len(names))
+	for _, name := range names {
+		index := slices.Index(header, name)
+		if index < 0 {
+			return nil, fmt.Errorf("unknown column: %q", name)
+		}
+		stats = append(stats, &columnStats{
+			name:     name,
+			index:    index,
+			counts:   make(map[string]int),
+			numeric:  true,
+			temporal: true,
+		})
+	}
+
+	for from := 0; ; from += columnStatsBatchSize {
+		rows, err := result.Rows(from, from+columnStatsBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("result.Rows: %w", err)
+		}
+		if len(rows) < 1 {
+			break
+		}
+
+		for _, row := range rows {
+			for _, s := range stats {
+				s.add(cell(row, s.index))
+			}
+		}
+	}
+
+	rows := make([]Row, 0, len(stats))
+	for _, s := range stats {
+		rows = append(rows, s.row(top))
+	}
+
+	return newRowsStream(ColumnStatsHeader, rows), nil
+}
+
+func (s *columnStats) add(value any) {
+	if value == nil {
+		s.nulls++
+		return
+	}
+	s.count++
+
+	text := fmt.Sprint(value)
+	s.counts[text]++
+
+	length := utf8.RuneCountInString(text)
+	if s.count == 1 || length < s.minLength {
+		s.minLength = length
+	}
+	if s.count == 1 || length > s.maxLength {
+		s.maxLength = length
+	}
+
+	number, isNumber := toNumber(value)
+	t, isTime := value.(time.Time)
+
+	// type of the column changed - min and max are compared as text from now on
+	if (s.numeric && !isNumber) || (s.temporal && !isTime) {
+		if s.count > 1 && (s.numeric || s.temporal) {
+			s.min, s.max = fmt.Sprint(s.min), fmt.Sprint(s.max)
+		}
+		s.numeric = s.numeric && isNumber
+		s.temporal = s.temporal && isTime
+	}
+
+	var less, greater bool
+	switch {
+	case s.count == 1:
+		less, greater = true, true
+	case s.numeric:
+		n, _ := toNumber(s.min)
+		less = number < n
+		n, _ = toNumber(s.max)
+		greater = number > n
+	case s.temporal:
+		less = t.Before(s.min.(time.Time))
+		greater = t.After(s.max.(time.Time))
+	default:
+		less = text < s.min.(string)
+		greater = text > s.max.(string)
+	}
+
+	if s.numeric {
+		s.sum += number
+	}
+
+	// min and max are kept as text for text columns
+	if !s.numeric && !s.temporal {
+		value = text
+	}
+	if less {
+		s.min = value
+	}
+	if greater {
+		s.max = value
+	}
+}
+
+func (s *columnStats) typ() string {
+	switch {
+	case s.count == 0:
+		return "null"
+	case s.numeric:
+		return "number"
+	case s.temporal:
+		return "time"
+	default:
+		return "text"
+	}
+}
+
+// row returns stats of the column in order of ColumnStatsHeader.
+func (s *columnStats) row(top int) Row {
+	var mean, minLength, maxLength any
+	switch s.typ() {
+	case "number":
+		mean = s.sum / float64(s.count)
+	case "text":
+		minLength, maxLength = s.minLength, s.maxLength
+	}
+
+	return Row{
+		s.name,
+		s.typ(),
+		s.count,
+		s.nulls,
+		len(s.counts),
+		s.min,
+		s.max,
+		mean,
+		minLength,
+		maxLength,
+		s.top(top),
+	}
+}
+
+// top returns the most frequent values with the number of their occurrences.
+func (s *columnStats) top(n int) string {
+	values := make([]string, 0, len(s.counts))
+	for value := range s.counts {
+		values = append(values, value)
+	}
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Or(cmp.Compare(s.counts[b], s.counts[a]), cmp.Compare(a, b))
+	})
+
+	parts := make([]string, 0, n)
+	for _, value := range values[:min(n, len(values))] {
+		parts = append(parts, fmt.Sprintf("%s (%d)", value, s.counts[value]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,60 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
+)
+
+func TestColumnStats(t *testing.T) {
+	r := require.New(t)
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	header := core.Header{"id", "name", "created", "mixed", "empty"}
+	rows := []core.Row{
+		{int64(1), "apple", day, 1, nil},
+		{int64(2), "banana", day.Add(48 * time.Hour), "b", nil},
+		{int64(3), "apple", nil, 3, nil},
+		{int64(10), nil, day.Add(24 * time.Hour), "a", nil},
+	}
+
+	result := new(core.Result)
+	r.NoError(result.SetIter(mock.NewResultStream(rows, mock.ResultStreamWithHeader(header)), nil))
+
+	stream, err := core.ColumnStats(result, &core.ColumnStatsOptions{Top: 1})
+	r.NoError(err)
+	r.Equal(core.ColumnStatsHeader, stream.Header())
+
+	var stats []core.Row
+	for stream.HasNext() {
+		row, err := stream.Next()
+		r.NoError(err)
+		stats = append(stats, row)
+	}
+
+	expected := []core.Row{
+		{"id", "number", 4, 0, 4, int64(1), int64(10), 4.0, nil, nil, "1 (1)"},
+		{"name", "text", 3, 1, 2, "apple", "banana", nil, 5, 6, "apple (2)"},
+		{"created", "time", 3, 1, 3, day, day.Add(48 * time.Hour), nil, nil, nil, day.String() + " (1)"},
+		{"mixed", "text", 4, 0, 4, "1", "b", nil, 1, 1, "1 (1)"},
+		{"empty", "null", 0, 4, 0, nil, nil, nil, nil, nil, ""},
+	}
+	r.Equal(expected, stats)
+
+	// only selected columns
+	stream, err = core.ColumnStats(result, &core.ColumnStatsOptions{Columns: []string{"name"}})
+	r.NoError(err)
+	row, err := stream.Next()
+	r.NoError(err)
+	r.Equal("name", row[0])
+	r.Equal("apple (2), banana (1)", row[10])
+	r.False(stream.HasNext())
+
+	_, err = core.ColumnStats(result, &core.ColumnStatsOptions{Columns: []string{"missing"}})
+	r.Error(err)
+}
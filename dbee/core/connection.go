@@ -10,7 +10,19 @@ import (
 	"github.com/google/uuid"
 )
 
-var ErrDatabaseSwitchingNotSupported = errors.New("database switching not supported")
+var (
+	ErrDatabaseSwitchingNotSupported = errors.New("database switching not supported")
+	ErrQueryParamsNotSupported       = errors.New("query parameters not supported")
+	ErrTransactionsNotSupported      = errors.New("transactions not supported")
+	ErrExplainNotSupported           = errors.New("explain not supported")
+	ErrExplainAnalyzeNotSupported    = errors.New("explain analyze not supported")
+	// ErrDerivedCallRerun is the error of a rerun of a call whose result was derived from
+	// other results (see CallKind.IsDerived). Such calls have no query to execute.
+	ErrDerivedCallRerun = errors.New("derived call can't be rerun")
+	// ErrAnalyzeNeedsConfirmation is returned by Explain with analyze, if the query is estimated over
+	// the ConfirmAboveBytes option of the connection and the explain isn't confirmed.
+	ErrAnalyzeNeedsConfirmation = errors.New("explain analyze needs confirmation")
+)
 
 // TableOptions contain options for gathering information about specific table.
 type TableOptions struct {
@@ -41,6 +53,52 @@ type (
 		SelectDatabase(string) error
 		ListDatabases() (current string, available []string, err error)
 	}
+
+	// CurrentDatabaser is an optional interface for drivers that can tell which database queries are
+	// sent to right now (e.g. after a USE statement). It's asked before every call, so it should be cheap.
+	CurrentDatabaser interface {
+		CurrentDatabase(ctx context.Context) (string, error)
+	}
+
+	// Transactor is an optional interface for drivers that support explicit transactions.
+	// While a transaction is open, driver executes all queries in it, on a single connection.
+	Transactor interface {
+		BeginTransaction(ctx context.Context) error
+		CommitTransaction() error
+		RollbackTransaction() error
+		InTransaction() bool
+	}
+
+	// ParamQuerier is an optional interface for drivers that can bind parameters to queries.
+	ParamQuerier interface {
+		QueryWithParams(ctx context.Context, query string, params *QueryParams) (ResultStream, error)
+	}
+
+	// Explainer is an optional interface for drivers that can explain how the database executes a query.
+	// If the driver can't analyze queries, it returns ErrExplainAnalyzeNotSupported.
+	Explainer interface {
+		Explain(ctx context.Context, query string, opts *ExplainOptions) (*PlanNode, error)
+	}
+
+	// Estimator is an optional interface for drivers that can estimate resources a query uses
+	// without executing it. If the query isn't worth estimating (e.g. it doesn't process any data),
+	// driver returns a nil estimate.
+	Estimator interface {
+		Estimate(ctx context.Context, query string, params *QueryParams) (*Estimate, error)
+	}
+
+	// ErrorParser is an optional interface for drivers that can convert native errors of their
+	// database client to query errors. It returns nil if err isn't an error of a query.
+	ErrorParser interface {
+		ParseError(err error) *QueryError
+	}
+
+	// Canceler is an optional interface for drivers that can stop a running query on the server.
+	// Driver reports the identifier of a query with ReportQueryID (or of its session with ReportSessionID) while executing it.
+	// Before a reported session executes other queries, the driver calls FinishQuery.
+	Canceler interface {
+		CancelQuery(ctx context.Context, queryID string) error
+	}
 )
 
 type ConnectionID string
@@ -114,12 +172,17 @@ func (c *Connection) Connect() error {
 	return nil
 }
 
-// Disconnect closes the database connection
+// Disconnect closes the database connection.
+// Open transaction is rolled back.
 func (c *Connection) Disconnect() error {
 	if !c.connected {
 		return nil // already disconnected
 	}
 
+	if c.InTransaction() {
+		_ = c.RollbackTransaction()
+	}
+
 	if c.driver != nil {
 		c.driver.Close()
 		c.driver = nil
@@ -134,6 +197,58 @@ func (c *Connection) IsConnected() bool {
 }
 
 func (c *Connection) Execute(query string, onEvent func(CallState, *Call)) *Call {
+	return c.ExecuteWithOptions(query, nil, nil, onEvent, nil)
+}
+
+// ExecuteWithParams executes the query with params bound as bind variables by the driver.
+// If params are empty, the query is executed as is.
+func (c *Connection) ExecuteWithParams(query string, params *QueryParams, onEvent func(CallState, *Call)) *Call {
+	return c.ExecuteWithOptions(query, params, nil, onEvent, nil)
+}
+
+// ExecuteWithOptions executes the query with params and call options.
+// Provided options override the call option defaults of the connection.
+// If the driver is an Estimator, queries estimated over ConfirmAboveBytes wait for confirmation.
+// Optional onProgress is called periodically while the call is executing or retrieving.
+func (c *Connection) ExecuteWithOptions(query string, params *QueryParams, opts *CallOptions, onEvent func(CallState, *Call), onProgress func(CallProgress, *Call)) *Call {
+	return c.execute(query, params, opts, "", onEvent, onProgress)
+}
+
+// Rerun executes the query of the call again, with the same params. The new call is linked to the
+// rerun call (see Call.GetParentID). The call can come from any connection, even a restored one.
+// Provided options override the call option defaults of the connection.
+// Rerun of a derived call fails with ErrDerivedCallRerun.
+func (c *Connection) Rerun(call *Call, opts *CallOptions, onEvent func(CallState, *Call), onProgress func(CallProgress, *Call)) *Call {
+	if kind := call.GetKind(); kind.IsDerived() {
+		source := callSource{
+			connID:   c.params.ID,
+			connName: c.params.Name,
+			kind:     kind,
+			parentID: call.GetID(),
+		}
+		fail := func(context.Context) (ResultStream, error) {
+			return nil, fmt.Errorf("%w: call %q is a %s", ErrDerivedCallRerun, call.GetID(), kind)
+		}
+		return newCallFromExecutor(fail, call.GetQuery(), CallOptions{}, source, callDriver{}, onEvent, nil)
+	}
+
+	return c.execute(call.GetQuery(), call.GetParams(), opts, call.GetID(), onEvent, onProgress)
+}
+
+// ExecuteStream starts a call of the connection whose result is produced by stream instead of the driver
+// (e.g. a result computed from results of other calls). The connection doesn't have to be connected.
+// Query describes the call in the call log and kind tells how the result is derived.
+func (c *Connection) ExecuteStream(kind CallKind, query string, stream func(context.Context) (ResultStream, error), onEvent func(CallState, *Call)) *Call {
+	source := callSource{
+		connID:   c.params.ID,
+		connName: c.params.Name,
+		kind:     kind,
+	}
+
+	return newCallFromExecutor(stream, query, CallOptions{}, source, callDriver{}, onEvent, nil)
+}
+
+func (c *Connection) execute(query string, params *QueryParams, opts *CallOptions, parentID CallID, onEvent func(CallState, *Call), onProgress func(CallProgress, *Call)) *Call {
 	exec := func(ctx context.Context) (ResultStream, error) {
 		if strings.TrimSpace(query) == "" {
 			return nil, errors.New("empty query")
@@ -141,10 +256,186 @@ func (c *Connection) Execute(query string, onEvent func(CallState, *Call)) *Call
 		if !c.connected || c.driver == nil {
 			return nil, errors.New("connection not established")
 		}
-		return c.driver.Query(ctx, query)
+		if params.IsEmpty() {
+			return c.driver.Query(ctx, query)
+		}
+
+		querier, ok := c.driver.(ParamQuerier)
+		if !ok {
+			return nil, ErrQueryParamsNotSupported
+		}
+		return querier.QueryWithParams(ctx, query, params)
+	}
+
+	var driver callDriver
+	driver.canceler, _ = c.driver.(Canceler)
+	driver.parser, _ = c.driver.(ErrorParser)
+
+	// queries which would fail anyway aren't estimated
+	if estimator, ok := c.driver.(Estimator); ok && strings.TrimSpace(query) != "" {
+		driver.estimator = func(ctx context.Context) (*Estimate, error) {
+			return estimator.Estimate(ctx, query, params)
+		}
+	}
+
+	source := callSource{
+		connID:   c.params.ID,
+		connName: c.params.Name,
+		database: currentDatabase(c.driver),
+		parentID: parentID,
+		params:   params,
+	}
+
+	return newCallFromExecutor(exec, query, c.params.CallOptions.Override(opts), source, driver, onEvent, onProgress)
+}
+
+// currentDatabase returns a function which asks the driver for the database the query is sent to.
+// The driver is captured, so that the running call doesn't read it from the connection.
+// It returns nil if the driver can't tell.
+func currentDatabase(driver Driver) func(context.Context) string {
+	databaser, ok := driver.(CurrentDatabaser)
+	if !ok {
+		return nil
 	}
 
-	return newCallFromExecutor(exec, query, onEvent)
+	return func(ctx context.Context) string {
+		name, err := databaser.CurrentDatabase(ctx)
+		if err != nil {
+			return ""
+		}
+		return name
+	}
+}
+
+// Explain returns the normalized plan of the query.
+// Explaining is limited by the timeout of the connection's calls. Analyze executes the query, so if
+// the driver is an Estimator, queries estimated over ConfirmAboveBytes need to be confirmed first.
+func (c *Connection) Explain(query string, opts *ExplainOptions) (*PlanNode, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("empty query")
+	}
+	if !c.connected || c.driver == nil {
+		return nil, errors.New("connection not established")
+	}
+
+	explainer, ok := c.driver.(Explainer)
+	if !ok {
+		return nil, ErrExplainNotSupported
+	}
+	if opts == nil {
+		opts = &ExplainOptions{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout := c.params.CallOptions.Timeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	}
+	defer cancel()
+
+	if opts.Analyze && !opts.Confirmed {
+		err := c.confirmAnalyze(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan, err := explainer.Explain(ctx, query, opts)
+	if err != nil {
+		parser, _ := c.driver.(ErrorParser)
+		return nil, fmt.Errorf("explainer.Explain: %w", toQueryError(err, parser))
+	}
+
+	return plan, nil
+}
+
+// confirmAnalyze returns ErrAnalyzeNeedsConfirmation if the query is estimated over the ConfirmAboveBytes
+// option. Queries which can't be estimated are analyzed, just like they are executed (see Call.confirm).
+func (c *Connection) confirmAnalyze(ctx context.Context, query string) error {
+	threshold := c.params.CallOptions.ConfirmAboveBytes
+	estimator, ok := c.driver.(Estimator)
+	if !ok || threshold <= 0 {
+		return nil
+	}
+
+	estimate, err := estimator.Estimate(ctx, query, nil)
+	if err != nil || estimate == nil || estimate.Bytes <= int64(threshold) {
+		return nil
+	}
+
+	detail := estimate.Detail
+	if detail == "" {
+		detail = fmt.Sprintf("%d bytes processed", estimate.Bytes)
+	}
+	return fmt.Errorf("%w: %s", ErrAnalyzeNeedsConfirmation, detail)
+}
+
+func (c *Connection) transactor() (Transactor, error) {
+	if !c.connected || c.driver == nil {
+		return nil, errors.New("connection not established")
+	}
+
+	transactor, ok := c.driver.(Transactor)
+	if !ok {
+		return nil, ErrTransactionsNotSupported
+	}
+
+	return transactor, nil
+}
+
+// BeginTransaction opens a transaction. All subsequent calls are pinned to a single
+// connection and executed in the transaction, until it's committed or rolled back.
+func (c *Connection) BeginTransaction() error {
+	transactor, err := c.transactor()
+	if err != nil {
+		return err
+	}
+
+	err = transactor.BeginTransaction(context.Background())
+	if err != nil {
+		return fmt.Errorf("transactor.BeginTransaction: %w", err)
+	}
+
+	return nil
+}
+
+// CommitTransaction commits the open transaction.
+func (c *Connection) CommitTransaction() error {
+	transactor, err := c.transactor()
+	if err != nil {
+		return err
+	}
+
+	err = transactor.CommitTransaction()
+	if err != nil {
+		return fmt.Errorf("transactor.CommitTransaction: %w", err)
+	}
+
+	return nil
+}
+
+// RollbackTransaction discards the open transaction.
+func (c *Connection) RollbackTransaction() error {
+	transactor, err := c.transactor()
+	if err != nil {
+		return err
+	}
+
+	err = transactor.RollbackTransaction()
+	if err != nil {
+		return fmt.Errorf("transactor.RollbackTransaction: %w", err)
+	}
+
+	return nil
+}
+
+// InTransaction returns true if the connection has an open transaction.
+func (c *Connection) InTransaction() bool {
+	transactor, err := c.transactor()
+	if err != nil {
+		return false
+	}
+
+	return transactor.InTransaction()
 }
 
 // SelectDatabase tries to switch to a given database with the used client.
@@ -159,6 +450,11 @@ func (c *Connection) SelectDatabase(name string) error {
 		return ErrDatabaseSwitchingNotSupported
 	}
 
+	// switching database would discard the transaction
+	if c.InTransaction() {
+		return errors.New("cannot switch database while a transaction is open")
+	}
+
 	err := switcher.SelectDatabase(name)
 	if err != nil {
 		return fmt.Errorf("switcher.SelectDatabase: %w", err)
@@ -7,6 +7,8 @@ type ConnectionParams struct {
 	Name string
 	Type string
 	URL  string
+	// CallOptions are the default options of calls executed on the connection.
+	CallOptions CallOptions
 }
 
 // Expand returns a copy of the original parameters with expanded fields
@@ -16,19 +18,31 @@ func (p *ConnectionParams) Expand() *ConnectionParams {
 		Name: expandOrDefault(p.Name),
 		Type: expandOrDefault(p.Type),
 		URL:  expandOrDefault(p.URL),
+
+		CallOptions: p.CallOptions,
 	}
 }
 
 func (cp *ConnectionParams) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		ID   string `json:"id"`
-		Name string `json:"name"`
-		Type string `json:"type"`
-		URL  string `json:"url"`
+		ID        string `json:"id"`
+		Name      string `json:"name"`
+		Type      string `json:"type"`
+		URL       string `json:"url"`
+		TimeoutMs int64  `json:"timeout_ms,omitempty"`
+		MaxRows   int    `json:"max_rows,omitempty"`
+		MaxBytes  int    `json:"max_bytes,omitempty"`
+
+		ConfirmAboveBytes int `json:"confirm_above_bytes,omitempty"`
 	}{
-		ID:   string(cp.ID),
-		Name: cp.Name,
-		Type: cp.Type,
-		URL:  cp.URL,
+		ID:        string(cp.ID),
+		Name:      cp.Name,
+		Type:      cp.Type,
+		URL:       cp.URL,
+		TimeoutMs: cp.CallOptions.Timeout.Milliseconds(),
+		MaxRows:   cp.CallOptions.MaxRows,
+		MaxBytes:  cp.CallOptions.MaxBytes,
+
+		ConfirmAboveBytes: cp.CallOptions.ConfirmAboveBytes,
 	})
 }
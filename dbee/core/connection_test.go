@@ -0,0 +1,96 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
+)
+
+func TestConnection_Transaction(t *testing.T) {
+	r := require.New(t)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(mock.NewRows(0, 10)))
+	r.NoError(err)
+
+	// not possible without a connection
+	r.Error(connection.BeginTransaction())
+
+	r.NoError(connection.Connect())
+	r.False(connection.InTransaction())
+
+	r.NoError(connection.BeginTransaction())
+	r.True(connection.InTransaction())
+	r.Error(connection.BeginTransaction())
+
+	r.NoError(connection.CommitTransaction())
+	r.False(connection.InTransaction())
+	r.Error(connection.RollbackTransaction())
+
+	// disconnect rolls back the open transaction
+	r.NoError(connection.BeginTransaction())
+	r.NoError(connection.Disconnect())
+	r.False(connection.InTransaction())
+
+	r.NoError(connection.Connect())
+	r.False(connection.InTransaction())
+}
+
+func TestConnection_ExplainAnalyzeConfirmation(t *testing.T) {
+	r := require.New(t)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{
+		CallOptions: core.CallOptions{ConfirmAboveBytes: 100},
+	}, mock.NewAdapter(nil,
+		mock.AdapterWithEstimate("big", &core.Estimate{Bytes: 1000}),
+		mock.AdapterWithEstimate("small", &core.Estimate{Bytes: 10}),
+	))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	// plain explain doesn't execute the query
+	_, err = connection.Explain("big", nil)
+	r.NoError(err)
+
+	_, err = connection.Explain("big", &core.ExplainOptions{Analyze: true})
+	r.ErrorIs(err, core.ErrAnalyzeNeedsConfirmation)
+
+	plan, err := connection.Explain("big", &core.ExplainOptions{Analyze: true, Confirmed: true})
+	r.NoError(err)
+	r.Equal("analyzed", plan.Detail)
+
+	_, err = connection.Explain("small", &core.ExplainOptions{Analyze: true})
+	r.NoError(err)
+}
+
+func TestConnection_CallDatabase(t *testing.T) {
+	r := require.New(t)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(mock.NewRows(0, 10),
+		mock.AdapterWithDatabases("first", "second", "third"),
+	))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	execute := func(query string) *core.Call {
+		call := connection.Execute(query, nil)
+		select {
+		case <-call.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("call did not finish in expected time")
+		}
+		return call
+	}
+
+	r.Equal("first", execute("_").GetDatabase())
+
+	r.NoError(connection.SelectDatabase("second"))
+	r.Equal("second", execute("_").GetDatabase())
+
+	// database switched by a query is recorded with the next call
+	r.Equal("second", execute("use third").GetDatabase())
+	r.Equal("third", execute("_").GetDatabase())
+}
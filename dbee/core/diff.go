@@ -0,0 +1,344 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Markers of rows in the diff column of a result diff.
+const (
+	DiffAdded     = "+"
+	DiffRemoved   = "-"
+	DiffChanged   = "~"
+	DiffUnchanged = "="
+)
+
+// DiffColumn is the name of the column which holds the marker of each row of a result diff.
+const DiffColumn = "diff"
+
+// DefaultDiffMaxRows is the number of rows of each result compared by key columns,
+// if DiffOptions.MaxRows isn't set.
+const DefaultDiffMaxRows = 100_000
+
+// ErrDiffTooManyRows is returned by DiffResults if a result compared by key columns
+// has more rows than DiffOptions.MaxRows.
+var ErrDiffTooManyRows = errors.New("too many rows to compare by key columns")
+
+// DiffOptions configure how results are compared.
+type DiffOptions struct {
+	// KeyColumns identify the same row in both results.
+	// Rows are compared by their position if no key columns are provided.
+	KeyColumns []string
+	// IncludeUnchanged includes rows which are the same in both results.
+	IncludeUnchanged bool
+	// MaxRows is the maximum number of rows of each result compared by key columns,
+	// since these results are held in memory. Zero means DefaultDiffMaxRows and a negative
+	// value means no limit. Results compared by position are streamed and never limited.
+	MaxRows int
+}
+
+// DiffResults compares rows of "from" result with rows of "to" result.
+//
+// The returned stream has a leading diff column with the marker of each row (added, removed
+// or changed), followed by columns of both results. Changed cells hold both values
+// in the form of "old → new", other cells hold values of the row in its result.
+// Rows are in order of the "to" result, with removed rows placed before the
+// row that followed them in the "from" result.
+//
+// Rows compared by position are read in batches while the stream is consumed. Rows compared
+// by key columns are read at once and the diff fails with ErrDiffTooManyRows if
+// any of the results has more than DiffOptions.MaxRows rows.
+func DiffResults(from, to *Result, opts *DiffOptions) (ResultStream, error) {
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+
+	d, err := newResultDiff(from.Header(), to.Header(), opts.KeyColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.fromKeys) < 1 {
+		return &positionalDiffStream{
+			diff:             d,
+			from:             newBatchReader(from, diffBatchSize),
+			to:               newBatchReader(to, diffBatchSize),
+			includeUnchanged: opts.IncludeUnchanged,
+		}, nil
+	}
+
+	maxRows := opts.MaxRows
+	if maxRows == 0 {
+		maxRows = DefaultDiffMaxRows
+	}
+
+	fromRows, err := readDiffRows(from, maxRows)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	toRows, err := readDiffRows(to, maxRows)
+	if err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+
+	return newRowsStream(d.header, d.diffByKey(fromRows, toRows, opts.IncludeUnchanged)), nil
+}
+
+// diffBatchSize is the number of rows read from each result at once while diffing.
+const diffBatchSize = archiveChunkSize
+
+// readDiffRows reads all rows of the result, failing once there are more than maxRows
+// rows (if maxRows isn't negative), without reading the rest.
+func readDiffRows(result *Result, maxRows int) ([]Row, error) {
+	var rows []Row
+	reader := newBatchReader(result, diffBatchSize)
+	for {
+		row, ok, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return rows, nil
+		}
+		if maxRows >= 0 && len(rows) >= maxRows {
+			return nil, fmt.Errorf("%w: result has more than %d rows", ErrDiffTooManyRows, maxRows)
+		}
+		rows = append(rows, row)
+	}
+}
+
+var _ ResultStream = (*positionalDiffStream)(nil)
+
+// positionalDiffStream compares rows of both results by their position, while it's consumed.
+// Rows are in order of the "to" result, followed by rows removed from the end of the "from" result.
+type positionalDiffStream struct {
+	diff             *resultDiff
+	from             *batchReader
+	to               *batchReader
+	includeUnchanged bool
+
+	// next row of the diff and the error of reading it
+	row Row
+	err error
+}
+
+func (s *positionalDiffStream) Meta() *Meta {
+	return &Meta{SchemaType: SchemaFul}
+}
+
+func (s *positionalDiffStream) Header() Header {
+	return s.diff.header
+}
+
+func (s *positionalDiffStream) HasNext() bool {
+	if s.row != nil || s.err != nil {
+		return true
+	}
+
+	for {
+		fromRow, fromOk, err := s.from.next()
+		if err != nil {
+			s.err = fmt.Errorf("from: %w", err)
+			return true
+		}
+		toRow, toOk, err := s.to.next()
+		if err != nil {
+			s.err = fmt.Errorf("to: %w", err)
+			return true
+		}
+
+		switch {
+		case !fromOk && !toOk:
+			return false
+		case !fromOk:
+			s.row = s.diff.row(DiffAdded, nil, toRow)
+		case !toOk:
+			s.row = s.diff.row(DiffRemoved, fromRow, nil)
+		case s.diff.changed(fromRow, toRow):
+			s.row = s.diff.row(DiffChanged, fromRow, toRow)
+		case s.includeUnchanged:
+			s.row = s.diff.row(DiffUnchanged, fromRow, toRow)
+		default:
+			continue
+		}
+		return true
+	}
+}
+
+func (s *positionalDiffStream) Next() (Row, error) {
+	if !s.HasNext() {
+		return nil, errors.New("no next row")
+	}
+
+	row, err := s.row, s.err
+	s.row, s.err = nil, nil
+	return row, err
+}
+
+func (s *positionalDiffStream) Close() {}
+
+// resultDiff maps columns of both compared results to columns of the diff.
+type resultDiff struct {
+	header Header
+	// indexes of columns in "from" and "to" rows for each column of the diff
+	// after the diff column (-1 if the result doesn't have the column)
+	fromColumns []int
+	toColumns   []int
+	// indexes of key columns in "from" and "to" rows
+	fromKeys []int
+	toKeys   []int
+}
+
+func newResultDiff(fromHeader, toHeader Header, keys []string) (*resultDiff, error) {
+	d := &resultDiff{
+		header: Header{DiffColumn},
+	}
+
+	// columns of "from" result first, followed by the columns only "to" result has
+	for i, column := range fromHeader {
+		d.header = append(d.header, column)
+		d.fromColumns = append(d.fromColumns, i)
+		d.toColumns = append(d.toColumns, slices.Index(toHeader, column))
+	}
+	for i, column := range toHeader {
+		if slices.Contains(fromHeader, column) {
+			continue
+		}
+		d.header = append(d.header, column)
+		d.fromColumns = append(d.fromColumns, -1)
+		d.toColumns = append(d.toColumns, i)
+	}
+
+	for _, key := range keys {
+		fromIndex := slices.Index(fromHeader, key)
+		toIndex := slices.Index(toHeader, key)
+		if fromIndex < 0 || toIndex < 0 {
+			return nil, fmt.Errorf("key column %q is not in both results", key)
+		}
+		d.fromKeys = append(d.fromKeys, fromIndex)
+		d.toKeys = append(d.toKeys, toIndex)
+	}
+
+	return d, nil
+}
+
+// diffByKey compares rows matched by key columns. Rows with the same key are matched in order.
+func (d *resultDiff) diffByKey(fromRows, toRows []Row, includeUnchanged bool) []Row {
+	byKey := make(map[string][]int)
+	for i, row := range fromRows {
+		key := rowKey(row, d.fromKeys)
+		byKey[key] = append(byKey[key], i)
+	}
+
+	// index of the matching row in "from" for each row in "to" (-1 if added)
+	matches := make([]int, len(toRows))
+	matched := make([]bool, len(fromRows))
+	for i, row := range toRows {
+		key := rowKey(row, d.toKeys)
+		candidates := byKey[key]
+		if len(candidates) < 1 {
+			matches[i] = -1
+			continue
+		}
+		matches[i] = candidates[0]
+		matched[candidates[0]] = true
+		byKey[key] = candidates[1:]
+	}
+
+	var rows []Row
+
+	// next row of "from" which wasn't checked for removal yet
+	next := 0
+	removeUntil := func(end int) {
+		for ; next < end; next++ {
+			if !matched[next] {
+				rows = append(rows, d.row(DiffRemoved, fromRows[next], nil))
+			}
+		}
+	}
+
+	for i, m := range matches {
+		if m < 0 {
+			rows = append(rows, d.row(DiffAdded, nil, toRows[i]))
+			continue
+		}
+
+		removeUntil(m)
+
+		marker := DiffUnchanged
+		if d.changed(fromRows[m], toRows[i]) {
+			marker = DiffChanged
+		}
+		if marker == DiffChanged || includeUnchanged {
+			rows = append(rows, d.row(marker, fromRows[m], toRows[i]))
+		}
+	}
+	removeUntil(len(fromRows))
+
+	return rows
+}
+
+// changed reports if any of the columns both results have differs between the rows.
+func (d *resultDiff) changed(fromRow, toRow Row) bool {
+	for i := range d.fromColumns {
+		if d.fromColumns[i] < 0 || d.toColumns[i] < 0 {
+			continue
+		}
+		if !cellsEqual(cell(fromRow, d.fromColumns[i]), cell(toRow, d.toColumns[i])) {
+			return true
+		}
+	}
+	return false
+}
+
+// row returns the row of the diff. Either of the rows can be nil for added or removed rows.
+func (d *resultDiff) row(marker string, fromRow, toRow Row) Row {
+	row := make(Row, 0, len(d.header))
+	row = append(row, marker)
+
+	for i := range d.fromColumns {
+		var fromValue, toValue any
+		fromOk := fromRow != nil && d.fromColumns[i] >= 0
+		toOk := toRow != nil && d.toColumns[i] >= 0
+		if fromOk {
+			fromValue = cell(fromRow, d.fromColumns[i])
+		}
+		if toOk {
+			toValue = cell(toRow, d.toColumns[i])
+		}
+
+		switch {
+		case fromOk && toOk && !cellsEqual(fromValue, toValue):
+			row = append(row, fmt.Sprintf("%v → %v", fromValue, toValue))
+		case toOk:
+			row = append(row, toValue)
+		default:
+			row = append(row, fromValue)
+		}
+	}
+
+	return row
+}
+
+func cell(row Row, index int) any {
+	if index < 0 || index >= len(row) {
+		return nil
+	}
+	return row[index]
+}
+
+// cellsEqual compares values by their string representation, so that the same values
+// are equal even if drivers return them as different types (e.g. int32 and int64).
+func cellsEqual(a, b any) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func rowKey(row Row, keys []int) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprint(cell(row, key))
+	}
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,188 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
+)
+
+func TestDiffResults(t *testing.T) {
+	type testCase struct {
+		name           string
+		fromHeader     core.Header
+		from           []core.Row
+		toHeader       core.Header
+		to             []core.Row
+		opts           *core.DiffOptions
+		expectedHeader core.Header
+		expected       []core.Row
+		expectedError  bool
+	}
+
+	testCases := []testCase{
+		{
+			name:           "by key",
+			fromHeader:     core.Header{"id", "name"},
+			from:           []core.Row{{1, "a"}, {2, "b"}, {3, "c"}},
+			toHeader:       core.Header{"id", "name"},
+			to:             []core.Row{{1, "a"}, {3, "x"}, {4, "d"}},
+			opts:           &core.DiffOptions{KeyColumns: []string{"id"}},
+			expectedHeader: core.Header{"diff", "id", "name"},
+			expected: []core.Row{
+				{"-", 2, "b"},
+				{"~", 3, "c → x"},
+				{"+", 4, "d"},
+			},
+		},
+		{
+			name:           "by key including unchanged",
+			fromHeader:     core.Header{"id", "name"},
+			from:           []core.Row{{1, "a"}, {2, "b"}},
+			toHeader:       core.Header{"id", "name"},
+			to:             []core.Row{{2, "b"}, {1, "a"}},
+			opts:           &core.DiffOptions{KeyColumns: []string{"id"}, IncludeUnchanged: true},
+			expectedHeader: core.Header{"diff", "id", "name"},
+			expected: []core.Row{
+				{"=", 2, "b"},
+				{"=", 1, "a"},
+			},
+		},
+		{
+			name:           "by position",
+			fromHeader:     core.Header{"id", "name"},
+			from:           []core.Row{{1, "a"}, {2, "b"}, {3, "c"}},
+			toHeader:       core.Header{"id", "name"},
+			to:             []core.Row{{1, "a"}, {2, "x"}},
+			expectedHeader: core.Header{"diff", "id", "name"},
+			expected: []core.Row{
+				{"~", 2, "b → x"},
+				{"-", 3, "c"},
+			},
+		},
+		{
+			name:           "by position with added rows",
+			fromHeader:     core.Header{"id", "name"},
+			from:           []core.Row{{1, "a"}},
+			toHeader:       core.Header{"id", "name"},
+			to:             []core.Row{{1, "a"}, {2, "b"}},
+			opts:           &core.DiffOptions{IncludeUnchanged: true},
+			expectedHeader: core.Header{"diff", "id", "name"},
+			expected: []core.Row{
+				{"=", 1, "a"},
+				{"+", 2, "b"},
+			},
+		},
+		{
+			name:           "different columns",
+			fromHeader:     core.Header{"id", "old"},
+			from:           []core.Row{{1, "a"}},
+			toHeader:       core.Header{"new", "id"},
+			to:             []core.Row{{"b", int64(1)}, {"c", int64(2)}},
+			opts:           &core.DiffOptions{KeyColumns: []string{"id"}, IncludeUnchanged: true},
+			expectedHeader: core.Header{"diff", "id", "old", "new"},
+			expected: []core.Row{
+				{"=", int64(1), "a", "b"},
+				{"+", int64(2), nil, "c"},
+			},
+		},
+		{
+			name:          "unknown key column",
+			fromHeader:    core.Header{"id"},
+			from:          []core.Row{{1}},
+			toHeader:      core.Header{"id"},
+			to:            []core.Row{{1}},
+			opts:          &core.DiffOptions{KeyColumns: []string{"missing"}},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			from := new(core.Result)
+			r.NoError(from.SetIter(mock.NewResultStream(tc.from, mock.ResultStreamWithHeader(tc.fromHeader)), nil))
+			to := new(core.Result)
+			r.NoError(to.SetIter(mock.NewResultStream(tc.to, mock.ResultStreamWithHeader(tc.toHeader)), nil))
+
+			stream, err := core.DiffResults(from, to, tc.opts)
+			if tc.expectedError {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.expectedHeader, stream.Header())
+
+			var rows []core.Row
+			for stream.HasNext() {
+				row, err := stream.Next()
+				r.NoError(err)
+				rows = append(rows, row)
+			}
+			r.Equal(tc.expected, rows)
+		})
+	}
+}
+
+// newDiffResult returns a drained result of rows with the header of mock rows.
+func newDiffResult(t *testing.T, rows []core.Row) *core.Result {
+	t.Helper()
+
+	result := new(core.Result)
+	require.NoError(t, result.SetIter(mock.NewResultStream(rows, mock.ResultStreamWithHeader(core.Header{"id", "name"})), nil))
+	return result
+}
+
+func TestDiffResults_ByPositionInBatches(t *testing.T) {
+	r := require.New(t)
+
+	// results span many batches, every 1000th row is changed
+	from := mock.NewRows(0, 2600)
+	to := mock.NewRows(0, 2500)
+	var expected []core.Row
+	for i := 0; i < len(to); i += 1000 {
+		to[i] = core.Row{i, "changed"}
+		expected = append(expected, core.Row{"~", i, fmt.Sprintf("row_%d → changed", i)})
+	}
+	for _, row := range from[len(to):] {
+		expected = append(expected, core.Row{"-", row[0], row[1]})
+	}
+
+	stream, err := core.DiffResults(newDiffResult(t, from), newDiffResult(t, to), nil)
+	r.NoError(err)
+
+	var rows []core.Row
+	for stream.HasNext() {
+		row, err := stream.Next()
+		r.NoError(err)
+		rows = append(rows, row)
+	}
+	r.Equal(expected, rows)
+}
+
+func TestDiffResults_MaxRows(t *testing.T) {
+	r := require.New(t)
+
+	from := newDiffResult(t, mock.NewRows(0, 10))
+	to := newDiffResult(t, mock.NewRows(0, 11))
+	keys := []string{"id"}
+
+	// results compared by key are limited
+	_, err := core.DiffResults(from, to, &core.DiffOptions{KeyColumns: keys, MaxRows: 10})
+	r.ErrorIs(err, core.ErrDiffTooManyRows)
+
+	stream, err := core.DiffResults(from, to, &core.DiffOptions{KeyColumns: keys, MaxRows: 11})
+	r.NoError(err)
+	r.True(stream.HasNext())
+
+	_, err = core.DiffResults(from, to, &core.DiffOptions{KeyColumns: keys, MaxRows: -1})
+	r.NoError(err)
+
+	// results compared by position aren't
+	_, err = core.DiffResults(from, to, &core.DiffOptions{MaxRows: 1})
+	r.NoError(err)
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCallRejected is the error of a call whose execution was rejected while it was awaiting confirmation.
+var ErrCallRejected = errors.New("call rejected")
+
+// Estimate is an estimate of resources a query uses, made before it's executed.
+// Values which are unknown are zero.
+type Estimate struct {
+	// Bytes is the estimated number of bytes processed (or scanned) by the query.
+	Bytes int64
+	// Rows is the estimated number of rows produced by the query.
+	Rows int64
+	// Detail describes the estimate further (e.g. number of scanned partitions).
+	Detail string
+}
+
+// confirm estimates the query with estimator and if the estimate is over the threshold (in bytes),
+// it waits until the call is approved or rejected with Confirm. If estimating fails, the query is
+// executed without confirmation (the failure is recorded in the estimate's detail), as it would
+// most likely fail the same way. It returns false if the query mustn't be executed.
+func (c *Call) confirm(ctx context.Context, estimator func(context.Context) (*Estimate, error), threshold int, timeout time.Duration, sendEvent func(CallState)) bool {
+	estimateCtx, cancel := context.WithCancel(ctx)
+	if timeout > 0 {
+		estimateCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	estimate, err := estimator(estimateCtx)
+	cancel()
+
+	switch {
+	case ctx.Err() != nil:
+		// canceled - state was already reported
+		return false
+	case err != nil:
+		c.setEstimate(&Estimate{Detail: fmt.Sprintf("query could not be estimated: %s", err)})
+		return true
+	case estimate == nil:
+		// queries which can't be estimated (e.g. statements which don't process data)
+		return true
+	case estimate.Bytes <= int64(threshold):
+		c.setEstimate(estimate)
+		return true
+	default:
+		c.setEstimate(estimate)
+	}
+
+	sendEvent(CallStateAwaitingConfirmation)
+
+	select {
+	case <-ctx.Done():
+		return false
+	case approved := <-c.confirmCh:
+		if !approved {
+			c.setOutcome(ErrCallRejected)
+			sendEvent(CallStateCanceled)
+			return false
+		}
+	}
+
+	sendEvent(CallStateExecuting)
+	return true
+}
+
+// Confirm approves or rejects execution of a call which is awaiting confirmation.
+func (c *Call) Confirm(approve bool) error {
+	if state := c.GetState(); state != CallStateAwaitingConfirmation {
+		return fmt.Errorf("call is not awaiting confirmation (state: %s)", state)
+	}
+
+	select {
+	case c.confirmCh <- approve:
+		return nil
+	default:
+		return errors.New("call was already confirmed")
+	}
+}
+
+// GetEstimate returns the estimate of the query made before executing it,
+// or nil if the query wasn't estimated.
+func (c *Call) GetEstimate() *Estimate {
+	c.metaMutex.RLock()
+	defer c.metaMutex.RUnlock()
+
+	return c.estimate
+}
+
+func (c *Call) setEstimate(estimate *Estimate) {
+	c.metaMutex.Lock()
+	defer c.metaMutex.Unlock()
+
+	c.estimate = estimate
+}
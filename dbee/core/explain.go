@@ -0,0 +1,33 @@
+package core
+
+import (
+	"time"
+)
+
+// ExplainOptions are options for explaining a query.
+type ExplainOptions struct {
+	// Analyze executes the query to collect the actual number of rows and timing of plan nodes.
+	Analyze bool
+	// Confirmed analyzes the query even if its estimate is over the ConfirmAboveBytes option
+	// of the connection (see ErrAnalyzeNeedsConfirmation).
+	Confirmed bool
+}
+
+// PlanNode is a node of a query plan, normalized across databases.
+// Numeric values which are unknown (not provided by the database) are zero.
+type PlanNode struct {
+	// Operation performed by the node (e.g. "Seq Scan", "Hash Join", "S00: Input").
+	Operation string
+	// Detail describes the node further (relation, index, condition...).
+	Detail string
+	// Cost is the estimated cost of the node (including its children) in units of the database.
+	Cost float64
+	// Rows is the estimated number of rows produced by the node.
+	Rows float64
+	// ActualRows is the number of rows produced by the node (only with analyze).
+	ActualRows float64
+	// Time spent in the node (only with analyze).
+	Time time.Duration
+
+	Children []*PlanNode
+}
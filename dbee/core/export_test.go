@@ -0,0 +1,13 @@
+package core
+
+import "path/filepath"
+
+// SetLegacyArchiveDir makes legacy archives read from dir instead of their old location
+// and returns a function which restores it.
+func SetLegacyArchiveDir(dir string) (restore func()) {
+	previous := legacyArchiveDir
+	legacyArchiveDir = func(callID CallID) string {
+		return filepath.Join(dir, string(callID))
+	}
+	return func() { legacyArchiveDir = previous }
+}
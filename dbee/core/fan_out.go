@@ -0,0 +1,220 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FanOutConnectionColumn is the leading column of combined results of fan-out calls,
+// holding the name of the connection the row comes from.
+const FanOutConnectionColumn = "connection"
+
+// FanOutSummaryHeader is the header of the summary of fan-out calls, with a row per connection.
+var FanOutSummaryHeader = Header{FanOutConnectionColumn, "call_id", "state", "rows", "time_taken", "error"}
+
+// ErrFanOutNeedsConfirmation is the reason a call of a fan-out was rejected, since its query
+// was estimated over the ConfirmAboveBytes option of its connection. Calls of a fan-out
+// can't wait for confirmation, as that would stall the whole fan-out.
+var ErrFanOutNeedsConfirmation = errors.New("query needs confirmation, execute it on the connection alone")
+
+// FanOutCall is the call which executed the query on a single connection of a fan-out.
+type FanOutCall struct {
+	// Connection is the name of the connection.
+	Connection string
+	// Call is nil if the call couldn't be started (e.g. the connection failed).
+	Call *Call
+	// Err is the reason the call couldn't be started or was stopped. It's reported
+	// in the summary instead of the error of the call.
+	Err error
+}
+
+// CombineFanOut combines finished fan-out calls to a stream of two result sets.
+// The first one holds rows of first result sets of all calls, led by FanOutConnectionColumn,
+// with the union of their columns in order of appearance (cells of missing columns are nil).
+// The second one is the summary of the calls (see FanOutSummaryHeader).
+// Rows are read from the calls in batches, as the stream is consumed.
+func CombineFanOut(calls []*FanOutCall) (ResultStream, error) {
+	header := Header{FanOutConnectionColumn}
+	sources := make([]*fanOutSource, 0, len(calls))
+	summary := make([]Row, 0, len(calls))
+
+	for _, fc := range calls {
+		if fc.Call == nil {
+			summary = append(summary, Row{fc.Connection, nil, nil, 0, nil, errorText(fc.Err)})
+			continue
+		}
+		call := fc.Call
+		err := fc.Err
+		if err == nil {
+			err = call.Err()
+		}
+		summary = append(summary, Row{
+			fc.Connection,
+			string(call.GetID()),
+			call.GetState().String(),
+			call.GetRowCount(),
+			call.GetTimeTaken().String(),
+			errorText(err),
+		})
+
+		// calls which failed before retrieving any rows have no result
+		result, err := call.GetResultSet(0)
+		if err != nil {
+			continue
+		}
+
+		// position of every column of the result in the combined header,
+		// repeated column names of a result are kept as separate columns
+		columns := make([]int, len(result.Header()))
+		used := make(map[int]bool, len(columns))
+		for i, name := range result.Header() {
+			index := -1
+			for j := 1; j < len(header); j++ {
+				if header[j] == name && !used[j] {
+					index = j
+					break
+				}
+			}
+			if index < 0 {
+				header = append(header, name)
+				index = len(header) - 1
+			}
+			used[index] = true
+			columns[i] = index
+		}
+
+		sources = append(sources, &fanOutSource{
+			connection: fc.Connection,
+			result:     result,
+			columns:    columns,
+		})
+	}
+
+	return &fanOutStream{
+		header:  header,
+		meta:    &Meta{SchemaType: SchemaFul},
+		sources: sources,
+		summary: newRowsStream(FanOutSummaryHeader, summary),
+	}, nil
+}
+
+func errorText(err error) any {
+	if err == nil {
+		return nil
+	}
+	return err.Error()
+}
+
+var _ MultiResultStream = (*fanOutStream)(nil)
+
+// fanOutSource is the result of a single fan-out call.
+type fanOutSource struct {
+	connection string
+	result     *Result
+	// columns maps indexes of result columns to indexes in the combined header
+	columns []int
+}
+
+// fanOutStream streams combined rows of fan-out calls, followed by their summary.
+type fanOutStream struct {
+	header  Header
+	meta    *Meta
+	sources []*fanOutSource
+
+	// current source and its batch of rows
+	source int
+	from   int
+	batch  []Row
+	index  int
+	err    error
+
+	summary   *rowsStream
+	inSummary bool
+}
+
+func (s *fanOutStream) Meta() *Meta {
+	if s.inSummary {
+		return s.summary.Meta()
+	}
+	return s.meta
+}
+
+func (s *fanOutStream) Header() Header {
+	if s.inSummary {
+		return s.summary.Header()
+	}
+	return s.header
+}
+
+func (s *fanOutStream) HasNext() bool {
+	if s.inSummary {
+		return s.summary.HasNext()
+	}
+	if s.err != nil {
+		return true
+	}
+
+	for s.index >= len(s.batch) {
+		if s.source >= len(s.sources) {
+			return false
+		}
+
+		rows, err := s.sources[s.source].result.Rows(s.from, s.from+archiveChunkSize)
+		if err != nil {
+			s.err = fmt.Errorf("result.Rows: %w", err)
+			return true
+		}
+		if len(rows) < 1 {
+			s.source++
+			s.from = 0
+			continue
+		}
+
+		s.batch = rows
+		s.index = 0
+		s.from += len(rows)
+	}
+
+	return true
+}
+
+func (s *fanOutStream) Next() (Row, error) {
+	if s.inSummary {
+		return s.summary.Next()
+	}
+	if !s.HasNext() {
+		return nil, errors.New("no next row")
+	}
+	if s.err != nil {
+		err := s.err
+		s.err = nil
+		// skip the rest of the failed source
+		s.source++
+		s.from = 0
+		s.batch = nil
+		return nil, err
+	}
+
+	src := s.sources[s.source]
+	row := s.batch[s.index]
+	s.index++
+
+	combined := make(Row, len(s.header))
+	combined[0] = src.connection
+	for i, index := range src.columns {
+		if i < len(row) {
+			combined[index] = row[i]
+		}
+	}
+	return combined, nil
+}
+
+func (s *fanOutStream) NextResultSet() bool {
+	if s.inSummary {
+		return false
+	}
+	s.inSummary = true
+	return true
+}
+
+func (s *fanOutStream) Close() {}
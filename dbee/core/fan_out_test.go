@@ -0,0 +1,93 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
+)
+
+func TestCombineFanOut(t *testing.T) {
+	r := require.New(t)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(nil))
+	r.NoError(err)
+
+	execute := func(stream func() (core.ResultStream, error)) *core.Call {
+		call := connection.ExecuteStream(core.CallKindFanOut, "_", func(context.Context) (core.ResultStream, error) {
+			return stream()
+		}, nil)
+
+		select {
+		case <-call.Done():
+			// wait a bit for state to stabilize
+			time.Sleep(100 * time.Millisecond)
+		case <-time.After(5 * time.Second):
+			t.Fatal("call did not finish in expected time")
+		}
+		return call
+	}
+
+	first := execute(func() (core.ResultStream, error) {
+		return mock.NewResultStream(
+			[]core.Row{{1, "a"}, {2, "b"}},
+			mock.ResultStreamWithHeader(core.Header{"id", "name"}),
+		), nil
+	})
+	second := execute(func() (core.ResultStream, error) {
+		return mock.NewResultStream(
+			[]core.Row{{"x", 3, 4}},
+			mock.ResultStreamWithHeader(core.Header{"extra", "id", "id"}),
+		), nil
+	})
+	failed := execute(func() (core.ResultStream, error) {
+		return nil, errors.New("relation does not exist")
+	})
+
+	stream, err := core.CombineFanOut([]*core.FanOutCall{
+		{Connection: "shard_1", Call: first},
+		{Connection: "shard_2", Call: second},
+		{Connection: "shard_3", Call: failed},
+		{Connection: "shard_4", Err: errors.New("connection refused")},
+		{Connection: "shard_5", Call: failed, Err: core.ErrFanOutNeedsConfirmation},
+	})
+	r.NoError(err)
+
+	multi, ok := stream.(core.MultiResultStream)
+	r.True(ok)
+
+	drain := func() []core.Row {
+		var rows []core.Row
+		for multi.HasNext() {
+			row, err := multi.Next()
+			r.NoError(err)
+			rows = append(rows, row)
+		}
+		return rows
+	}
+
+	r.Equal(core.Header{"connection", "id", "name", "extra", "id"}, multi.Header())
+	r.Equal([]core.Row{
+		{"shard_1", 1, "a", nil, nil},
+		{"shard_1", 2, "b", nil, nil},
+		{"shard_2", 3, nil, "x", 4},
+	}, drain())
+
+	r.True(multi.NextResultSet())
+	r.Equal(core.FanOutSummaryHeader, multi.Header())
+
+	summary := drain()
+	r.Len(summary, 5)
+	r.Equal([]any{"shard_1", string(first.GetID()), "archived", 2}, []any(summary[0][:4]))
+	r.Equal([]any{"shard_3", "executing_failed", "relation does not exist"}, []any{summary[2][0], summary[2][2], summary[2][5]})
+	r.Equal(core.Row{"shard_4", nil, nil, 0, nil, "connection refused"}, summary[3])
+	// reason the call was stopped is reported instead of its error
+	r.Equal(core.ErrFanOutNeedsConfirmation.Error(), summary[4][5])
+
+	r.False(multi.NextResultSet())
+}
@@ -0,0 +1,92 @@
+package format
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+var (
+	_ core.Formatter          = (*AsciiDoc)(nil)
+	_ core.MeasuringFormatter = (*AsciiDoc)(nil)
+)
+
+// AsciiDoc renders rows as an AsciiDoc table, with alignment set in the cols attribute.
+type AsciiDoc struct {
+	columns markupColumns
+}
+
+func NewAsciiDoc(opts *MarkupOptions) *AsciiDoc {
+	return &AsciiDoc{
+		columns: newMarkupColumns(opts, escapeAsciiDoc),
+	}
+}
+
+// escapeAsciiDoc escapes cell separators and keeps line breaks as hard line breaks.
+func escapeAsciiDoc(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", " +\n", "\n", " +\n").Replace(s)
+}
+
+func (af *AsciiDoc) Format(header core.Header, rows []core.Row, opts *core.FormatterOptions) ([]byte, error) {
+	return formatAll(NewAsciiDoc(&af.columns.opts), header, rows, opts)
+}
+
+func (af *AsciiDoc) MeasureRows(rows []core.Row, _ *core.FormatterOptions) {
+	af.columns.measureRows(rows)
+}
+
+func (af *AsciiDoc) WriteHeader(w io.Writer, header core.Header, _ *core.FormatterOptions) error {
+	af.columns.setHeader(header)
+
+	var sb strings.Builder
+
+	attributes := []string{`options="header"`}
+	if af.columns.opts.AlignByType && len(header) > 0 {
+		cols := make([]string, len(header))
+		for i := range header {
+			switch af.columns.align(i) {
+			case alignCenter:
+				cols[i] = "^1"
+			case alignRight:
+				cols[i] = ">1"
+			default:
+				cols[i] = "<1"
+			}
+		}
+		attributes = append([]string{`cols="` + strings.Join(cols, ",") + `"`}, attributes...)
+	}
+	sb.WriteString("[" + strings.Join(attributes, ",") + "]\n|===\n")
+
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = "|" + af.columns.text(name)
+	}
+	sb.WriteString(strings.Join(names, " ") + "\n\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (af *AsciiDoc) WriteRows(w io.Writer, rows []core.Row, _ *core.FormatterOptions) error {
+	var sb strings.Builder
+	for _, row := range rows {
+		cells := make([]string, len(af.columns.header))
+		for i := range cells {
+			var value any
+			if i < len(row) {
+				value = row[i]
+			}
+			cells[i] = "|" + af.columns.cell(value)
+		}
+		sb.WriteString(strings.Join(cells, " ") + "\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (af *AsciiDoc) WriteFooter(w io.Writer, _ *core.FormatterOptions) error {
+	_, err := io.WriteString(w, "|===\n")
+	return err
+}
@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"encoding/csv"
 	"fmt"
+	"io"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 )
 
-var _ core.Formatter = (*CSV)(nil)
+var (
+	_ core.Formatter       = (*CSV)(nil)
+	_ core.StreamFormatter = (*CSV)(nil)
+)
 
 type CSV struct{}
 
@@ -16,10 +20,8 @@ func NewCSV() *CSV {
 	return &CSV{}
 }
 
-func (cf *CSV) parseSchemaFul(header core.Header, rows []core.Row) [][]string {
-	data := [][]string{
-		header,
-	}
+func (cf *CSV) parseRows(rows []core.Row) [][]string {
+	data := make([][]string, 0, len(rows))
 	for _, row := range rows {
 		var csvRow []string
 		for _, rec := range row {
@@ -31,6 +33,10 @@ func (cf *CSV) parseSchemaFul(header core.Header, rows []core.Row) [][]string {
 	return data
 }
 
+func (cf *CSV) parseSchemaFul(header core.Header, rows []core.Row) [][]string {
+	return append([][]string{header}, cf.parseRows(rows)...)
+}
+
 func (cf *CSV) Format(header core.Header, rows []core.Row, _ *core.FormatterOptions) ([]byte, error) {
 	// parse as if schema is defined regardles of schema presence in the result
 	data := cf.parseSchemaFul(header, rows)
@@ -45,3 +51,23 @@ func (cf *CSV) Format(header core.Header, rows []core.Row, _ *core.FormatterOpti
 
 	return b.Bytes(), nil
 }
+
+func (cf *CSV) WriteHeader(w io.Writer, header core.Header, _ *core.FormatterOptions) error {
+	err := csv.NewWriter(w).WriteAll([][]string{header})
+	if err != nil {
+		return fmt.Errorf("w.WriteAll: %w", err)
+	}
+	return nil
+}
+
+func (cf *CSV) WriteRows(w io.Writer, rows []core.Row, _ *core.FormatterOptions) error {
+	err := csv.NewWriter(w).WriteAll(cf.parseRows(rows))
+	if err != nil {
+		return fmt.Errorf("w.WriteAll: %w", err)
+	}
+	return nil
+}
+
+func (cf *CSV) WriteFooter(io.Writer, *core.FormatterOptions) error {
+	return nil
+}
@@ -0,0 +1,89 @@
+package format
+
+import (
+	"html"
+	"io"
+	"strings"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+var (
+	_ core.Formatter          = (*HTML)(nil)
+	_ core.MeasuringFormatter = (*HTML)(nil)
+)
+
+// HTML renders rows as a standalone HTML table, with alignment set by inline styles.
+type HTML struct {
+	columns markupColumns
+}
+
+func NewHTML(opts *MarkupOptions) *HTML {
+	return &HTML{
+		columns: newMarkupColumns(opts, escapeHTML),
+	}
+}
+
+// escapeHTML escapes special characters and replaces line breaks with <br>.
+func escapeHTML(s string) string {
+	return strings.NewReplacer("\r\n", "<br>", "\n", "<br>").Replace(html.EscapeString(s))
+}
+
+func (hf *HTML) Format(header core.Header, rows []core.Row, opts *core.FormatterOptions) ([]byte, error) {
+	return formatAll(NewHTML(&hf.columns.opts), header, rows, opts)
+}
+
+func (hf *HTML) MeasureRows(rows []core.Row, _ *core.FormatterOptions) {
+	hf.columns.measureRows(rows)
+}
+
+func (hf *HTML) WriteHeader(w io.Writer, header core.Header, _ *core.FormatterOptions) error {
+	hf.columns.setHeader(header)
+
+	var sb strings.Builder
+	sb.WriteString("<table>\n<thead>\n<tr>")
+	for i, name := range header {
+		sb.WriteString("<th" + hf.style(i) + ">" + hf.columns.text(name) + "</th>")
+	}
+	sb.WriteString("</tr>\n</thead>\n<tbody>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (hf *HTML) WriteRows(w io.Writer, rows []core.Row, _ *core.FormatterOptions) error {
+	var sb strings.Builder
+	for _, row := range rows {
+		sb.WriteString("<tr>")
+		for i := range hf.columns.header {
+			var value any
+			if i < len(row) {
+				value = row[i]
+			}
+			sb.WriteString("<td" + hf.style(i) + ">" + hf.columns.cell(value) + "</td>")
+		}
+		sb.WriteString("</tr>\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (hf *HTML) WriteFooter(w io.Writer, _ *core.FormatterOptions) error {
+	_, err := io.WriteString(w, "</tbody>\n</table>\n")
+	return err
+}
+
+// style returns the style attribute with alignment of the column.
+func (hf *HTML) style(column int) string {
+	switch hf.columns.align(column) {
+	case alignLeft:
+		return ` style="text-align: left"`
+	case alignCenter:
+		return ` style="text-align: center"`
+	case alignRight:
+		return ` style="text-align: right"`
+	default:
+		return ""
+	}
+}
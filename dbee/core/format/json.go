@@ -3,13 +3,21 @@ package format
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 )
 
-var _ core.Formatter = (*JSON)(nil)
+var (
+	_ core.Formatter       = (*JSON)(nil)
+	_ core.StreamFormatter = (*JSON)(nil)
+)
 
-type JSON struct{}
+type JSON struct {
+	// state of the stream output
+	header  core.Header
+	written int
+}
 
 func NewJSON() *JSON {
 	return &JSON{}
@@ -48,16 +56,20 @@ func (jf *JSON) parseSchemaLess(header core.Header, rows []core.Row) []any {
 	return data
 }
 
-func (jf *JSON) Format(header core.Header, rows []core.Row, opts *core.FormatterOptions) ([]byte, error) {
-	var data any
+// parse returns values of rows in the shape of the schema type.
+func (jf *JSON) parse(header core.Header, rows []core.Row, opts *core.FormatterOptions) any {
 	switch opts.SchemaType {
 	case core.SchemaLess:
-		data = jf.parseSchemaLess(header, rows)
+		return jf.parseSchemaLess(header, rows)
 	case core.SchemaFul:
 		fallthrough
 	default:
-		data = jf.parseSchemaFul(header, rows)
+		return jf.parseSchemaFul(header, rows)
 	}
+}
+
+func (jf *JSON) Format(header core.Header, rows []core.Row, opts *core.FormatterOptions) ([]byte, error) {
+	data := jf.parse(header, rows, opts)
 
 	out, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
@@ -66,3 +78,55 @@ func (jf *JSON) Format(header core.Header, rows []core.Row, opts *core.Formatter
 
 	return out, nil
 }
+
+// WriteHeader starts the array of values, header is kept to name fields of rows.
+func (jf *JSON) WriteHeader(w io.Writer, header core.Header, _ *core.FormatterOptions) error {
+	jf.header = header
+	jf.written = 0
+
+	_, err := io.WriteString(w, "[")
+	return err
+}
+
+// WriteRows writes values of rows as elements of the array, indented the same as Format.
+func (jf *JSON) WriteRows(w io.Writer, rows []core.Row, opts *core.FormatterOptions) error {
+	var values []any
+	switch data := jf.parse(jf.header, rows, opts).(type) {
+	case []any:
+		values = data
+	case []map[string]any:
+		for _, record := range data {
+			values = append(values, record)
+		}
+	}
+
+	for _, value := range values {
+		out, err := json.MarshalIndent(value, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("json.MarshalIndent: %w", err)
+		}
+
+		separator := ",\n  "
+		if jf.written == 0 {
+			separator = "\n  "
+		}
+		jf.written++
+
+		_, err = w.Write(append([]byte(separator), out...))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (jf *JSON) WriteFooter(w io.Writer, _ *core.FormatterOptions) error {
+	end := "\n]"
+	if jf.written == 0 {
+		end = "]"
+	}
+
+	_, err := io.WriteString(w, end)
+	return err
+}
@@ -0,0 +1,110 @@
+package format
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+var (
+	_ core.Formatter          = (*Markdown)(nil)
+	_ core.MeasuringFormatter = (*Markdown)(nil)
+)
+
+// Markdown renders rows as a GitHub flavored Markdown table, with columns padded to the same width.
+type Markdown struct {
+	columns markupColumns
+}
+
+func NewMarkdown(opts *MarkupOptions) *Markdown {
+	return &Markdown{
+		columns: newMarkupColumns(opts, escapeMarkdown),
+	}
+}
+
+// escapeMarkdown escapes pipes and replaces line breaks, which would end the table row.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace(s)
+}
+
+func (mf *Markdown) Format(header core.Header, rows []core.Row, opts *core.FormatterOptions) ([]byte, error) {
+	return formatAll(NewMarkdown(&mf.columns.opts), header, rows, opts)
+}
+
+func (mf *Markdown) MeasureRows(rows []core.Row, _ *core.FormatterOptions) {
+	mf.columns.measureRows(rows)
+}
+
+// WriteHeader writes the header and the delimiter row, which holds alignment of columns.
+func (mf *Markdown) WriteHeader(w io.Writer, header core.Header, _ *core.FormatterOptions) error {
+	mf.columns.setHeader(header)
+
+	names := make([]string, len(header))
+	delimiters := make([]string, len(header))
+	for i, name := range header {
+		names[i] = mf.pad(i, mf.columns.text(name))
+
+		// delimiters are at least 3 characters wide
+		width := max(mf.columns.widths[i], 3)
+		switch mf.columns.align(i) {
+		case alignLeft:
+			delimiters[i] = ":" + strings.Repeat("-", width-1)
+		case alignCenter:
+			delimiters[i] = ":" + strings.Repeat("-", width-2) + ":"
+		case alignRight:
+			delimiters[i] = strings.Repeat("-", width-1) + ":"
+		default:
+			delimiters[i] = strings.Repeat("-", width)
+		}
+	}
+
+	_, err := io.WriteString(w, mf.row(names)+mf.row(delimiters))
+	return err
+}
+
+func (mf *Markdown) WriteRows(w io.Writer, rows []core.Row, _ *core.FormatterOptions) error {
+	var sb strings.Builder
+	for _, row := range rows {
+		cells := make([]string, len(mf.columns.header))
+		for i := range cells {
+			var value any
+			if i < len(row) {
+				value = row[i]
+			}
+			cells[i] = mf.pad(i, mf.columns.cell(value))
+		}
+		sb.WriteString(mf.row(cells))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (mf *Markdown) WriteFooter(io.Writer, *core.FormatterOptions) error {
+	return nil
+}
+
+// pad pads the text to the width of the column, on the left side for right aligned columns.
+func (mf *Markdown) pad(column int, text string) string {
+	width := 3
+	if column < len(mf.columns.widths) {
+		width = max(mf.columns.widths[column], width)
+	}
+	padding := strings.Repeat(" ", max(width-utf8.RuneCountInString(text), 0))
+
+	switch mf.columns.align(column) {
+	case alignRight:
+		return padding + text
+	case alignCenter:
+		half := len(padding) / 2
+		return padding[:half] + text + padding[half:]
+	default:
+		return text + padding
+	}
+}
+
+func (mf *Markdown) row(cells []string) string {
+	return "| " + strings.Join(cells, " | ") + " |\n"
+}
@@ -0,0 +1,171 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+// MarkupOptions configure the Markdown, HTML and AsciiDoc formatters.
+type MarkupOptions struct {
+	// AlignByType aligns columns by the type of their values: numbers to the right,
+	// booleans to the center and the rest to the left. Columns aren't aligned otherwise.
+	AlignByType bool
+	// MaxCellWidth is the maximum number of characters of a cell, longer cells are truncated
+	// and end with an ellipsis. Cells aren't truncated if it's not positive.
+	MaxCellWidth int
+}
+
+// markupNull is the text of NULL cells.
+const markupNull = "NULL"
+
+// columnKind is the type of all non-NULL values of a column.
+type columnKind int
+
+const (
+	kindUnknown columnKind = iota
+	kindNumber
+	kindBool
+	kindText
+)
+
+func kindOf(value any) columnKind {
+	if value == nil {
+		return kindUnknown
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return kindNumber
+	case reflect.Bool:
+		return kindBool
+	default:
+		return kindText
+	}
+}
+
+// alignment of a markup column.
+type alignment int
+
+const (
+	alignNone alignment = iota
+	alignLeft
+	alignCenter
+	alignRight
+)
+
+// markupColumns are the state shared by markup formatters: the header and kinds and widths
+// of columns, measured over all rows before the header is written.
+type markupColumns struct {
+	opts MarkupOptions
+	// escape escapes text of cells for the markup (widths are measured on escaped text)
+	escape func(string) string
+
+	header core.Header
+	kinds  []columnKind
+	widths []int
+}
+
+func newMarkupColumns(opts *MarkupOptions, escape func(string) string) markupColumns {
+	var o MarkupOptions
+	if opts != nil {
+		o = *opts
+	}
+	return markupColumns{opts: o, escape: escape}
+}
+
+// grow makes sure kinds and widths are known for the number of columns.
+func (mc *markupColumns) grow(columns int) {
+	for len(mc.kinds) < columns {
+		mc.kinds = append(mc.kinds, kindUnknown)
+		mc.widths = append(mc.widths, 0)
+	}
+}
+
+func (mc *markupColumns) measureRows(rows []core.Row) {
+	for _, row := range rows {
+		mc.grow(len(row))
+		for i, value := range row {
+			mc.widths[i] = max(mc.widths[i], utf8.RuneCountInString(mc.cell(value)))
+
+			kind := kindOf(value)
+			switch {
+			case kind == kindUnknown || kind == mc.kinds[i]:
+			case mc.kinds[i] == kindUnknown:
+				mc.kinds[i] = kind
+			default:
+				mc.kinds[i] = kindText
+			}
+		}
+	}
+}
+
+// setHeader keeps the header, whose names also count to widths.
+func (mc *markupColumns) setHeader(header core.Header) {
+	mc.header = header
+	mc.grow(len(header))
+	for i, name := range header {
+		mc.widths[i] = max(mc.widths[i], utf8.RuneCountInString(mc.text(name)))
+	}
+}
+
+// align returns the alignment of the column.
+func (mc *markupColumns) align(column int) alignment {
+	if !mc.opts.AlignByType {
+		return alignNone
+	}
+	if column >= len(mc.kinds) {
+		return alignLeft
+	}
+
+	switch mc.kinds[column] {
+	case kindNumber:
+		return alignRight
+	case kindBool:
+		return alignCenter
+	default:
+		return alignLeft
+	}
+}
+
+// cell returns escaped and truncated text of the value.
+func (mc *markupColumns) cell(value any) string {
+	if value == nil {
+		return markupNull
+	}
+	return mc.text(fmt.Sprint(value))
+}
+
+// text returns the escaped and truncated text.
+func (mc *markupColumns) text(s string) string {
+	if mc.opts.MaxCellWidth > 0 && utf8.RuneCountInString(s) > mc.opts.MaxCellWidth {
+		s = string([]rune(s)[:mc.opts.MaxCellWidth-1]) + "…"
+	}
+	return mc.escape(s)
+}
+
+// formatAll formats all rows at once with a new stream formatter.
+func formatAll(formatter core.MeasuringFormatter, header core.Header, rows []core.Row, opts *core.FormatterOptions) ([]byte, error) {
+	var buf bytes.Buffer
+
+	formatter.MeasureRows(rows, opts)
+	err := formatter.WriteHeader(&buf, header, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = formatter.WriteRows(&buf, rows, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = formatter.WriteFooter(&buf, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
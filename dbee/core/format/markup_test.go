@@ -0,0 +1,132 @@
+package format_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/format"
+)
+
+// markupFormatter formats the whole output or streams it.
+type markupFormatter interface {
+	core.Formatter
+	core.MeasuringFormatter
+}
+
+func TestMarkup(t *testing.T) {
+	header := core.Header{"id", "name", "active"}
+	rows := []core.Row{
+		{1, "a|b", true},
+		{nil, "<i>long\nname</i>", false},
+		{100, nil, nil},
+	}
+
+	type testCase struct {
+		name      string
+		formatter func(opts *format.MarkupOptions) markupFormatter
+		opts      *format.MarkupOptions
+		expected  string
+	}
+
+	markdown := func(opts *format.MarkupOptions) markupFormatter { return format.NewMarkdown(opts) }
+	html := func(opts *format.MarkupOptions) markupFormatter { return format.NewHTML(opts) }
+	asciidoc := func(opts *format.MarkupOptions) markupFormatter { return format.NewAsciiDoc(opts) }
+
+	testCases := []testCase{
+		{
+			name:      "markdown",
+			formatter: markdown,
+			opts:      &format.MarkupOptions{},
+			expected: `| id   | name                | active |
+| ---- | ------------------- | ------ |
+| 1    | a\|b                | true   |
+| NULL | <i>long<br>name</i> | false  |
+| 100  | NULL                | NULL   |
+`,
+		},
+		{
+			name:      "markdown aligned and truncated",
+			formatter: markdown,
+			opts:      &format.MarkupOptions{AlignByType: true, MaxCellWidth: 6},
+			expected: `|   id | name   | active |
+| ---: | :----- | :----: |
+|    1 | a\|b   |  true  |
+| NULL | <i>lo… | false  |
+|  100 | NULL   |  NULL  |
+`,
+		},
+		{
+			name:      "html aligned",
+			formatter: html,
+			opts:      &format.MarkupOptions{AlignByType: true},
+			expected: `<table>
+<thead>
+<tr><th style="text-align: right">id</th><th style="text-align: left">name</th><th style="text-align: center">active</th></tr>
+</thead>
+<tbody>
+<tr><td style="text-align: right">1</td><td style="text-align: left">a|b</td><td style="text-align: center">true</td></tr>
+<tr><td style="text-align: right">NULL</td><td style="text-align: left">&lt;i&gt;long<br>name&lt;/i&gt;</td><td style="text-align: center">false</td></tr>
+<tr><td style="text-align: right">100</td><td style="text-align: left">NULL</td><td style="text-align: center">NULL</td></tr>
+</tbody>
+</table>
+`,
+		},
+		{
+			name:      "html truncated",
+			formatter: html,
+			opts:      &format.MarkupOptions{MaxCellWidth: 4},
+			expected: `<table>
+<thead>
+<tr><th>id</th><th>name</th><th>act…</th></tr>
+</thead>
+<tbody>
+<tr><td>1</td><td>a|b</td><td>true</td></tr>
+<tr><td>NULL</td><td>&lt;i&gt;…</td><td>fal…</td></tr>
+<tr><td>100</td><td>NULL</td><td>NULL</td></tr>
+</tbody>
+</table>
+`,
+		},
+		{
+			name:      "asciidoc aligned",
+			formatter: asciidoc,
+			opts:      &format.MarkupOptions{AlignByType: true},
+			expected: `[cols=">1,<1,^1",options="header"]
+|===
+|id |name |active
+
+|1 |a\|b |true
+|NULL |<i>long +
+name</i> |false
+|100 |NULL |NULL
+|===
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			out, err := tc.formatter(tc.opts).Format(header, rows, &core.FormatterOptions{})
+			r.NoError(err)
+			r.Equal(tc.expected, string(out))
+
+			// streamed output is the same
+			formatter := tc.formatter(tc.opts)
+			var buf bytes.Buffer
+			for _, row := range rows {
+				formatter.MeasureRows([]core.Row{row}, &core.FormatterOptions{})
+			}
+			r.NoError(formatter.WriteHeader(&buf, header, &core.FormatterOptions{}))
+			for _, row := range rows {
+				r.NoError(formatter.WriteRows(&buf, []core.Row{row}, &core.FormatterOptions{}))
+			}
+			r.NoError(formatter.WriteFooter(&buf, &core.FormatterOptions{}))
+			r.Equal(tc.expected, buf.String())
+		})
+	}
+}
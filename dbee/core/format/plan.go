@@ -0,0 +1,78 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+// PlanText renders a query plan as an indented tree of text lines.
+type PlanText struct{}
+
+func NewPlanText() *PlanText {
+	return &PlanText{}
+}
+
+func (pf *PlanText) Format(plan *core.PlanNode) ([]byte, error) {
+	if plan == nil {
+		return nil, errors.New("no plan to format")
+	}
+
+	var buf bytes.Buffer
+	pf.writeNode(&buf, plan, "", "")
+
+	return buf.Bytes(), nil
+}
+
+// writeNode writes the node with prefix and its children with childPrefix (for connecting lines).
+func (pf *PlanText) writeNode(buf *bytes.Buffer, node *core.PlanNode, prefix, childPrefix string) {
+	buf.WriteString(prefix)
+	buf.WriteString(pf.nodeLine(node))
+	buf.WriteByte('\n')
+
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			pf.writeNode(buf, child, childPrefix+"└─ ", childPrefix+"   ")
+		} else {
+			pf.writeNode(buf, child, childPrefix+"├─ ", childPrefix+"│  ")
+		}
+	}
+}
+
+func (*PlanText) nodeLine(node *core.PlanNode) string {
+	line := node.Operation
+	if node.Detail != "" {
+		line += " " + node.Detail
+	}
+	// detail might span multiple lines (e.g. conditions)
+	line = strings.Join(strings.Fields(line), " ")
+
+	var stats []string
+	if node.Cost != 0 {
+		stats = append(stats, "cost="+formatPlanNumber(node.Cost))
+	}
+	if node.Rows != 0 {
+		stats = append(stats, "rows="+formatPlanNumber(node.Rows))
+	}
+	if node.ActualRows != 0 {
+		stats = append(stats, "actual_rows="+formatPlanNumber(node.ActualRows))
+	}
+	if node.Time != 0 {
+		stats = append(stats, "time="+node.Time.String())
+	}
+
+	if len(stats) > 0 {
+		line += "  (" + strings.Join(stats, " ") + ")"
+	}
+
+	return line
+}
+
+// formatPlanNumber formats a number with at most 2 decimal places.
+func formatPlanNumber(n float64) string {
+	return strconv.FormatFloat(math.Round(n*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,346 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+// SQLStatement is the kind of statements the SQL formatter renders.
+type SQLStatement string
+
+const (
+	// SQLInsert renders an INSERT statement per row.
+	SQLInsert SQLStatement = "insert"
+	// SQLInsertMulti renders INSERT statements with multiple rows each (see SQLOptions.BatchSize).
+	SQLInsertMulti SQLStatement = "insert_multi"
+	// SQLUpdate renders an UPDATE statement per row, matching rows by key columns.
+	SQLUpdate SQLStatement = "update"
+	// SQLUpsert renders an upsert per row, matching rows by key columns: INSERT ... ON CONFLICT
+	// (postgres and sqlite), INSERT ... ON DUPLICATE KEY UPDATE (mysql) or MERGE (the rest).
+	SQLUpsert SQLStatement = "upsert"
+)
+
+// DefaultSQLBatchSize is the default number of rows of multi-row INSERT statements.
+const DefaultSQLBatchSize = 100
+
+// maxSQLServerBatchSize is the maximum number of rows of a VALUES list in SQL Server.
+const maxSQLServerBatchSize = 1000
+
+// SQLOptions configure the SQL formatter.
+type SQLOptions struct {
+	// Table is the target table, optionally qualified (e.g. "schema.table"). Parts of the name
+	// can be quoted with double quotes, backticks or brackets if they contain dots (e.g. "my.schema".table).
+	Table string
+	// KeyColumns identify rows of UPDATE statements and upserts.
+	KeyColumns []string
+	// Statement is the kind of statements, SQLInsert by default.
+	Statement SQLStatement
+	// Dialect is the connection type (e.g. "postgres"), which determines quoting of identifiers
+	// and literals. Unknown types use ANSI SQL.
+	Dialect string
+	// BatchSize is the number of rows of multi-row INSERT statements, DefaultSQLBatchSize if not positive.
+	BatchSize int
+}
+
+var (
+	_ core.Formatter       = (*SQL)(nil)
+	_ core.StreamFormatter = (*SQL)(nil)
+)
+
+// SQL renders rows as statements which load them to the target table.
+type SQL struct {
+	opts    SQLOptions
+	dialect sqlDialect
+	// table is the quoted name of the target table
+	table string
+
+	// header of the stream output
+	header core.Header
+}
+
+func NewSQL(opts *SQLOptions) (*SQL, error) {
+	if opts == nil || strings.TrimSpace(opts.Table) == "" {
+		return nil, errors.New("no target table provided")
+	}
+
+	o := *opts
+	if o.Statement == "" {
+		o.Statement = SQLInsert
+	}
+	switch o.Statement {
+	case SQLInsert, SQLInsertMulti:
+	case SQLUpdate, SQLUpsert:
+		if len(o.KeyColumns) < 1 {
+			return nil, fmt.Errorf("%s statements need key columns", o.Statement)
+		}
+	default:
+		return nil, fmt.Errorf("unknown statement: %q", o.Statement)
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultSQLBatchSize
+	}
+
+	dialect := sqlDialectOf(o.Dialect)
+	if dialect == dialectSQLServer {
+		o.BatchSize = min(o.BatchSize, maxSQLServerBatchSize)
+	}
+
+	table, err := dialect.quoteTable(o.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQL{
+		opts:    o,
+		dialect: dialect,
+		table:   table,
+	}, nil
+}
+
+func (sf *SQL) Format(header core.Header, rows []core.Row, _ *core.FormatterOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	err := sf.writeStatements(&buf, header, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriteHeader checks that the header has all key columns.
+func (sf *SQL) WriteHeader(_ io.Writer, header core.Header, _ *core.FormatterOptions) error {
+	_, err := sf.columns(header)
+	if err != nil {
+		return err
+	}
+
+	sf.header = header
+	return nil
+}
+
+func (sf *SQL) WriteRows(w io.Writer, rows []core.Row, _ *core.FormatterOptions) error {
+	return sf.writeStatements(w, sf.header, rows)
+}
+
+func (sf *SQL) WriteFooter(io.Writer, *core.FormatterOptions) error {
+	return nil
+}
+
+// sqlColumns are quoted columns of the header, split to key and other columns.
+type sqlColumns struct {
+	all    []string
+	keys   []int
+	others []int
+}
+
+func (sf *SQL) columns(header core.Header) (*sqlColumns, error) {
+	if len(header) < 1 {
+		return nil, errors.New("result has no columns")
+	}
+
+	columns := &sqlColumns{all: make([]string, len(header))}
+	for i, name := range header {
+		columns.all[i] = sf.dialect.quoteIdentifier(name)
+		if !slices.Contains(sf.opts.KeyColumns, name) {
+			columns.others = append(columns.others, i)
+		}
+	}
+
+	for _, key := range sf.opts.KeyColumns {
+		index := slices.Index(header, key)
+		if index < 0 {
+			return nil, fmt.Errorf("unknown key column: %q", key)
+		}
+		columns.keys = append(columns.keys, index)
+	}
+
+	if sf.opts.Statement == SQLUpdate && len(columns.others) < 1 {
+		return nil, errors.New("update statements need columns which are not keys")
+	}
+
+	return columns, nil
+}
+
+// writeStatements writes statements of the rows, a statement per line.
+func (sf *SQL) writeStatements(w io.Writer, header core.Header, rows []core.Row) error {
+	columns, err := sf.columns(header)
+	if err != nil {
+		return err
+	}
+
+	table := sf.table
+
+	values := func(row core.Row) []string {
+		literals := make([]string, len(columns.all))
+		for i := range literals {
+			var value any
+			if i < len(row) {
+				value = row[i]
+			}
+			literals[i] = sf.dialect.literal(value)
+		}
+		return literals
+	}
+
+	var buf bytes.Buffer
+	switch sf.opts.Statement {
+	case SQLInsertMulti:
+		for start := 0; start < len(rows); start += sf.opts.BatchSize {
+			batch := make([][]string, 0, sf.opts.BatchSize)
+			for _, row := range rows[start:min(start+sf.opts.BatchSize, len(rows))] {
+				batch = append(batch, values(row))
+			}
+			sf.writeInsertMulti(&buf, table, columns, batch)
+		}
+	default:
+		for _, row := range rows {
+			literals := values(row)
+			switch sf.opts.Statement {
+			case SQLUpdate:
+				sf.writeUpdate(&buf, table, columns, literals)
+			case SQLUpsert:
+				sf.writeUpsert(&buf, table, columns, literals)
+			default:
+				fmt.Fprintf(&buf, "INSERT INTO %s (%s) VALUES (%s);\n", table, strings.Join(columns.all, ", "), strings.Join(literals, ", "))
+			}
+		}
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (sf *SQL) writeInsertMulti(buf *bytes.Buffer, table string, columns *sqlColumns, batch [][]string) {
+	cols := strings.Join(columns.all, ", ")
+
+	// oracle doesn't support multiple rows in VALUES
+	if sf.dialect == dialectOracle {
+		buf.WriteString("INSERT ALL\n")
+		for _, literals := range batch {
+			fmt.Fprintf(buf, "  INTO %s (%s) VALUES (%s)\n", table, cols, strings.Join(literals, ", "))
+		}
+		buf.WriteString("SELECT 1 FROM DUAL;\n")
+		return
+	}
+
+	fmt.Fprintf(buf, "INSERT INTO %s (%s) VALUES\n", table, cols)
+	for i, literals := range batch {
+		end := ",\n"
+		if i == len(batch)-1 {
+			end = ";\n"
+		}
+		fmt.Fprintf(buf, "  (%s)%s", strings.Join(literals, ", "), end)
+	}
+}
+
+func (sf *SQL) writeUpdate(buf *bytes.Buffer, table string, columns *sqlColumns, literals []string) {
+	set := make([]string, 0, len(columns.others))
+	for _, i := range columns.others {
+		set = append(set, columns.all[i]+" = "+literals[i])
+	}
+
+	where := make([]string, 0, len(columns.keys))
+	for _, i := range columns.keys {
+		if literals[i] == "NULL" {
+			where = append(where, columns.all[i]+" IS NULL")
+			continue
+		}
+		where = append(where, columns.all[i]+" = "+literals[i])
+	}
+
+	fmt.Fprintf(buf, "UPDATE %s SET %s WHERE %s;\n", table, strings.Join(set, ", "), strings.Join(where, " AND "))
+}
+
+func (sf *SQL) writeUpsert(buf *bytes.Buffer, table string, columns *sqlColumns, literals []string) {
+	cols := strings.Join(columns.all, ", ")
+	vals := strings.Join(literals, ", ")
+
+	switch sf.dialect {
+	case dialectPostgres, dialectSQLite:
+		keys := make([]string, 0, len(columns.keys))
+		for _, i := range columns.keys {
+			keys = append(keys, columns.all[i])
+		}
+
+		action := "DO NOTHING"
+		if len(columns.others) > 0 {
+			set := make([]string, 0, len(columns.others))
+			for _, i := range columns.others {
+				set = append(set, columns.all[i]+" = EXCLUDED."+columns.all[i])
+			}
+			action = "DO UPDATE SET " + strings.Join(set, ", ")
+		}
+
+		fmt.Fprintf(buf, "INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s;\n", table, cols, vals, strings.Join(keys, ", "), action)
+
+	case dialectMySQL:
+		// without other columns, a key is set to itself to ignore duplicates
+		updated := columns.others
+		if len(updated) < 1 {
+			updated = columns.keys[:1]
+		}
+		set := make([]string, 0, len(updated))
+		for _, i := range updated {
+			set = append(set, columns.all[i]+" = VALUES("+columns.all[i]+")")
+		}
+
+		fmt.Fprintf(buf, "INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s;\n", table, cols, vals, strings.Join(set, ", "))
+
+	default:
+		sf.writeMerge(buf, table, columns, literals)
+	}
+}
+
+// writeMerge writes a MERGE statement, with the row as source "s" of target "t".
+func (sf *SQL) writeMerge(buf *bytes.Buffer, table string, columns *sqlColumns, literals []string) {
+	var source string
+	switch sf.dialect {
+	case dialectSQLServer:
+		source = fmt.Sprintf("(VALUES (%s)) AS s (%s)", strings.Join(literals, ", "), strings.Join(columns.all, ", "))
+	case dialectOracle:
+		selected := make([]string, len(literals))
+		for i, literal := range literals {
+			selected[i] = literal + " " + columns.all[i]
+		}
+		source = fmt.Sprintf("(SELECT %s FROM DUAL) s", strings.Join(selected, ", "))
+	default:
+		selected := make([]string, len(literals))
+		for i, literal := range literals {
+			selected[i] = literal + " AS " + columns.all[i]
+		}
+		source = fmt.Sprintf("(SELECT %s) AS s", strings.Join(selected, ", "))
+	}
+
+	target := table + " AS t"
+	if sf.dialect == dialectOracle {
+		target = table + " t"
+	}
+
+	on := make([]string, 0, len(columns.keys))
+	for _, i := range columns.keys {
+		on = append(on, "t."+columns.all[i]+" = s."+columns.all[i])
+	}
+
+	var matched string
+	if len(columns.others) > 0 {
+		set := make([]string, 0, len(columns.others))
+		for _, i := range columns.others {
+			set = append(set, columns.all[i]+" = s."+columns.all[i])
+		}
+		matched = " WHEN MATCHED THEN UPDATE SET " + strings.Join(set, ", ")
+	}
+
+	inserted := make([]string, len(columns.all))
+	for i, column := range columns.all {
+		inserted[i] = "s." + column
+	}
+
+	fmt.Fprintf(buf, "MERGE INTO %s USING %s ON (%s)%s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);\n",
+		target, source, strings.Join(on, " AND "), matched, strings.Join(columns.all, ", "), strings.Join(inserted, ", "))
+}
@@ -0,0 +1,277 @@
+package format
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlDialect determines quoting of identifiers and literals of a database.
+type sqlDialect string
+
+const (
+	dialectGeneric   sqlDialect = "generic"
+	dialectPostgres  sqlDialect = "postgres"
+	dialectMySQL     sqlDialect = "mysql"
+	dialectSQLServer sqlDialect = "sqlserver"
+	dialectOracle    sqlDialect = "oracle"
+	dialectSQLite    sqlDialect = "sqlite"
+	dialectSnowflake sqlDialect = "snowflake"
+	dialectBigQuery  sqlDialect = "bigquery"
+)
+
+// sqlDialectOf returns the dialect of the connection type (including its aliases).
+// Unknown types use ANSI SQL.
+func sqlDialectOf(typ string) sqlDialect {
+	switch strings.ToLower(typ) {
+	case "postgres", "postgresql", "pg":
+		return dialectPostgres
+	case "mysql", "mariadb":
+		return dialectMySQL
+	case "sqlserver", "mssql":
+		return dialectSQLServer
+	case "oracle":
+		return dialectOracle
+	case "sqlite", "sqlite3", "scratch":
+		return dialectSQLite
+	case "snowflake", "sf":
+		return dialectSnowflake
+	case "bigquery":
+		return dialectBigQuery
+	default:
+		return dialectGeneric
+	}
+}
+
+// quoteIdentifier quotes a single identifier.
+func (d sqlDialect) quoteIdentifier(name string) string {
+	switch d {
+	case dialectMySQL:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case dialectBigQuery:
+		return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+	case dialectSQLServer:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// quoteTable quotes every part of a (qualified) table name (see splitTableName).
+func (d sqlDialect) quoteTable(name string) (string, error) {
+	parts, err := splitTableName(name)
+	if err != nil {
+		return "", err
+	}
+	for i, part := range parts {
+		parts[i] = d.quoteIdentifier(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// splitTableName splits a qualified table name to its parts. Parts can be quoted with double quotes,
+// backticks or brackets (e.g. "my.schema".table), so that they can contain dots and quotes
+// (escaped by doubling them). Unquoted parts are trimmed.
+func splitTableName(name string) ([]string, error) {
+	var parts []string
+
+	rest := strings.TrimSpace(name)
+	for {
+		var part string
+		if rest != "" && strings.ContainsRune("\"`[", rune(rest[0])) {
+			closing := rest[0]
+			if closing == '[' {
+				closing = ']'
+			}
+
+			var sb strings.Builder
+			i := 1
+			for ; ; i++ {
+				if i >= len(rest) {
+					return nil, fmt.Errorf("unterminated quoted identifier in table name: %q", name)
+				}
+				if rest[i] != closing {
+					sb.WriteByte(rest[i])
+					continue
+				}
+				if i+1 < len(rest) && rest[i+1] == closing {
+					sb.WriteByte(closing)
+					i++
+					continue
+				}
+				break
+			}
+
+			part = sb.String()
+			rest = strings.TrimSpace(rest[i+1:])
+			if rest != "" && rest[0] != '.' {
+				return nil, fmt.Errorf("unexpected text after quoted identifier in table name: %q", name)
+			}
+		} else {
+			end := strings.IndexByte(rest, '.')
+			if end < 0 {
+				end = len(rest)
+			}
+			part = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+
+		if part == "" {
+			return nil, fmt.Errorf("empty identifier in table name: %q", name)
+		}
+		parts = append(parts, part)
+
+		if rest == "" {
+			return parts, nil
+		}
+		// skip the dot
+		rest = strings.TrimSpace(rest[1:])
+	}
+}
+
+// literal returns the value as a literal. Values which aren't numbers, booleans, bytes or times
+// are text literals, with maps, slices and JSON values encoded as JSON.
+func (d sqlDialect) literal(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		return d.boolLiteral(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case float32:
+		return d.floatLiteral(float64(v), 32)
+	case float64:
+		return d.floatLiteral(v, 64)
+	case string:
+		return d.stringLiteral(v)
+	case []byte:
+		return d.bytesLiteral(v)
+	case time.Time:
+		return d.timeLiteral(v)
+	case json.Marshaler:
+		b, err := v.MarshalJSON()
+		if err != nil {
+			return d.stringLiteral(fmt.Sprint(v))
+		}
+		return d.stringLiteral(string(b))
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		b, err := json.Marshal(value)
+		if err == nil {
+			return d.stringLiteral(string(b))
+		}
+	}
+	return d.stringLiteral(fmt.Sprint(value))
+}
+
+func (d sqlDialect) boolLiteral(b bool) string {
+	switch d {
+	case dialectSQLServer, dialectOracle, dialectSQLite:
+		if b {
+			return "1"
+		}
+		return "0"
+	default:
+		if b {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+}
+
+// floatLiteral returns the number, or a text literal for values which don't have a numeric literal.
+func (d sqlDialect) floatLiteral(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return d.stringLiteral("NaN")
+	case math.IsInf(f, 1):
+		return d.stringLiteral("Infinity")
+	case math.IsInf(f, -1):
+		return d.stringLiteral("-Infinity")
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+func (d sqlDialect) stringLiteral(s string) string {
+	switch d {
+	case dialectMySQL:
+		// backslash is an escape character in the default sql mode
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(s) + "'"
+	case dialectBigQuery:
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`, "\n", `\n`, "\r", `\r`).Replace(s) + "'"
+	case dialectSQLServer:
+		return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+	default:
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+}
+
+func (d sqlDialect) bytesLiteral(b []byte) string {
+	h := hex.EncodeToString(b)
+
+	switch d {
+	case dialectPostgres:
+		return "decode('" + h + "', 'hex')"
+	case dialectSQLServer:
+		return "0x" + strings.ToUpper(h)
+	case dialectOracle:
+		return "HEXTORAW('" + strings.ToUpper(h) + "')"
+	case dialectSnowflake:
+		return "TO_BINARY('" + strings.ToUpper(h) + "', 'HEX')"
+	case dialectBigQuery:
+		return "FROM_HEX('" + h + "')"
+	default:
+		return "X'" + strings.ToUpper(h) + "'"
+	}
+}
+
+// timeLiteral returns the time as a timestamp literal. Times in UTC are written without the offset,
+// because databases usually return values of columns without time zone in UTC. MySQL times are always
+// written in UTC without the offset, as DATETIME literals with an offset need MySQL 8.0.19 (and aren't
+// supported by MariaDB).
+func (d sqlDialect) timeLiteral(t time.Time) string {
+	const layout = "2006-01-02 15:04:05.999999999"
+
+	utc := t.Location() == time.UTC
+	text := t.Format(layout)
+
+	switch d {
+	case dialectPostgres:
+		if utc {
+			return "TIMESTAMP '" + text + "'"
+		}
+		return "TIMESTAMPTZ '" + t.Format(layout+"-07:00") + "'"
+	case dialectOracle:
+		if utc {
+			return "TIMESTAMP '" + text + "'"
+		}
+		return "TIMESTAMP '" + t.Format(layout+" -07:00") + "'"
+	case dialectSnowflake:
+		if utc {
+			return "'" + text + "'::TIMESTAMP_NTZ"
+		}
+		return "'" + t.Format(layout+" -07:00") + "'::TIMESTAMP_TZ"
+	case dialectBigQuery:
+		return "TIMESTAMP '" + t.Format(layout+"-07:00") + "'"
+	case dialectMySQL:
+		return d.stringLiteral(t.UTC().Format(layout))
+	case dialectSQLite, dialectSQLServer:
+		if utc {
+			return d.stringLiteral(text)
+		}
+		return d.stringLiteral(t.Format(layout + "-07:00"))
+	default:
+		if utc {
+			return "TIMESTAMP '" + text + "'"
+		}
+		return "TIMESTAMP '" + t.Format(layout+"-07:00") + "'"
+	}
+}
@@ -0,0 +1,205 @@
+package format_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/format"
+)
+
+func TestSQL(t *testing.T) {
+	header := core.Header{"id", "name", "data"}
+	rows := []core.Row{
+		{1, "it's", nil},
+		{2, `a\b`, []byte{0xde, 0xad}},
+	}
+
+	type testCase struct {
+		name     string
+		opts     *format.SQLOptions
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name: "postgres insert",
+			opts: &format.SQLOptions{Table: "public.items", Dialect: "pg"},
+			expected: `INSERT INTO "public"."items" ("id", "name", "data") VALUES (1, 'it''s', NULL);
+INSERT INTO "public"."items" ("id", "name", "data") VALUES (2, 'a\b', decode('dead', 'hex'));
+`,
+		},
+		{
+			name: "mysql multi-row insert",
+			opts: &format.SQLOptions{Table: "items", Dialect: "mysql", Statement: format.SQLInsertMulti},
+			expected: "INSERT INTO `items` (`id`, `name`, `data`) VALUES\n" +
+				"  (1, 'it''s', NULL),\n" +
+				"  (2, 'a\\\\b', X'DEAD');\n",
+		},
+		{
+			name: "oracle multi-row insert in batches",
+			opts: &format.SQLOptions{Table: "items", Dialect: "oracle", Statement: format.SQLInsertMulti, BatchSize: 1},
+			expected: `INSERT ALL
+  INTO "items" ("id", "name", "data") VALUES (1, 'it''s', NULL)
+SELECT 1 FROM DUAL;
+INSERT ALL
+  INTO "items" ("id", "name", "data") VALUES (2, 'a\b', HEXTORAW('DEAD'))
+SELECT 1 FROM DUAL;
+`,
+		},
+		{
+			name: "sqlserver update",
+			opts: &format.SQLOptions{Table: "dbo.items", Dialect: "mssql", Statement: format.SQLUpdate, KeyColumns: []string{"id"}},
+			expected: `UPDATE [dbo].[items] SET [name] = N'it''s', [data] = NULL WHERE [id] = 1;
+UPDATE [dbo].[items] SET [name] = N'a\b', [data] = 0xDEAD WHERE [id] = 2;
+`,
+		},
+		{
+			name: "sqlite upsert",
+			opts: &format.SQLOptions{Table: "items", Dialect: "sqlite3", Statement: format.SQLUpsert, KeyColumns: []string{"id"}},
+			expected: `INSERT INTO "items" ("id", "name", "data") VALUES (1, 'it''s', NULL) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "data" = EXCLUDED."data";
+INSERT INTO "items" ("id", "name", "data") VALUES (2, 'a\b', X'DEAD') ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "data" = EXCLUDED."data";
+`,
+		},
+		{
+			name: "mysql upsert",
+			opts: &format.SQLOptions{Table: "items", Dialect: "mysql", Statement: format.SQLUpsert, KeyColumns: []string{"id", "name", "data"}},
+			expected: "INSERT INTO `items` (`id`, `name`, `data`) VALUES (1, 'it''s', NULL) ON DUPLICATE KEY UPDATE `id` = VALUES(`id`);\n" +
+				"INSERT INTO `items` (`id`, `name`, `data`) VALUES (2, 'a\\\\b', X'DEAD') ON DUPLICATE KEY UPDATE `id` = VALUES(`id`);\n",
+		},
+		{
+			name: "bigquery merge",
+			opts: &format.SQLOptions{Table: "project.dataset.items", Dialect: "bigquery", Statement: format.SQLUpsert, KeyColumns: []string{"id"}},
+			expected: "MERGE INTO `project`.`dataset`.`items` AS t USING (SELECT 1 AS `id`, 'it\\'s' AS `name`, NULL AS `data`) AS s ON (t.`id` = s.`id`) " +
+				"WHEN MATCHED THEN UPDATE SET `name` = s.`name`, `data` = s.`data` WHEN NOT MATCHED THEN INSERT (`id`, `name`, `data`) VALUES (s.`id`, s.`name`, s.`data`);\n" +
+				"MERGE INTO `project`.`dataset`.`items` AS t USING (SELECT 2 AS `id`, 'a\\\\b' AS `name`, FROM_HEX('dead') AS `data`) AS s ON (t.`id` = s.`id`) " +
+				"WHEN MATCHED THEN UPDATE SET `name` = s.`name`, `data` = s.`data` WHEN NOT MATCHED THEN INSERT (`id`, `name`, `data`) VALUES (s.`id`, s.`name`, s.`data`);\n",
+		},
+		{
+			name: "oracle merge",
+			opts: &format.SQLOptions{Table: "items", Dialect: "oracle", Statement: format.SQLUpsert, KeyColumns: []string{"id", "name"}},
+			expected: `MERGE INTO "items" t USING (SELECT 1 "id", 'it''s' "name", NULL "data" FROM DUAL) s ON (t."id" = s."id" AND t."name" = s."name") WHEN MATCHED THEN UPDATE SET "data" = s."data" WHEN NOT MATCHED THEN INSERT ("id", "name", "data") VALUES (s."id", s."name", s."data");
+MERGE INTO "items" t USING (SELECT 2 "id", 'a\b' "name", HEXTORAW('DEAD') "data" FROM DUAL) s ON (t."id" = s."id" AND t."name" = s."name") WHEN MATCHED THEN UPDATE SET "data" = s."data" WHEN NOT MATCHED THEN INSERT ("id", "name", "data") VALUES (s."id", s."name", s."data");
+`,
+		},
+		{
+			name: "sqlserver merge",
+			opts: &format.SQLOptions{Table: "items", Dialect: "sqlserver", Statement: format.SQLUpsert, KeyColumns: []string{"id"}},
+			expected: `MERGE INTO [items] AS t USING (VALUES (1, N'it''s', NULL)) AS s ([id], [name], [data]) ON (t.[id] = s.[id]) WHEN MATCHED THEN UPDATE SET [name] = s.[name], [data] = s.[data] WHEN NOT MATCHED THEN INSERT ([id], [name], [data]) VALUES (s.[id], s.[name], s.[data]);
+MERGE INTO [items] AS t USING (VALUES (2, N'a\b', 0xDEAD)) AS s ([id], [name], [data]) ON (t.[id] = s.[id]) WHEN MATCHED THEN UPDATE SET [name] = s.[name], [data] = s.[data] WHEN NOT MATCHED THEN INSERT ([id], [name], [data]) VALUES (s.[id], s.[name], s.[data]);
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			formatter, err := format.NewSQL(tc.opts)
+			r.NoError(err)
+
+			out, err := formatter.Format(header, rows, &core.FormatterOptions{})
+			r.NoError(err)
+			r.Equal(tc.expected, string(out))
+
+			// streamed output is the same
+			var buf bytes.Buffer
+			r.NoError(formatter.WriteHeader(&buf, header, &core.FormatterOptions{}))
+			for _, row := range rows {
+				r.NoError(formatter.WriteRows(&buf, []core.Row{row}, &core.FormatterOptions{}))
+			}
+			r.NoError(formatter.WriteFooter(&buf, &core.FormatterOptions{}))
+			if tc.opts.Statement != format.SQLInsertMulti || tc.opts.BatchSize == 1 {
+				r.Equal(tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestSQL_Literals(t *testing.T) {
+	r := require.New(t)
+
+	utc := time.Date(2024, 5, 6, 7, 8, 9, 120000000, time.UTC)
+	zoned := time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", 2*60*60))
+
+	header := core.Header{"b", "f", "nan", "utc", "zoned", "json", "map"}
+	row := core.Row{true, 1.5, math.NaN(), utc, zoned, json.RawMessage(`{"a":1}`), map[string]any{"k": []int{1}}}
+
+	expected := map[string]string{
+		"postgres":   `(TRUE, 1.5, 'NaN', TIMESTAMP '2024-05-06 07:08:09.12', TIMESTAMPTZ '2024-05-06 07:08:09+02:00', '{"a":1}', '{"k":[1]}')`,
+		"oracle":     `(1, 1.5, 'NaN', TIMESTAMP '2024-05-06 07:08:09.12', TIMESTAMP '2024-05-06 07:08:09 +02:00', '{"a":1}', '{"k":[1]}')`,
+		"snowflake":  `(TRUE, 1.5, 'NaN', '2024-05-06 07:08:09.12'::TIMESTAMP_NTZ, '2024-05-06 07:08:09 +02:00'::TIMESTAMP_TZ, '{"a":1}', '{"k":[1]}')`,
+		"bigquery":   `(TRUE, 1.5, 'NaN', TIMESTAMP '2024-05-06 07:08:09.12+00:00', TIMESTAMP '2024-05-06 07:08:09+02:00', '{"a":1}', '{"k":[1]}')`,
+		"sqlserver":  `(1, 1.5, N'NaN', N'2024-05-06 07:08:09.12', N'2024-05-06 07:08:09+02:00', N'{"a":1}', N'{"k":[1]}')`,
+		"mysql":      `(TRUE, 1.5, 'NaN', '2024-05-06 07:08:09.12', '2024-05-06 05:08:09', '{"a":1}', '{"k":[1]}')`,
+		"clickhouse": `(TRUE, 1.5, 'NaN', TIMESTAMP '2024-05-06 07:08:09.12', TIMESTAMP '2024-05-06 07:08:09+02:00', '{"a":1}', '{"k":[1]}')`,
+	}
+
+	for dialect, values := range expected {
+		formatter, err := format.NewSQL(&format.SQLOptions{Table: "t", Dialect: dialect})
+		r.NoError(err)
+
+		out, err := formatter.Format(header, []core.Row{row}, &core.FormatterOptions{})
+		r.NoError(err)
+		r.Contains(string(out), " VALUES "+values+";", dialect)
+	}
+}
+
+func TestSQL_TableNames(t *testing.T) {
+	testCases := []struct {
+		table    string
+		dialect  string
+		expected string
+	}{
+		{table: "items", dialect: "postgres", expected: `"items"`},
+		{table: " public . items ", dialect: "postgres", expected: `"public"."items"`},
+		{table: `"my.schema".items`, dialect: "postgres", expected: `"my.schema"."items"`},
+		{table: `"a""b"."c"`, dialect: "postgres", expected: `"a""b"."c"`},
+		{table: "`db`.`my``table`", dialect: "mysql", expected: "`db`.`my``table`"},
+		{table: `"db".items`, dialect: "mysql", expected: "`db`.`items`"},
+		{table: "[dbo].[a]]b.c]", dialect: "sqlserver", expected: "[dbo].[a]]b.c]"},
+		{table: "project.`data.set`.items", dialect: "bigquery", expected: "`project`.`data.set`.`items`"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.table, func(t *testing.T) {
+			r := require.New(t)
+
+			formatter, err := format.NewSQL(&format.SQLOptions{Table: tc.table, Dialect: tc.dialect})
+			r.NoError(err)
+
+			out, err := formatter.Format(core.Header{"id"}, []core.Row{{1}}, &core.FormatterOptions{})
+			r.NoError(err)
+			r.True(strings.HasPrefix(string(out), "INSERT INTO "+tc.expected+" ("), string(out))
+		})
+	}
+
+	for _, table := range []string{`"unterminated`, "a..b", "a.", ".a", `"a"b`, `""`} {
+		_, err := format.NewSQL(&format.SQLOptions{Table: table})
+		require.Error(t, err, table)
+	}
+}
+
+func TestNewSQL_Errors(t *testing.T) {
+	r := require.New(t)
+
+	_, err := format.NewSQL(&format.SQLOptions{})
+	r.Error(err)
+	_, err = format.NewSQL(&format.SQLOptions{Table: "t", Statement: format.SQLUpdate})
+	r.Error(err)
+	_, err = format.NewSQL(&format.SQLOptions{Table: "t", Statement: "delete"})
+	r.Error(err)
+
+	formatter, err := format.NewSQL(&format.SQLOptions{Table: "t", Statement: format.SQLUpdate, KeyColumns: []string{"id"}})
+	r.NoError(err)
+	_, err = formatter.Format(core.Header{"name"}, nil, &core.FormatterOptions{})
+	r.Error(err)
+	_, err = formatter.Format(core.Header{"id"}, nil, &core.FormatterOptions{})
+	r.Error(err)
+}
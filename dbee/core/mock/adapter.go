@@ -2,19 +2,42 @@ package mock
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 )
 
-var _ core.Driver = (*driver)(nil)
+var (
+	_ core.Driver           = (*driver)(nil)
+	_ core.DatabaseSwitcher = (*driver)(nil)
+	_ core.CurrentDatabaser = (*driver)(nil)
+	_ core.Transactor       = (*driver)(nil)
+	_ core.Canceler         = (*driver)(nil)
+	_ core.Estimator        = (*driver)(nil)
+	_ core.ErrorParser      = (*driver)(nil)
+	_ core.Explainer        = (*driver)(nil)
+)
 
 type driver struct {
-	data   []core.Row
-	config *adapterConfig
+	data          []core.Row
+	config        *adapterConfig
+	inTransaction bool
+
+	database      string
+	databaseMutex sync.Mutex
 }
 
 func (d *driver) Query(ctx context.Context, query string) (core.ResultStream, error) {
+	core.ReportQueryID(ctx, query)
+
+	// queries like "use name" switch the database, same as USE statements
+	if name, ok := strings.CutPrefix(query, "use "); ok {
+		_ = d.SelectDatabase(name)
+	}
+
 	eff, ok := d.config.querySideEffects[query]
 	if ok {
 		err := eff(ctx)
@@ -23,7 +46,9 @@ func (d *driver) Query(ctx context.Context, query string) (core.ResultStream, er
 		}
 	}
 
-	return NewResultStream(d.data, d.config.resultStreamOptions...), nil
+	stream := NewResultStream(d.data, d.config.resultStreamOptions...)
+	stream.ctx = ctx
+	return stream, nil
 }
 
 func (d *driver) Structure() ([]*core.Structure, error) {
@@ -48,6 +73,82 @@ func (d *driver) Columns(opts *core.TableOptions) ([]*core.Column, error) {
 	return columns, nil
 }
 
+func (d *driver) SelectDatabase(name string) error {
+	d.databaseMutex.Lock()
+	defer d.databaseMutex.Unlock()
+
+	d.database = name
+	return nil
+}
+
+func (d *driver) ListDatabases() (current string, available []string, err error) {
+	d.databaseMutex.Lock()
+	defer d.databaseMutex.Unlock()
+
+	return d.database, d.config.databases, nil
+}
+
+func (d *driver) CurrentDatabase(context.Context) (string, error) {
+	d.databaseMutex.Lock()
+	defer d.databaseMutex.Unlock()
+
+	return d.database, nil
+}
+
+func (d *driver) BeginTransaction(_ context.Context) error {
+	if d.inTransaction {
+		return errors.New("transaction already in progress")
+	}
+	d.inTransaction = true
+	return nil
+}
+
+func (d *driver) CommitTransaction() error {
+	if !d.inTransaction {
+		return errors.New("no transaction in progress")
+	}
+	d.inTransaction = false
+	return nil
+}
+
+func (d *driver) RollbackTransaction() error {
+	return d.CommitTransaction()
+}
+
+func (d *driver) InTransaction() bool {
+	return d.inTransaction
+}
+
+func (d *driver) CancelQuery(_ context.Context, queryID string) error {
+	if d.config.cancelQuery != nil {
+		d.config.cancelQuery(queryID)
+	}
+	return nil
+}
+
+func (d *driver) Estimate(_ context.Context, query string, _ *core.QueryParams) (*core.Estimate, error) {
+	if err, ok := d.config.estimateErrors[query]; ok {
+		return nil, err
+	}
+	return d.config.estimates[query], nil
+}
+
+// Explain returns a plan with a single node, whose operation is the query.
+func (d *driver) Explain(_ context.Context, query string, opts *core.ExplainOptions) (*core.PlanNode, error) {
+	detail := ""
+	if opts.Analyze {
+		detail = "analyzed"
+	}
+	return &core.PlanNode{Operation: query, Detail: detail}, nil
+}
+
+func (d *driver) ParseError(err error) *core.QueryError {
+	if d.config.errorParser != nil {
+		return d.config.errorParser(err)
+	}
+	return nil
+}
+
 func (d *driver) Close() {}
 
 var _ core.Adapter = (*Adapter)(nil)
@@ -62,6 +163,8 @@ func NewAdapter(data []core.Row, opts ...AdapterOption) *Adapter {
 		querySideEffects: make(map[string]func(context.Context) error),
 		tableHelpers:     make(map[string]string),
 		tableColumns:     make(map[string][]*core.Column),
+		estimates:        make(map[string]*core.Estimate),
+		estimateErrors:   make(map[string]error),
 
 		resultStreamOptions: []ResultStreamOption{},
 	}
@@ -76,9 +179,15 @@ func NewAdapter(data []core.Row, opts ...AdapterOption) *Adapter {
 }
 
 func (a *Adapter) Connect(_ string) (core.Driver, error) {
+	var database string
+	if len(a.config.databases) > 0 {
+		database = a.config.databases[0]
+	}
+
 	return &driver{
-		data:   a.data,
-		config: a.config,
+		data:     a.data,
+		config:   a.config,
+		database: database,
 	}, nil
 }
 
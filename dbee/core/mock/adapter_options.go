@@ -10,8 +10,14 @@ type adapterConfig struct {
 	querySideEffects map[string]func(context.Context) error
 	tableHelpers     map[string]string
 	tableColumns     map[string][]*core.Column
+	estimates        map[string]*core.Estimate
+	estimateErrors   map[string]error
+	databases        []string
 
 	resultStreamOptions []ResultStreamOption
+
+	cancelQuery func(queryID string)
+	errorParser func(err error) *core.QueryError
 }
 
 type AdapterOption func(*adapterConfig)
@@ -54,3 +60,44 @@ func AdapterWithResultStreamOpts(opts ...ResultStreamOption) AdapterOption {
 		c.resultStreamOptions = append(c.resultStreamOptions, opts...)
 	}
 }
+
+// AdapterWithEstimate registers an estimate of the query. Queries without estimates aren't estimated.
+func AdapterWithEstimate(query string, estimate *core.Estimate) AdapterOption {
+	return func(c *adapterConfig) {
+		_, ok := c.estimates[query]
+		if ok {
+			panic("estimate already registered for query: " + query)
+		}
+
+		c.estimates[query] = estimate
+	}
+}
+
+// AdapterWithEstimateError makes estimating the query fail with err.
+func AdapterWithEstimateError(query string, err error) AdapterOption {
+	return func(c *adapterConfig) {
+		c.estimateErrors[query] = err
+	}
+}
+
+// AdapterWithDatabases sets databases listed by the driver. The first one is selected after connecting.
+func AdapterWithDatabases(databases ...string) AdapterOption {
+	return func(c *adapterConfig) {
+		c.databases = databases
+	}
+}
+
+// AdapterWithErrorParser registers a function which converts errors of queries to query errors.
+func AdapterWithErrorParser(parser func(err error) *core.QueryError) AdapterOption {
+	return func(c *adapterConfig) {
+		c.errorParser = parser
+	}
+}
+
+// AdapterWithCancelQuery registers a callback which is called when a query is canceled on the "server".
+// Queries are identified by their text.
+func AdapterWithCancelQuery(cancelQuery func(queryID string)) AdapterOption {
+	return func(c *adapterConfig) {
+		c.cancelQuery = cancelQuery
+	}
+}
@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -29,10 +30,17 @@ func newNext(rows []core.Row) (func() (core.Row, error), func() bool) {
 	return next, hasNext
 }
 
+var _ core.MultiResultStream = (*ResultStream)(nil)
+
 type ResultStream struct {
 	next    func() (core.Row, error)
 	hasNext func() bool
+	header  core.Header
+	sets    [][]core.Row
 	config  *resultStreamConfig
+
+	// ctx of the query which returned the stream
+	ctx context.Context
 }
 
 func makeDefaultHeader(rows []core.Row) core.Header {
@@ -63,7 +71,10 @@ func NewResultStream(rows []core.Row, opts ...ResultStreamOption) *ResultStream
 	return &ResultStream{
 		next:    next,
 		hasNext: hasNext,
+		header:  config.header,
+		sets:    config.sets,
 		config:  config,
+		ctx:     context.Background(),
 	}
 }
 
@@ -72,19 +83,54 @@ func (rs *ResultStream) Meta() *core.Meta {
 }
 
 func (rs *ResultStream) Header() core.Header {
-	return rs.config.header
+	return rs.header
 }
 
 func (rs *ResultStream) Next() (core.Row, error) {
 	time.Sleep(rs.config.nextSleep)
-	return rs.next()
+	row, err := rs.next()
+	if err == nil && rs.config.readRows != nil {
+		rs.config.readRows.Add(1)
+	}
+	return row, err
 }
 
 func (rs *ResultStream) HasNext() bool {
 	return rs.hasNext()
 }
 
-func (rs *ResultStream) Close() {}
+func (rs *ResultStream) NextResultSet() bool {
+	if len(rs.sets) < 1 {
+		return false
+	}
+
+	rows := rs.sets[0]
+	rs.sets = rs.sets[1:]
+
+	rs.next, rs.hasNext = newNext(rows)
+	rs.header = makeDefaultHeader(rows)
+	return true
+}
+
+// Close reads the remaining rows of all result sets if the stream drains on close,
+// until the context of the query is canceled.
+func (rs *ResultStream) Close() {
+	if !rs.config.drainOnClose {
+		return
+	}
+
+	for {
+		for rs.HasNext() {
+			if rs.ctx.Err() != nil {
+				return
+			}
+			_, _ = rs.Next()
+		}
+		if !rs.NextResultSet() {
+			return
+		}
+	}
+}
 
 // NewRows returns a slice of rows in form of:
 //
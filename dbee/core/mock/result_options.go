@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
@@ -10,6 +11,10 @@ type resultStreamConfig struct {
 	nextSleep time.Duration
 	meta      *core.Meta
 	header    core.Header
+	sets      [][]core.Row
+
+	drainOnClose bool
+	readRows     *atomic.Int64
 }
 
 type ResultStreamOption func(*resultStreamConfig)
@@ -31,3 +36,26 @@ func ResultStreamWithHeader(header core.Header) ResultStreamOption {
 		c.header = header
 	}
 }
+
+// ResultStreamWithResultSets adds result sets which follow the initial rows of the stream.
+// Each set gets a default header matching its first row.
+func ResultStreamWithResultSets(sets ...[]core.Row) ResultStreamOption {
+	return func(c *resultStreamConfig) {
+		c.sets = append(c.sets, sets...)
+	}
+}
+
+// ResultStreamWithDrainOnClose makes the stream read its remaining rows when it's closed,
+// until the context of the query is canceled (as some database clients do).
+func ResultStreamWithDrainOnClose() ResultStreamOption {
+	return func(c *resultStreamConfig) {
+		c.drainOnClose = true
+	}
+}
+
+// ResultStreamWithReadCounter counts all rows read from the stream in counter.
+func ResultStreamWithReadCounter(counter *atomic.Int64) ResultStreamOption {
+	return func(c *resultStreamConfig) {
+		c.readRows = counter
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"errors"
+	"strings"
+)
+
+// QueryError is an error reported by the database for a query (as opposed to e.g. a connection error).
+// Drivers create it from native errors of database clients. Values which are unknown are zero.
+type QueryError struct {
+	// Severity of the error (e.g. "ERROR", "FATAL" or a numeric class).
+	Severity string
+	// SQLState is the standard SQLSTATE code of the error.
+	SQLState string
+	// Code is the vendor specific error code (e.g. error number).
+	Code    string
+	Message string
+	Detail  string
+	Hint    string
+	// Position is the 1-based character position of the error in the query.
+	Position int
+	// Line and Column are the 1-based location of the error in the query,
+	// for databases which don't report the position.
+	Line   int
+	Column int
+
+	// err is the original error of the driver
+	err error
+}
+
+// NewQueryError returns a query error created from the original err of a driver.
+// Fields are populated by the caller.
+func NewQueryError(err error) *QueryError {
+	return &QueryError{
+		err: err,
+	}
+}
+
+func (e *QueryError) Error() string {
+	var codes []string
+	if e.Code != "" {
+		codes = append(codes, "code: "+e.Code)
+	}
+	if e.SQLState != "" {
+		codes = append(codes, "sqlstate: "+e.SQLState)
+	}
+
+	var b strings.Builder
+	b.WriteString(e.Message)
+	if len(codes) > 0 {
+		b.WriteString(" (" + strings.Join(codes, ", ") + ")")
+	}
+	if e.Detail != "" {
+		b.WriteString("\nDetail: ")
+		b.WriteString(e.Detail)
+	}
+	if e.Hint != "" {
+		b.WriteString("\nHint: ")
+		b.WriteString(e.Hint)
+	}
+	return b.String()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.err
+}
+
+// AsQueryError returns the query error in err's chain, or nil if there is none.
+func AsQueryError(err error) *QueryError {
+	var qe *QueryError
+	if errors.As(err, &qe) {
+		return qe
+	}
+	return nil
+}
+
+// toQueryError converts err with parser if the error isn't a query error already.
+// Errors which the parser doesn't recognize are returned as is.
+func toQueryError(err error, parser ErrorParser) error {
+	if parser == nil || AsQueryError(err) != nil {
+		return err
+	}
+	if qe := parser.ParseError(err); qe != nil {
+		return qe
+	}
+	return err
+}
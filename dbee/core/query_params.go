@@ -0,0 +1,254 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// QueryParams are bind variables passed to the driver along with the query.
+// Positional values are bound in order ($1, ?, @p1, :1 ...),
+// named values by their name (:name, @name ...).
+type QueryParams struct {
+	Positional []any
+	Named      map[string]any
+}
+
+// NewQueryParams creates query params from a list of positional values
+// or a map of named values (e.g. decoded from lua tables).
+// Nil value results in nil params.
+func NewQueryParams(values any) (*QueryParams, error) {
+	switch v := values.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		if len(v) < 1 {
+			return nil, nil
+		}
+		return &QueryParams{Positional: v}, nil
+	case map[string]any:
+		if len(v) < 1 {
+			return nil, nil
+		}
+		return &QueryParams{Named: v}, nil
+	case *QueryParams:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported query params type: %T", values)
+	}
+}
+
+// persistQueryParams controls if params of calls are written to the call log.
+var persistQueryParams atomic.Bool
+
+func init() {
+	persistQueryParams.Store(true)
+}
+
+// SetPersistQueryParams sets if params of calls are written to the call log along with the query.
+// Params are stored in plain text, so calls with sensitive params (e.g. passwords) can't be
+// rerun after a restart if they aren't persisted.
+func SetPersistQueryParams(persist bool) {
+	persistQueryParams.Store(persist)
+}
+
+// queryParamsPersistent is used for marshaling and unmarshaling query params of a call
+type queryParamsPersistent struct {
+	Positional []queryParamPersistent          `json:"positional,omitempty"`
+	Named      map[string]queryParamPersistent `json:"named,omitempty"`
+}
+
+// types of persisted param values
+const (
+	paramTypeNull   = "null"
+	paramTypeBool   = "bool"
+	paramTypeInt    = "int"
+	paramTypeUint   = "uint"
+	paramTypeFloat  = "float"
+	paramTypeString = "string"
+	paramTypeBytes  = "bytes"
+	paramTypeTime   = "time"
+	// paramTypeJSON is any other value, stored as is
+	paramTypeJSON = "json"
+)
+
+// queryParamPersistent is a single param value with its type, so that it's restored
+// with the same Go type (e.g. integers aren't decoded as floats).
+type queryParamPersistent struct {
+	value any
+}
+
+func (p queryParamPersistent) MarshalJSON() ([]byte, error) {
+	var typ string
+	var value any
+	switch v := p.value.(type) {
+	case nil:
+		typ = paramTypeNull
+	case bool:
+		typ, value = paramTypeBool, v
+	case int, int8, int16, int32, int64:
+		typ, value = paramTypeInt, reflect.ValueOf(v).Int()
+	case uint, uint8, uint16, uint32, uint64:
+		typ, value = paramTypeUint, reflect.ValueOf(v).Uint()
+	case float32, float64:
+		// stored as text, since JSON numbers can't hold NaN and infinity
+		typ, value = paramTypeFloat, strconv.FormatFloat(reflect.ValueOf(v).Float(), 'g', -1, 64)
+	case string:
+		typ, value = paramTypeString, v
+	case []byte:
+		typ, value = paramTypeBytes, v
+	case time.Time:
+		typ, value = paramTypeTime, v
+	default:
+		typ, value = paramTypeJSON, v
+	}
+
+	return json.Marshal(struct {
+		Type  string `json:"type"`
+		Value any    `json:"value,omitempty"`
+	}{Type: typ, Value: value})
+}
+
+func (p *queryParamPersistent) UnmarshalJSON(data []byte) error {
+	var typed struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	err := json.Unmarshal(data, &typed)
+	if err != nil || typed.Type == "" {
+		// call logs written before types were stored hold plain values
+		p.value, err = decodeParamJSON(data)
+		return err
+	}
+
+	var value any
+	switch typed.Type {
+	case paramTypeNull:
+	case paramTypeBool:
+		var v bool
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case paramTypeInt:
+		var v int64
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case paramTypeUint:
+		var v uint64
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case paramTypeFloat:
+		var v string
+		err = json.Unmarshal(typed.Value, &v)
+		if err == nil {
+			value, err = strconv.ParseFloat(v, 64)
+		}
+	case paramTypeString:
+		var v string
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case paramTypeBytes:
+		var v []byte
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case paramTypeTime:
+		var v time.Time
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case paramTypeJSON:
+		value, err = decodeParamJSON(typed.Value)
+	default:
+		// same as above, a plain value which happens to look like a typed one
+		value, err = decodeParamJSON(data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed decoding %s param: %w", typed.Type, err)
+	}
+
+	p.value = value
+	return nil
+}
+
+// decodeParamJSON decodes a param value without a stored type. Numbers are decoded
+// as integers if they are whole, so that large integers don't lose precision.
+func decodeParamJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	return restoreNumbers(value), nil
+}
+
+// restoreNumbers converts json numbers in the value to int64 or float64.
+func restoreNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = restoreNumbers(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = restoreNumbers(v[k])
+		}
+	}
+	return value
+}
+
+func (p *QueryParams) toPersistent() *queryParamsPersistent {
+	if p.IsEmpty() || !persistQueryParams.Load() {
+		return nil
+	}
+
+	persistent := &queryParamsPersistent{}
+	for _, value := range p.Positional {
+		persistent.Positional = append(persistent.Positional, queryParamPersistent{value: value})
+	}
+	if len(p.Named) > 0 {
+		persistent.Named = make(map[string]queryParamPersistent, len(p.Named))
+		for name, value := range p.Named {
+			persistent.Named[name] = queryParamPersistent{value: value}
+		}
+	}
+	return persistent
+}
+
+// restore converts persisted params back to query params.
+func (p *queryParamsPersistent) restore() *QueryParams {
+	if p == nil {
+		return nil
+	}
+
+	params := &QueryParams{}
+	for _, value := range p.Positional {
+		params.Positional = append(params.Positional, value.value)
+	}
+	if len(p.Named) > 0 {
+		params.Named = make(map[string]any, len(p.Named))
+		for name, value := range p.Named {
+			params.Named[name] = value.value
+		}
+	}
+
+	if params.IsEmpty() {
+		return nil
+	}
+	return params
+}
+
+// IsEmpty reports if there are no params to bind.
+func (p *QueryParams) IsEmpty() bool {
+	return p == nil || (len(p.Positional) < 1 && len(p.Named) < 1)
+}
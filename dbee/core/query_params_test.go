@@ -0,0 +1,108 @@
+package core_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewQueryParams(t *testing.T) {
+	r := require.New(t)
+
+	testCases := []struct {
+		input    any
+		expected *core.QueryParams
+	}{
+		{nil, nil},
+		{[]any{}, nil},
+		{map[string]any{}, nil},
+		{[]any{1, "two"}, &core.QueryParams{Positional: []any{1, "two"}}},
+		{map[string]any{"id": 1}, &core.QueryParams{Named: map[string]any{"id": 1}}},
+	}
+
+	for _, tc := range testCases {
+		actual, err := core.NewQueryParams(tc.input)
+		r.NoError(err)
+		r.Equal(tc.expected, actual)
+	}
+
+	_, err := core.NewQueryParams("not params")
+	r.Error(err)
+}
+
+func TestQueryParams_Persistence(t *testing.T) {
+	r := require.New(t)
+
+	connection, err := core.NewConnection(&core.ConnectionParams{}, mock.NewAdapter(mock.NewRows(0, 10)))
+	r.NoError(err)
+	r.NoError(connection.Connect())
+
+	// restore returns params of the call after it's written to the call log and read back
+	restore := func(params *core.QueryParams) *core.QueryParams {
+		call := connection.ExecuteWithParams("_", params, nil)
+		<-call.Done()
+
+		b, err := json.Marshal(call)
+		r.NoError(err)
+		restoredCall := new(core.Call)
+		r.NoError(json.Unmarshal(b, restoredCall))
+		return restoredCall.GetParams()
+	}
+
+	timestamp := time.Date(2024, 2, 29, 13, 14, 15, 16, time.UTC)
+	params := &core.QueryParams{
+		Positional: []any{
+			nil,
+			true,
+			int64(1<<53 + 1),
+			int64(math.MinInt64),
+			uint64(math.MaxUint64),
+			2.0,
+			0.1,
+			math.Inf(-1),
+			"1",
+			[]byte{0, 1, 2},
+			timestamp,
+			map[string]any{"nested": []any{int64(1<<60 + 1), 0.5, "a"}},
+		},
+		Named: map[string]any{
+			"id":   int64(42),
+			"name": "name",
+		},
+	}
+	r.Equal(params, restore(params))
+
+	// smaller types are widened
+	r.Equal(&core.QueryParams{Positional: []any{int64(1), int64(2), uint64(3), float64(float32(0.5))}},
+		restore(&core.QueryParams{Positional: []any{1, int32(2), uint8(3), float32(0.5)}}))
+
+	// params are not persisted if disabled
+	core.SetPersistQueryParams(false)
+	defer core.SetPersistQueryParams(true)
+	r.Nil(restore(params))
+}
+
+func TestQueryParams_RestoreLegacy(t *testing.T) {
+	r := require.New(t)
+
+	// params were written as plain values before their types were stored
+	call := new(core.Call)
+	r.NoError(json.Unmarshal([]byte(`{
+		"id": "legacy",
+		"state": "archived",
+		"params": {
+			"positional": [9007199254740993, 1.5, "a", null, {"type": "unknown"}],
+			"named": {"x": 2}
+		}
+	}`), call))
+
+	r.Equal(&core.QueryParams{
+		Positional: []any{int64(9007199254740993), 1.5, "a", nil, map[string]any{"type": "unknown"}},
+		Named:      map[string]any{"x": int64(2)},
+	}, call.GetParams())
+}
@@ -1,24 +1,77 @@
 package core
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"io"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var ErrInvalidRange = func(from, to int) error { return fmt.Errorf("invalid selection range: %d ... %d", from, to) }
 
+// DefaultResultWindowSize is the default number of rows a spilled result keeps in memory.
+const DefaultResultWindowSize = 10_000
+
+var resultWindowSize atomic.Int64
+
+func init() {
+	resultWindowSize.Store(DefaultResultWindowSize)
+}
+
+// SetResultWindowSize sets the number of rows that results of new calls keep in memory.
+// Rows outside of this window are read from the call archive.
+// Non-positive size keeps all rows in memory.
+func SetResultWindowSize(size int) {
+	resultWindowSize.Store(int64(size))
+}
+
+// resultSpill is a storage that Result offloads its rows to,
+// so that only a window of rows has to be kept in memory.
+type resultSpill interface {
+	// writeRows persists rows starting at index "from".
+	writeRows(from int, rows []Row) error
+	// readRows reads persisted rows in range [from, to).
+	readRows(from, to int) ([]Row, error)
+}
+
 // Result is the cached form of the ResultStream iterator
 type Result struct {
 	header Header
 	meta   *Meta
+
+	// rows is the in-memory window of rows, starting at index "offset"
 	rows   []Row
+	offset int
+	// length is the number of all rows in the result
+	length int
+
+	spill      resultSpill
+	spilled    int
+	spillErr   error
+	windowSize int
 
 	isDrained  bool
 	isFilled   bool
 	writeMutex sync.Mutex
 	readMutex  sync.RWMutex
+
+	// last computed view of rows (see FormatView), generation is increased whenever rows change
+	lastView       *resultView
+	viewGeneration int
+	viewMutex      sync.Mutex
+}
+
+// newResult returns a result which keeps at most windowSize rows in memory
+// and persists all rows to spill as they are retrieved.
+func newResult(spill resultSpill, windowSize int) *Result {
+	return &Result{
+		spill:      spill,
+		windowSize: windowSize,
+	}
 }
 
 // SetIter sets the ResultStream iterator to result.
@@ -31,14 +84,26 @@ func (cr *Result) SetIter(iter ResultStream, onFillStart func()) error {
 	// close iterator on return
 	defer iter.Close()
 
+	cr.readMutex.Lock()
 	cr.header = iter.Header()
 	cr.meta = iter.Meta()
 	cr.rows = make([]Row, 0)
+	cr.offset = 0
+	cr.length = 0
+	cr.spilled = 0
+	cr.spillErr = nil
 
 	cr.isDrained = false
 	cr.isFilled = true
+	cr.readMutex.Unlock()
 
-	defer func() { cr.isDrained = true }()
+	cr.resetView()
+
+	defer func() {
+		cr.readMutex.Lock()
+		cr.isDrained = true
+		cr.readMutex.Unlock()
+	}()
 
 	// trigger callback
 	if onFillStart != nil {
@@ -49,16 +114,86 @@ func (cr *Result) SetIter(iter ResultStream, onFillStart func()) error {
 	for iter.HasNext() {
 		row, err := iter.Next()
 		if err != nil {
+			cr.readMutex.Lock()
 			cr.isFilled = false
+			cr.readMutex.Unlock()
 			return err
 		}
 
+		cr.readMutex.Lock()
 		cr.rows = append(cr.rows, row)
+		cr.length++
+		cr.readMutex.Unlock()
+
+		if cr.length-cr.spilled >= archiveChunkSize {
+			cr.spillPending()
+		}
+	}
+
+	// persist the remainder
+	if cr.length > cr.spilled {
+		cr.spillPending()
 	}
 
 	return nil
 }
 
+// spillPending writes rows which were not persisted yet to spill
+// and evicts the rows that don't fit into the memory window anymore.
+// It is only called from SetIter, which is the only writer of the result.
+func (cr *Result) spillPending() {
+	if cr.spill == nil || cr.spillErr != nil {
+		return
+	}
+
+	err := cr.spill.writeRows(cr.spilled, cr.rows[cr.spilled-cr.offset:])
+
+	cr.readMutex.Lock()
+	defer cr.readMutex.Unlock()
+
+	if err != nil {
+		// keep the rest of the rows in memory
+		cr.spillErr = err
+		return
+	}
+	cr.spilled = cr.length
+
+	if cr.windowSize > 0 && len(cr.rows) > cr.windowSize {
+		evict := len(cr.rows) - cr.windowSize
+		cr.rows = cr.rows[evict:]
+		cr.offset += evict
+	}
+}
+
+// spillError returns an error that occurred while persisting rows to spill.
+func (cr *Result) spillError() error {
+	cr.readMutex.RLock()
+	defer cr.readMutex.RUnlock()
+
+	return cr.spillErr
+}
+
+// setSpilled fills the result with rows that are already persisted in spill,
+// without loading any of them into memory.
+func (cr *Result) setSpilled(header Header, meta *Meta, length int) {
+	cr.writeMutex.Lock()
+	defer cr.writeMutex.Unlock()
+	cr.readMutex.Lock()
+	defer cr.readMutex.Unlock()
+
+	cr.resetView()
+
+	cr.header = header
+	cr.meta = meta
+	cr.rows = []Row{}
+	cr.offset = length
+	cr.length = length
+	cr.spilled = length
+	cr.spillErr = nil
+	cr.isDrained = true
+	cr.isFilled = true
+}
+
 func (cr *Result) Wipe() {
 	// lock write and read mutexes
 	cr.writeMutex.Lock()
@@ -67,9 +202,14 @@ func (cr *Result) Wipe() {
 	defer cr.readMutex.Unlock()
 
 	// clear everything
+	cr.resetView()
 	cr.header = Header{}
 	cr.meta = &Meta{}
 	cr.rows = []Row{}
+	cr.offset = 0
+	cr.length = 0
+	cr.spilled = 0
+	cr.spillErr = nil
 	cr.isDrained = false
 	cr.isFilled = false
 }
@@ -81,11 +221,11 @@ func (cr *Result) Format(formatter Formatter, from, to int) ([]byte, error) {
 	}
 
 	opts := &FormatterOptions{
-		SchemaType: cr.meta.SchemaType,
+		SchemaType: cr.Meta().SchemaType,
 		ChunkStart: fromAdjusted,
 	}
 
-	f, err := formatter.Format(cr.header, rows, opts)
+	f, err := formatter.Format(cr.Header(), rows, opts)
 	if err != nil {
 		return nil, fmt.Errorf("formatter.Format: %w", err)
 	}
@@ -93,19 +233,125 @@ func (cr *Result) Format(formatter Formatter, from, to int) ([]byte, error) {
 	return f, nil
 }
 
+// formatStreamBatchSize is the number of rows FormatStream reads and writes at once.
+const formatStreamBatchSize = archiveChunkSize
+
+// FormatStream writes rows in range [from, to) of the view to w with the stream formatter.
+// Rows are read in batches from memory or from the archive, so only a single batch of rows
+// and its output are held in memory at once (besides indexes of rows of a non-empty view).
+func (cr *Result) FormatStream(w io.Writer, formatter StreamFormatter, view *ResultViewOptions, from, to int) error {
+	var viewIndexes []int
+	inView := !view.IsEmpty()
+
+	if inView {
+		indexes, err := cr.view(view)
+		if err != nil {
+			return err
+		}
+		if (from < 0 && to >= 0) || ((from < 0) == (to < 0) && from > to) {
+			return ErrInvalidRange(from, to)
+		}
+		from, to = rowRange(from, to, len(indexes))
+		viewIndexes = indexes
+	} else {
+		err := cr.waitRange(from, to)
+		if err != nil {
+			return err
+		}
+		cr.readMutex.RLock()
+		from, to = rowRange(from, to, cr.length)
+		cr.readMutex.RUnlock()
+	}
+
+	header, meta := cr.Header(), cr.Meta()
+
+	batches := func(fn func(rows []Row, opts *FormatterOptions) error) error {
+		for start := from; start < to; start += formatStreamBatchSize {
+			end := min(start+formatStreamBatchSize, to)
+
+			var rows []Row
+			var err error
+			if inView {
+				rows, err = cr.rowsAt(viewIndexes[start:end])
+				if err != nil {
+					return fmt.Errorf("cr.rowsAt: %w", err)
+				}
+			} else {
+				rows, err = cr.Rows(start, end)
+				if err != nil {
+					return fmt.Errorf("cr.Rows: %w", err)
+				}
+			}
+
+			err = fn(rows, &FormatterOptions{SchemaType: meta.SchemaType, ChunkStart: start})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if measuring, ok := formatter.(MeasuringFormatter); ok {
+		err := batches(func(rows []Row, opts *FormatterOptions) error {
+			measuring.MeasureRows(rows, opts)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	opts := &FormatterOptions{SchemaType: meta.SchemaType, ChunkStart: from}
+
+	err := formatter.WriteHeader(w, header, opts)
+	if err != nil {
+		return fmt.Errorf("formatter.WriteHeader: %w", err)
+	}
+
+	err = batches(func(rows []Row, opts *FormatterOptions) error {
+		err := formatter.WriteRows(w, rows, opts)
+		if err != nil {
+			return fmt.Errorf("formatter.WriteRows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = formatter.WriteFooter(w, opts)
+	if err != nil {
+		return fmt.Errorf("formatter.WriteFooter: %w", err)
+	}
+
+	return nil
+}
+
 func (cr *Result) Len() int {
-	return len(cr.rows)
+	cr.readMutex.RLock()
+	defer cr.readMutex.RUnlock()
+
+	return cr.length
 }
 
 func (cr *Result) IsEmpty() bool {
+	cr.readMutex.RLock()
+	defer cr.readMutex.RUnlock()
+
 	return !cr.isFilled
 }
 
 func (cr *Result) Header() Header {
+	cr.readMutex.RLock()
+	defer cr.readMutex.RUnlock()
+
 	return cr.header
 }
 
 func (cr *Result) Meta() *Meta {
+	cr.readMutex.RLock()
+	defer cr.readMutex.RUnlock()
+
 	return cr.meta
 }
 
@@ -114,21 +360,106 @@ func (cr *Result) Rows(from, to int) ([]Row, error) {
 	return rows, err
 }
 
-// getRows returns the row range and adjusted from-to values
-func (cr *Result) getRows(from, to int) (rows []Row, rangeFrom, rangeTo int, err error) {
-	// increment the read mutex
+// rowsAt returns rows at the indexes, in order of indexes. Nearby indexes are read together,
+// so that rows in the archive are read at most a batch at once.
+func (cr *Result) rowsAt(indexes []int) ([]Row, error) {
+	// positions of indexes in ascending order of indexes
+	order := make([]int, len(indexes))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Compare(indexes[a], indexes[b])
+	})
+
+	rows := make([]Row, len(indexes))
+	for start := 0; start < len(order); {
+		first := indexes[order[start]]
+		end := start + 1
+		for end < len(order) && indexes[order[end]] < first+formatStreamBatchSize {
+			end++
+		}
+		last := indexes[order[end-1]]
+
+		batch, err := cr.Rows(first, last+1)
+		if err != nil {
+			return nil, fmt.Errorf("cr.Rows: %w", err)
+		}
+		if len(batch) <= last-first {
+			return nil, fmt.Errorf("rows %d-%d are not in the result anymore", first, last)
+		}
+		for _, i := range order[start:end] {
+			rows[i] = batch[indexes[i]-first]
+		}
+
+		start = end
+	}
+
+	return rows, nil
+}
+
+// batchReader reads rows of a result one by one, holding a single batch of rows in memory.
+type batchReader struct {
+	result    *Result
+	batchSize int
+	batch     []Row
+	// index of the next row in the result and in the batch
+	index      int
+	batchIndex int
+	done       bool
+}
+
+func newBatchReader(result *Result, batchSize int) *batchReader {
+	return &batchReader{
+		result:    result,
+		batchSize: batchSize,
+	}
+}
+
+// next returns the next row of the result, or false once all rows were read.
+func (r *batchReader) next() (Row, bool, error) {
+	if r.batchIndex >= len(r.batch) {
+		if r.done {
+			return nil, false, nil
+		}
+
+		batch, err := r.result.Rows(r.index, r.index+r.batchSize)
+		if err != nil {
+			return nil, false, fmt.Errorf("result.Rows: %w", err)
+		}
+		r.batch = batch
+		r.batchIndex = 0
+		if len(batch) < 1 {
+			r.done = true
+			return nil, false, nil
+		}
+	}
+
+	row := r.batch[r.batchIndex]
+	r.batchIndex++
+	r.index++
+	return row, true, nil
+}
+
+// isAvailable reports if the rows up to index "to" can be read.
+func (cr *Result) isAvailable(to int) bool {
 	cr.readMutex.RLock()
 	defer cr.readMutex.RUnlock()
 
+	return cr.isDrained || (to >= 0 && to <= cr.length)
+}
+
+// waitRange validates the range and waits until its rows are available (or the result is drained).
+func (cr *Result) waitRange(from, to int) error {
 	// validation
 	if (from < 0 && to < 0) || (from >= 0 && to >= 0) {
 		if from > to {
-			return nil, 0, 0, ErrInvalidRange(from, to)
+			return ErrInvalidRange(from, to)
 		}
 	}
 	// undefined -> error
 	if from < 0 && to >= 0 {
-		return nil, 0, 0, ErrInvalidRange(from, to)
+		return ErrInvalidRange(from, to)
 	}
 
 	// timeout context
@@ -136,16 +467,19 @@ func (cr *Result) getRows(from, to int) (rows []Row, rangeFrom, rangeTo int, err
 	defer cancel()
 
 	// Wait for drain, available index or timeout
-	for !cr.isDrained && (to < 0 || to > len(cr.rows)) {
-
+	for !cr.isAvailable(to) {
 		if err := ctx.Err(); err != nil {
-			return nil, 0, 0, fmt.Errorf("cache flushing timeout exceeded: %s", err)
+			return fmt.Errorf("cache flushing timeout exceeded: %s", err)
 		}
 		time.Sleep(50 * time.Millisecond)
 	}
 
-	// calculate range
-	length := len(cr.rows)
+	return nil
+}
+
+// rowRange converts a range with negative indexes counted from the end
+// to a range of indexes in [0, length].
+func rowRange(from, to, length int) (int, int) {
 	if from < 0 {
 		from += length + 1
 		if from < 0 {
@@ -166,5 +500,36 @@ func (cr *Result) getRows(from, to int) (rows []Row, rangeFrom, rangeTo int, err
 		to = length
 	}
 
-	return cr.rows[from:to], from, to, nil
+	return from, to
+}
+
+// getRows returns the row range and adjusted from-to values
+func (cr *Result) getRows(from, to int) (rows []Row, rangeFrom, rangeTo int, err error) {
+	err = cr.waitRange(from, to)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	// increment the read mutex
+	cr.readMutex.RLock()
+	defer cr.readMutex.RUnlock()
+
+	// calculate range
+	from, to = rowRange(from, to, cr.length)
+
+	// whole range is in memory
+	if from >= cr.offset {
+		return cr.rows[from-cr.offset : to-cr.offset], from, to, nil
+	}
+
+	// range starts before the memory window - read evicted rows from spill
+	spilled, err := cr.spill.readRows(from, min(to, cr.offset))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("cr.spill.readRows: %w", err)
+	}
+	if to > cr.offset {
+		spilled = append(spilled, cr.rows[:to-cr.offset]...)
+	}
+
+	return spilled, from, to, nil
 }
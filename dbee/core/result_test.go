@@ -1,12 +1,14 @@
 package core_test
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/format"
 	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
 )
 
@@ -109,3 +111,58 @@ func TestResult(t *testing.T) {
 		})
 	}
 }
+
+func TestResult_FormatStream(t *testing.T) {
+	r := require.New(t)
+
+	rows := mock.NewRows(0, 1234)
+
+	result := new(core.Result)
+	err := result.SetIter(mock.NewResultStream(rows), nil)
+	r.NoError(err)
+
+	view := &core.ResultViewOptions{Sort: []core.ResultSort{{Column: "header_0", Descending: true}}}
+
+	type testCase struct {
+		name     string
+		from, to int
+		view     *core.ResultViewOptions
+	}
+
+	testCases := []testCase{
+		{name: "all rows", from: 0, to: -1},
+		{name: "range across batches", from: 321, to: 1111},
+		{name: "negative range", from: -100, to: -1},
+		{name: "empty range", from: 10, to: 10},
+		{name: "view", from: 0, to: -1, view: view},
+		{name: "range of view", from: 600, to: 700, view: view},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			// streamed output is the same as output formatted at once
+			expected, _, err := result.FormatView(format.NewCSV(), tc.view, tc.from, tc.to)
+			r.NoError(err)
+			var actual bytes.Buffer
+			err = result.FormatStream(&actual, format.NewCSV(), tc.view, tc.from, tc.to)
+			r.NoError(err)
+			r.Equal(string(expected), actual.String())
+
+			expected, _, err = result.FormatView(format.NewJSON(), tc.view, tc.from, tc.to)
+			r.NoError(err)
+			actual.Reset()
+			err = result.FormatStream(&actual, format.NewJSON(), tc.view, tc.from, tc.to)
+			r.NoError(err)
+			if tc.from == tc.to {
+				r.Equal("[]", actual.String())
+			} else {
+				r.Equal(string(expected), actual.String())
+			}
+		})
+	}
+
+	err = result.FormatStream(new(bytes.Buffer), format.NewCSV(), nil, 10, 5)
+	r.Error(err)
+}
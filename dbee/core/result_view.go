@@ -0,0 +1,419 @@
+package core
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterOperator is the comparison a ResultFilter applies to values of its column.
+type FilterOperator string
+
+const (
+	FilterEquals         FilterOperator = "eq"
+	FilterNotEquals      FilterOperator = "ne"
+	FilterContains       FilterOperator = "contains"
+	FilterRegex          FilterOperator = "regex"
+	FilterNull           FilterOperator = "null"
+	FilterNotNull        FilterOperator = "not_null"
+	FilterGreater        FilterOperator = "gt"
+	FilterGreaterOrEqual FilterOperator = "ge"
+	FilterLess           FilterOperator = "lt"
+	FilterLessOrEqual    FilterOperator = "le"
+)
+
+type (
+	// ResultSort sorts rows by values of the column.
+	ResultSort struct {
+		Column     string
+		Descending bool
+	}
+
+	// ResultFilter keeps only rows whose value of the column matches the value with the operator.
+	// Values are compared as numbers if both of them are numeric and time values are compared
+	// as times with values which are dates or timestamps (in the zone of the time value, if
+	// the timestamp has no zone). Contains is case insensitive.
+	ResultFilter struct {
+		Column   string
+		Operator FilterOperator
+		Value    string
+	}
+
+	// ResultViewOptions describe a view of result rows. Rows are filtered first and then sorted.
+	ResultViewOptions struct {
+		// Sort is the list of sort columns, in order of precedence.
+		Sort []ResultSort
+		// Filters which all have to match.
+		Filters []ResultFilter
+		// Search is a case insensitive text which any value of the row has to contain.
+		Search string
+	}
+)
+
+// IsEmpty reports if the view shows all rows in their original order.
+func (o *ResultViewOptions) IsEmpty() bool {
+	return o == nil || (len(o.Sort) < 1 && len(o.Filters) < 1 && o.Search == "")
+}
+
+// resultView is the last computed view of a result.
+type resultView struct {
+	key string
+	// indexes of rows of the view in the result
+	indexes []int
+}
+
+// viewBatchSize is the number of rows read at once while computing a view.
+const viewBatchSize = archiveChunkSize
+
+// FormatView formats rows of the view of result in range [from, to), which is applied the same way as in Format.
+// Views are computed over the rows of the result (in memory or in the archive) and indexes of rows of
+// the last one are kept, so that paging through it doesn't compute it again. It also returns the number
+// of rows in the view.
+func (cr *Result) FormatView(formatter Formatter, view *ResultViewOptions, from, to int) ([]byte, int, error) {
+	if view.IsEmpty() {
+		f, err := cr.Format(formatter, from, to)
+		return f, cr.Len(), err
+	}
+
+	indexes, err := cr.view(view)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if (from < 0 && to >= 0) || ((from < 0) == (to < 0) && from > to) {
+		return nil, 0, ErrInvalidRange(from, to)
+	}
+	from, to = rowRange(from, to, len(indexes))
+
+	rows, err := cr.rowsAt(indexes[from:to])
+	if err != nil {
+		return nil, 0, fmt.Errorf("cr.rowsAt: %w", err)
+	}
+
+	opts := &FormatterOptions{
+		SchemaType: cr.Meta().SchemaType,
+		ChunkStart: from,
+	}
+
+	f, err := formatter.Format(cr.Header(), rows, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("formatter.Format: %w", err)
+	}
+
+	return f, len(indexes), nil
+}
+
+// view returns indexes of rows of the view, which are cached once the result is drained.
+func (cr *Result) view(opts *ResultViewOptions) ([]int, error) {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+	key := string(b)
+
+	// the view isn't computed under the lock, as reading rows can wait for the result to be drained
+	cr.viewMutex.Lock()
+	last, generation := cr.lastView, cr.viewGeneration
+	cr.viewMutex.Unlock()
+
+	if last != nil && last.key == key {
+		return last.indexes, nil
+	}
+
+	cr.readMutex.RLock()
+	drained := cr.isDrained
+	header := cr.header
+	cr.readMutex.RUnlock()
+
+	indexes, err := applyView(header, newBatchReader(cr, viewBatchSize), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// rows of a result which is still being retrieved would be missing from the view
+	cr.viewMutex.Lock()
+	if drained && generation == cr.viewGeneration {
+		cr.lastView = &resultView{key: key, indexes: indexes}
+	}
+	cr.viewMutex.Unlock()
+
+	return indexes, nil
+}
+
+func (cr *Result) resetView() {
+	cr.viewMutex.Lock()
+	defer cr.viewMutex.Unlock()
+
+	cr.lastView = nil
+	cr.viewGeneration++
+}
+
+// viewRow is a row which matched the view, with its values of the sort columns.
+type viewRow struct {
+	index  int
+	values []any
+}
+
+// applyView filters rows read by reader and sorts them. Only the matching rows' values of the sort
+// columns are held in memory. It returns indexes of the rows of the view in the result.
+func applyView(header Header, reader *batchReader, opts *ResultViewOptions) ([]int, error) {
+	columnIndex := func(column string) (int, error) {
+		i := slices.Index(header, column)
+		if i < 0 {
+			return 0, fmt.Errorf("unknown column: %q", column)
+		}
+		return i, nil
+	}
+
+	var matchers []func(Row) bool
+	for _, filter := range opts.Filters {
+		i, err := columnIndex(filter.Column)
+		if err != nil {
+			return nil, err
+		}
+		match, err := filter.matcher()
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, func(row Row) bool { return match(cell(row, i)) })
+	}
+	if opts.Search != "" {
+		search := strings.ToLower(opts.Search)
+		matchers = append(matchers, func(row Row) bool {
+			return slices.ContainsFunc(row, func(value any) bool {
+				return value != nil && strings.Contains(strings.ToLower(fmt.Sprint(value)), search)
+			})
+		})
+	}
+
+	sortColumns := make([]int, len(opts.Sort))
+	for i, sort := range opts.Sort {
+		index, err := columnIndex(sort.Column)
+		if err != nil {
+			return nil, err
+		}
+		sortColumns[i] = index
+	}
+
+	var filtered []viewRow
+	for index := 0; ; index++ {
+		row, ok, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		matches := true
+		for _, match := range matchers {
+			if !match(row) {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+
+		var values []any
+		if len(sortColumns) > 0 {
+			values = make([]any, len(sortColumns))
+			for i, column := range sortColumns {
+				values[i] = cell(row, column)
+			}
+		}
+		filtered = append(filtered, viewRow{index: index, values: values})
+	}
+
+	var comparators []func(a, b viewRow) int
+	for i, sort := range opts.Sort {
+		compare := columnComparator(filtered, i)
+		descending := sort.Descending
+		comparators = append(comparators, func(a, b viewRow) int {
+			c := compare(a.values[i], b.values[i])
+			if descending {
+				return -c
+			}
+			return c
+		})
+	}
+
+	if len(comparators) > 0 {
+		slices.SortStableFunc(filtered, func(a, b viewRow) int {
+			for _, compare := range comparators {
+				if c := compare(a, b); c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+
+	indexes := make([]int, len(filtered))
+	for i, row := range filtered {
+		indexes[i] = row.index
+	}
+
+	return indexes, nil
+}
+
+// matcher returns a function which reports if the value matches the filter.
+func (f *ResultFilter) matcher() (func(any) bool, error) {
+	number, isNumber := toNumber(f.Value)
+	filterTime, isTime := parseFilterTime(f.Value)
+
+	// compare returns the order of the value and the filter value
+	compare := func(value any) int {
+		if n, ok := toNumber(value); ok && isNumber {
+			return cmp.Compare(n, number)
+		}
+		if t, ok := value.(time.Time); ok && isTime {
+			return t.Compare(filterTime.in(t.Location()))
+		}
+		return strings.Compare(fmt.Sprint(value), f.Value)
+	}
+
+	switch f.Operator {
+	case FilterEquals:
+		return func(value any) bool { return value != nil && compare(value) == 0 }, nil
+	case FilterNotEquals:
+		return func(value any) bool { return value == nil || compare(value) != 0 }, nil
+	case FilterContains:
+		sub := strings.ToLower(f.Value)
+		return func(value any) bool {
+			return value != nil && strings.Contains(strings.ToLower(fmt.Sprint(value)), sub)
+		}, nil
+	case FilterRegex:
+		re, err := regexp.Compile(f.Value)
+		if err != nil {
+			return nil, fmt.Errorf("regexp.Compile: %w", err)
+		}
+		return func(value any) bool { return value != nil && re.MatchString(fmt.Sprint(value)) }, nil
+	case FilterNull:
+		return func(value any) bool { return value == nil }, nil
+	case FilterNotNull:
+		return func(value any) bool { return value != nil }, nil
+	case FilterGreater:
+		return func(value any) bool { return value != nil && compare(value) > 0 }, nil
+	case FilterGreaterOrEqual:
+		return func(value any) bool { return value != nil && compare(value) >= 0 }, nil
+	case FilterLess:
+		return func(value any) bool { return value != nil && compare(value) < 0 }, nil
+	case FilterLessOrEqual:
+		return func(value any) bool { return value != nil && compare(value) <= 0 }, nil
+	default:
+		return nil, fmt.Errorf("unknown filter operator: %q", f.Operator)
+	}
+}
+
+// filterTimeLayouts are the layouts of filter values compared with time values.
+var filterTimeLayouts = []struct {
+	layout string
+	zoned  bool
+}{
+	{time.RFC3339Nano, true},
+	{"2006-01-02 15:04:05.999999999Z07:00", true},
+	{"2006-01-02 15:04:05.999999999 -0700 MST", true},
+	{"2006-01-02T15:04:05.999999999", false},
+	{"2006-01-02 15:04:05.999999999", false},
+	{"2006-01-02T15:04", false},
+	{"2006-01-02 15:04", false},
+	{time.DateOnly, false},
+}
+
+// filterTime is a time of a filter value. Times without a zone are in the location of compared values.
+type filterTime struct {
+	t     time.Time
+	zoned bool
+}
+
+func (ft filterTime) in(loc *time.Location) time.Time {
+	if ft.zoned {
+		return ft.t
+	}
+	return time.Date(ft.t.Year(), ft.t.Month(), ft.t.Day(), ft.t.Hour(), ft.t.Minute(), ft.t.Second(), ft.t.Nanosecond(), loc)
+}
+
+// parseFilterTime parses the filter value as a date or a timestamp, optionally with a zone.
+func parseFilterTime(value string) (filterTime, bool) {
+	value = strings.TrimSpace(value)
+	for _, l := range filterTimeLayouts {
+		t, err := time.Parse(l.layout, value)
+		if err == nil {
+			return filterTime{t: t, zoned: l.zoned}, true
+		}
+	}
+	return filterTime{}, false
+}
+
+// columnComparator returns a function which compares values of the sort column in ascending order.
+// Values are compared as numbers or times if all values of the column are numbers or times,
+// and as strings otherwise. Nulls are after all values.
+func columnComparator(rows []viewRow, column int) func(a, b any) int {
+	numeric, temporal := true, true
+	for _, row := range rows {
+		value := row.values[column]
+		if value == nil {
+			continue
+		}
+		if _, ok := toNumber(value); !ok {
+			numeric = false
+		}
+		if _, ok := value.(time.Time); !ok {
+			temporal = false
+		}
+		if !numeric && !temporal {
+			break
+		}
+	}
+
+	return func(a, b any) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		case b == nil:
+			return -1
+		case numeric:
+			na, _ := toNumber(a)
+			nb, _ := toNumber(b)
+			return cmp.Compare(na, nb)
+		case temporal:
+			return a.(time.Time).Compare(b.(time.Time))
+		default:
+			return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+		}
+	}
+}
+
+// toNumber converts numeric values and numeric strings (e.g. decimals returned as text) to float64.
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	case []byte:
+		n, err := strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+		return n, err == nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
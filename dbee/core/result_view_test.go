@@ -0,0 +1,213 @@
+package core_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
+)
+
+// rowsFormatter formats rows as JSON arrays, so that formatted views can be compared.
+type rowsFormatter struct{}
+
+func (rowsFormatter) Format(_ core.Header, rows []core.Row, _ *core.FormatterOptions) ([]byte, error) {
+	return json.Marshal(rows)
+}
+
+func TestResult_FormatView(t *testing.T) {
+	header := core.Header{"id", "name", "amount"}
+	rows := []core.Row{
+		{1, "apple", "10"},
+		{2, "Banana", nil},
+		{3, "cherry", "9.5"},
+		{4, "apricot", "100"},
+		{5, "banana", "10"},
+	}
+
+	type testCase struct {
+		name           string
+		view           *core.ResultViewOptions
+		from           int
+		to             int
+		expected       string
+		expectedLength int
+		expectedError  bool
+	}
+
+	testCases := []testCase{
+		{
+			name:           "no view",
+			view:           nil,
+			from:           0,
+			to:             2,
+			expected:       `[[1,"apple","10"],[2,"Banana",null]]`,
+			expectedLength: 5,
+		},
+		{
+			name: "numeric sort with nulls last",
+			view: &core.ResultViewOptions{
+				Sort: []core.ResultSort{{Column: "amount"}},
+			},
+			from:           0,
+			to:             -1,
+			expected:       `[[3,"cherry","9.5"],[1,"apple","10"],[5,"banana","10"],[4,"apricot","100"],[2,"Banana",null]]`,
+			expectedLength: 5,
+		},
+		{
+			name: "multi column sort",
+			view: &core.ResultViewOptions{
+				Sort: []core.ResultSort{{Column: "amount", Descending: true}, {Column: "id", Descending: true}},
+			},
+			from:           0,
+			to:             3,
+			expected:       `[[2,"Banana",null],[4,"apricot","100"],[5,"banana","10"]]`,
+			expectedLength: 5,
+		},
+		{
+			name: "filters",
+			view: &core.ResultViewOptions{
+				Filters: []core.ResultFilter{
+					{Column: "name", Operator: core.FilterContains, Value: "AP"},
+					{Column: "amount", Operator: core.FilterGreaterOrEqual, Value: "10"},
+				},
+			},
+			from:           0,
+			to:             -1,
+			expected:       `[[1,"apple","10"],[4,"apricot","100"]]`,
+			expectedLength: 2,
+		},
+		{
+			name: "null filter",
+			view: &core.ResultViewOptions{
+				Filters: []core.ResultFilter{{Column: "amount", Operator: core.FilterNull}},
+			},
+			from:           0,
+			to:             -1,
+			expected:       `[[2,"Banana",null]]`,
+			expectedLength: 1,
+		},
+		{
+			name: "regex filter and search",
+			view: &core.ResultViewOptions{
+				Filters: []core.ResultFilter{{Column: "name", Operator: core.FilterRegex, Value: "^[a-z]"}},
+				Search:  "BAN",
+			},
+			from:           0,
+			to:             -1,
+			expected:       `[[5,"banana","10"]]`,
+			expectedLength: 1,
+		},
+		{
+			name: "unknown column",
+			view: &core.ResultViewOptions{
+				Sort: []core.ResultSort{{Column: "missing"}},
+			},
+			from:          0,
+			to:            -1,
+			expectedError: true,
+		},
+		{
+			name: "unknown operator",
+			view: &core.ResultViewOptions{
+				Filters: []core.ResultFilter{{Column: "id", Operator: "like"}},
+			},
+			from:          0,
+			to:            -1,
+			expectedError: true,
+		},
+	}
+
+	result := new(core.Result)
+	err := result.SetIter(mock.NewResultStream(rows, mock.ResultStreamWithHeader(header)), nil)
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			b, length, err := result.FormatView(rowsFormatter{}, tc.view, tc.from, tc.to)
+			if tc.expectedError {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.JSONEq(tc.expected, string(b))
+			r.Equal(tc.expectedLength, length)
+		})
+	}
+}
+
+func TestResult_FormatViewTimes(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	header := core.Header{"id", "at"}
+	rows := []core.Row{
+		{1, time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)},
+		{2, time.Date(2024, 1, 2, 0, 30, 0, 0, zone)},
+		{3, time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)},
+		{4, nil},
+		{5, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	testCases := []struct {
+		name     string
+		filter   core.ResultFilter
+		expected []int
+	}{
+		{
+			// 00:30 in UTC+2 is before 23:00 UTC of the previous day, which a text comparison gets wrong
+			name:     "zoned timestamp",
+			filter:   core.ResultFilter{Column: "at", Operator: core.FilterLess, Value: "2024-01-01T23:00:00Z"},
+			expected: []int{2, 5},
+		},
+		{
+			name:     "equal instant in another zone",
+			filter:   core.ResultFilter{Column: "at", Operator: core.FilterEquals, Value: "2024-01-02T00:00:00+01:00"},
+			expected: []int{1},
+		},
+		{
+			name:     "date in zone of the values",
+			filter:   core.ResultFilter{Column: "at", Operator: core.FilterGreaterOrEqual, Value: "2024-01-02"},
+			expected: []int{2, 3},
+		},
+		{
+			name:     "timestamp without zone",
+			filter:   core.ResultFilter{Column: "at", Operator: core.FilterGreater, Value: "2024-01-01 23:00:00"},
+			expected: []int{2, 3},
+		},
+		{
+			name:     "text of the value",
+			filter:   core.ResultFilter{Column: "at", Operator: core.FilterContains, Value: "2023-12"},
+			expected: []int{5},
+		},
+	}
+
+	result := new(core.Result)
+	err := result.SetIter(mock.NewResultStream(rows, mock.ResultStreamWithHeader(header)), nil)
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			view := &core.ResultViewOptions{
+				Filters: []core.ResultFilter{tc.filter},
+				Sort:    []core.ResultSort{{Column: "id"}},
+			}
+			b, length, err := result.FormatView(rowsFormatter{}, view, 0, -1)
+			r.NoError(err)
+			r.Equal(len(tc.expected), length)
+
+			var actual [][]any
+			r.NoError(json.Unmarshal(b, &actual))
+			ids := make([]int, len(actual))
+			for i, row := range actual {
+				ids[i] = int(row[0].(float64))
+			}
+			r.Equal(tc.expected, ids)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package core
+
+import "errors"
+
+var _ ResultStream = (*rowsStream)(nil)
+
+// rowsStream is a ResultStream of rows which are already in memory,
+// e.g. rows computed from results of other calls.
+type rowsStream struct {
+	header Header
+	meta   *Meta
+	rows   []Row
+	index  int
+}
+
+func newRowsStream(header Header, rows []Row) *rowsStream {
+	return &rowsStream{
+		header: header,
+		meta:   &Meta{SchemaType: SchemaFul},
+		rows:   rows,
+	}
+}
+
+func (s *rowsStream) Meta() *Meta {
+	return s.meta
+}
+
+func (s *rowsStream) Header() Header {
+	return s.header
+}
+
+func (s *rowsStream) HasNext() bool {
+	return s.index < len(s.rows)
+}
+
+func (s *rowsStream) Next() (Row, error) {
+	if !s.HasNext() {
+		return nil, errors.New("no next row")
+	}
+
+	row := s.rows[s.index]
+	s.index++
+	return row, nil
+}
+
+func (s *rowsStream) Close() {}
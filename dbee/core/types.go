@@ -2,6 +2,7 @@ package core
 
 import (
 	"errors"
+	"io"
 	"strings"
 )
 
@@ -23,6 +24,24 @@ type (
 	Formatter interface {
 		Format(header Header, rows []Row, opts *FormatterOptions) ([]byte, error)
 	}
+
+	// StreamFormatter writes the output to a writer in parts: the header, batches of rows and the footer,
+	// so that the whole output doesn't have to be held in memory. ChunkStart of options is the index
+	// of the first row of the batch. Formatters can keep state between the parts, so a stream
+	// formatter shouldn't be used for more than one output at a time.
+	StreamFormatter interface {
+		WriteHeader(w io.Writer, header Header, opts *FormatterOptions) error
+		WriteRows(w io.Writer, rows []Row, opts *FormatterOptions) error
+		WriteFooter(w io.Writer, opts *FormatterOptions) error
+	}
+
+	// MeasuringFormatter is an optional interface of StreamFormatter, implemented by formatters
+	// which have to see all rows before writing any (e.g. to align columns). Batches of rows
+	// are measured before the header is written.
+	MeasuringFormatter interface {
+		StreamFormatter
+		MeasureRows(rows []Row, opts *FormatterOptions)
+	}
 )
 
 type (
@@ -44,6 +63,15 @@ type (
 		HasNext() bool
 		Close()
 	}
+
+	// MultiResultStream is an optional interface of ResultStream, implemented by streams
+	// which can produce more than one result set (e.g. multi statement batches or procedures).
+	MultiResultStream interface {
+		ResultStream
+		// NextResultSet advances the stream to the next result set. Header and Meta
+		// describe the new set afterwards. Returns false if there are no more sets.
+		NextResultSet() bool
+	}
 )
 
 type StructureType int
@@ -1,22 +1,59 @@
 package main
 
 import (
+	"time"
+
 	"github.com/neovim/go-client/nvim"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/format"
 	"github.com/kndndrj/nvim-dbee/dbee/handler"
 	"github.com/kndndrj/nvim-dbee/dbee/plugin"
 )
 
 func mountEndpoints(p *plugin.Plugin, h *handler.Handler) {
+	p.RegisterEndpoint(
+		"DbeeConfigure",
+		func(args *struct {
+			Opts *struct {
+				ResultWindowSize  int    `msgpack:"result_window_size"`
+				HistoryDir        string `msgpack:"history_dir"`
+				HistoryMaxAgeS    int    `msgpack:"history_max_age_s"`
+				HistoryMaxCalls   int    `msgpack:"history_max_calls"`
+				HistoryMaxSize    int64  `msgpack:"history_max_size"`
+				HistoryGCInterval int    `msgpack:"history_gc_interval_s"`
+				HistoryOmitParams bool   `msgpack:"history_omit_params"`
+				SavedQueriesFile  string `msgpack:"saved_queries_file"`
+			} `msgpack:",array"`
+		},
+		) error {
+			return h.Configure(&handler.Options{
+				ResultWindowSize: args.Opts.ResultWindowSize,
+				HistoryDir:       args.Opts.HistoryDir,
+				Retention: handler.Retention{
+					MaxAge:   time.Duration(args.Opts.HistoryMaxAgeS) * time.Second,
+					MaxCalls: args.Opts.HistoryMaxCalls,
+					MaxSize:  args.Opts.HistoryMaxSize,
+					Interval: time.Duration(args.Opts.HistoryGCInterval) * time.Second,
+				},
+				HistoryOmitParams: args.Opts.HistoryOmitParams,
+				SavedQueriesFile:  args.Opts.SavedQueriesFile,
+			})
+		})
+
 	p.RegisterEndpoint(
 		"DbeeCreateConnection",
 		func(args *struct {
 			Opts *struct {
-				ID   string `msgpack:"id"`
-				URL  string `msgpack:"url"`
-				Type string `msgpack:"type"`
-				Name string `msgpack:"name"`
+				ID        string `msgpack:"id"`
+				URL       string `msgpack:"url"`
+				Type      string `msgpack:"type"`
+				Name      string `msgpack:"name"`
+				TimeoutMs int    `msgpack:"timeout_ms"`
+				MaxRows   int    `msgpack:"max_rows"`
+				MaxBytes  int    `msgpack:"max_bytes"`
+
+				ConfirmAboveBytes int `msgpack:"confirm_above_bytes"`
 			} `msgpack:",array"`
 		},
 		) (core.ConnectionID, error) {
@@ -25,6 +62,13 @@ func mountEndpoints(p *plugin.Plugin, h *handler.Handler) {
 				Name: args.Opts.Name,
 				Type: args.Opts.Type,
 				URL:  args.Opts.URL,
+				CallOptions: core.CallOptions{
+					Timeout:  time.Duration(args.Opts.TimeoutMs) * time.Millisecond,
+					MaxRows:  args.Opts.MaxRows,
+					MaxBytes: args.Opts.MaxBytes,
+
+					ConfirmAboveBytes: args.Opts.ConfirmAboveBytes,
+				},
 			})
 		})
 
@@ -64,6 +108,42 @@ func mountEndpoints(p *plugin.Plugin, h *handler.Handler) {
 			return h.ConnectionIsConnected(core.ConnectionID(args.ID))
 		})
 
+	p.RegisterEndpoint(
+		"DbeeConnectionBeginTransaction",
+		func(args *struct {
+			ID core.ConnectionID `msgpack:",array"`
+		},
+		) error {
+			return h.ConnectionBeginTransaction(args.ID)
+		})
+
+	p.RegisterEndpoint(
+		"DbeeConnectionCommitTransaction",
+		func(args *struct {
+			ID core.ConnectionID `msgpack:",array"`
+		},
+		) error {
+			return h.ConnectionCommitTransaction(args.ID)
+		})
+
+	p.RegisterEndpoint(
+		"DbeeConnectionRollbackTransaction",
+		func(args *struct {
+			ID core.ConnectionID `msgpack:",array"`
+		},
+		) error {
+			return h.ConnectionRollbackTransaction(args.ID)
+		})
+
+	p.RegisterEndpoint(
+		"DbeeConnectionInTransaction",
+		func(args *struct {
+			ID core.ConnectionID `msgpack:",array"`
+		},
+		) (bool, error) {
+			return h.ConnectionInTransaction(args.ID)
+		})
+
 	p.RegisterEndpoint(
 		"DbeeGetConnections",
 		func(args *struct {
@@ -122,9 +202,63 @@ func mountEndpoints(p *plugin.Plugin, h *handler.Handler) {
 		func(args *struct {
 			ID    core.ConnectionID `msgpack:",array"`
 			Query string
+			Opts  *struct {
+				Params    any `msgpack:"params"`
+				TimeoutMs int `msgpack:"timeout_ms"`
+				MaxRows   int `msgpack:"max_rows"`
+				MaxBytes  int `msgpack:"max_bytes"`
+
+				ConfirmAboveBytes int `msgpack:"confirm_above_bytes"`
+			}
+		},
+		) (any, error) {
+			var params any
+			opts := &core.CallOptions{}
+			if args.Opts != nil {
+				params = args.Opts.Params
+				opts = &core.CallOptions{
+					Timeout:  time.Duration(args.Opts.TimeoutMs) * time.Millisecond,
+					MaxRows:  args.Opts.MaxRows,
+					MaxBytes: args.Opts.MaxBytes,
+
+					ConfirmAboveBytes: args.Opts.ConfirmAboveBytes,
+				}
+			}
+			call, err := h.ConnectionExecute(args.ID, args.Query, params, opts)
+			return handler.WrapCall(call), err
+		})
+
+	p.RegisterEndpoint(
+		"DbeeExecuteMany",
+		func(args *struct {
+			IDs   []core.ConnectionID `msgpack:",array"`
+			Query string
+			Opts  *struct {
+				Params      any `msgpack:"params"`
+				Concurrency int `msgpack:"concurrency"`
+				TimeoutMs   int `msgpack:"timeout_ms"`
+				MaxRows     int `msgpack:"max_rows"`
+				MaxBytes    int `msgpack:"max_bytes"`
+
+				ConfirmAboveBytes int `msgpack:"confirm_above_bytes"`
+			}
 		},
 		) (any, error) {
-			call, err := h.ConnectionExecute(args.ID, args.Query)
+			var params any
+			var concurrency int
+			opts := &core.CallOptions{}
+			if args.Opts != nil {
+				params = args.Opts.Params
+				concurrency = args.Opts.Concurrency
+				opts = &core.CallOptions{
+					Timeout:  time.Duration(args.Opts.TimeoutMs) * time.Millisecond,
+					MaxRows:  args.Opts.MaxRows,
+					MaxBytes: args.Opts.MaxBytes,
+
+					ConfirmAboveBytes: args.Opts.ConfirmAboveBytes,
+				}
+			}
+			call, err := h.ExecuteMany(args.IDs, args.Query, params, opts, concurrency)
 			return handler.WrapCall(call), err
 		})
 
@@ -138,6 +272,15 @@ func mountEndpoints(p *plugin.Plugin, h *handler.Handler) {
 			return handler.WrapCalls(calls), err
 		})
 
+	p.RegisterEndpoint(
+		"DbeeConnectionPurgeHistory",
+		func(args *struct {
+			ID core.ConnectionID `msgpack:",array"`
+		},
+		) (any, error) {
+			return nil, h.ConnectionPurgeHistory(args.ID)
+		})
+
 	p.RegisterEndpoint(
 		"DbeeConnectionGetParams",
 		func(args *struct {
@@ -198,6 +341,29 @@ func mountEndpoints(p *plugin.Plugin, h *handler.Handler) {
 			return nil, h.ConnectionSelectDatabase(args.ID, args.Database)
 		})
 
+	p.RegisterEndpoint(
+		"DbeeConnectionExplain",
+		func(args *struct {
+			ID    core.ConnectionID `msgpack:",array"`
+			Query string
+			Opts  *struct {
+				Analyze bool `msgpack:"analyze"`
+				Confirm bool `msgpack:"confirm"`
+				Buffer  int  `msgpack:"buffer"`
+			}
+		},
+		) (any, error) {
+			opts := &core.ExplainOptions{}
+			buffer := 0
+			if args.Opts != nil {
+				opts.Analyze = args.Opts.Analyze
+				opts.Confirmed = args.Opts.Confirm
+				buffer = args.Opts.Buffer
+			}
+			plan, err := h.ConnectionExplain(args.ID, args.Query, opts, nvim.Buffer(buffer))
+			return handler.WrapPlanNode(plan), err
+		})
+
 	p.RegisterEndpoint(
 		"DbeeCallCancel",
 		func(args *struct {
@@ -207,18 +373,193 @@ func mountEndpoints(p *plugin.Plugin, h *handler.Handler) {
 			return nil, h.CallCancel(args.ID)
 		})
 
+	p.RegisterEndpoint(
+		"DbeeSearchCalls",
+		func(args *struct {
+			Opts *struct {
+				Query         string   `msgpack:"query"`
+				Regex         bool     `msgpack:"regex"`
+				States        []string `msgpack:"states"`
+				ConnectionIDs []string `msgpack:"conn_ids"`
+				SinceUs       int64    `msgpack:"since_us"`
+				UntilUs       int64    `msgpack:"until_us"`
+				MinDurationUs int64    `msgpack:"min_duration_us"`
+				MaxDurationUs int64    `msgpack:"max_duration_us"`
+				HasError      *bool    `msgpack:"has_error"`
+				SortBy        string   `msgpack:"sort_by"`
+				Ascending     bool     `msgpack:"ascending"`
+				Offset        int      `msgpack:"offset"`
+				Limit         int      `msgpack:"limit"`
+			} `msgpack:",array"`
+		},
+		) (any, error) {
+			search := &handler.CallSearch{}
+			if o := args.Opts; o != nil {
+				search = &handler.CallSearch{
+					Query:       o.Query,
+					Regex:       o.Regex,
+					MinDuration: time.Duration(o.MinDurationUs) * time.Microsecond,
+					MaxDuration: time.Duration(o.MaxDurationUs) * time.Microsecond,
+					HasError:    o.HasError,
+					SortBy:      o.SortBy,
+					Ascending:   o.Ascending,
+					Offset:      o.Offset,
+					Limit:       o.Limit,
+				}
+				for _, state := range o.States {
+					search.States = append(search.States, core.CallStateFromString(state))
+				}
+				for _, id := range o.ConnectionIDs {
+					search.ConnectionIDs = append(search.ConnectionIDs, core.ConnectionID(id))
+				}
+				if o.SinceUs > 0 {
+					search.Since = time.UnixMicro(o.SinceUs)
+				}
+				if o.UntilUs > 0 {
+					search.Until = time.UnixMicro(o.UntilUs)
+				}
+			}
+
+			result, err := h.SearchCalls(search)
+			return handler.WrapCallSearchResult(result), err
+		})
+
+	p.RegisterEndpoint(
+		"DbeeCallRerun",
+		func(args *struct {
+			ID     core.CallID `msgpack:",array"`
+			ConnID core.ConnectionID
+		},
+		) (any, error) {
+			call, err := h.CallRerun(args.ID, args.ConnID)
+			return handler.WrapCall(call), err
+		})
+
+	p.RegisterEndpoint(
+		"DbeeCallDiff",
+		func(args *struct {
+			FromID core.CallID `msgpack:",array"`
+			ToID   core.CallID
+			Opts   *struct {
+				KeyColumns       []string `msgpack:"key_columns"`
+				IncludeUnchanged bool     `msgpack:"include_unchanged"`
+				MaxRows          int      `msgpack:"max_rows"`
+				ResultSet        int      `msgpack:"result_set"`
+			}
+		},
+		) (any, error) {
+			opts := &core.DiffOptions{}
+			resultSet := 0
+			if o := args.Opts; o != nil {
+				opts.KeyColumns = o.KeyColumns
+				opts.IncludeUnchanged = o.IncludeUnchanged
+				opts.MaxRows = o.MaxRows
+				resultSet = o.ResultSet
+			}
+
+			call, err := h.CallDiff(args.FromID, args.ToID, resultSet, opts)
+			return handler.WrapCall(call), err
+		})
+
+	p.RegisterEndpoint(
+		"DbeeCallColumnStats",
+		func(args *struct {
+			ID   core.CallID `msgpack:",array"`
+			Opts *struct {
+				ResultSet int      `msgpack:"result_set"`
+				Columns   []string `msgpack:"columns"`
+				Top       int      `msgpack:"top"`
+			}
+		},
+		) (any, error) {
+			opts := &core.ColumnStatsOptions{}
+			resultSet := 0
+			if o := args.Opts; o != nil {
+				opts.Columns = o.Columns
+				opts.Top = o.Top
+				resultSet = o.ResultSet
+			}
+
+			call, err := h.CallColumnStats(args.ID, resultSet, opts)
+			return handler.WrapCall(call), err
+		})
+
+	p.RegisterEndpoint(
+		"DbeeCallSaveQuery",
+		func(args *struct {
+			ID   core.CallID `msgpack:",array"`
+			Opts *handler.SavedQuery
+		},
+		) (*handler.SavedQuery, error) {
+			return h.CallSaveQuery(args.ID, args.Opts)
+		})
+
+	p.RegisterEndpoint(
+		"DbeeSavedQueryList",
+		func() ([]*handler.SavedQuery, error) {
+			return h.SavedQueryList()
+		})
+
+	p.RegisterEndpoint(
+		"DbeeSavedQueryGet",
+		func(args *struct {
+			ID string `msgpack:",array"`
+		},
+		) (*handler.SavedQuery, error) {
+			return h.SavedQueryGet(args.ID)
+		})
+
+	p.RegisterEndpoint(
+		"DbeeSavedQuerySave",
+		func(args *struct {
+			Query *handler.SavedQuery `msgpack:",array"`
+		},
+		) (*handler.SavedQuery, error) {
+			return h.SavedQuerySave(args.Query)
+		})
+
+	p.RegisterEndpoint(
+		"DbeeSavedQueryDelete",
+		func(args *struct {
+			ID string `msgpack:",array"`
+		},
+		) (any, error) {
+			return nil, h.SavedQueryDelete(args.ID)
+		})
+
+	p.RegisterEndpoint(
+		"DbeeCallDelete",
+		func(args *struct {
+			ID core.CallID `msgpack:",array"`
+		},
+		) (any, error) {
+			return nil, h.CallDelete(args.ID)
+		})
+
+	p.RegisterEndpoint(
+		"DbeeCallConfirm",
+		func(args *struct {
+			ID      core.CallID `msgpack:",array"`
+			Approve bool
+		},
+		) (any, error) {
+			return nil, h.CallConfirm(args.ID, args.Approve)
+		})
+
 	p.RegisterEndpoint(
 		"DbeeCallDisplayResult",
 		func(args *struct {
 			ID   core.CallID `msgpack:",array"`
 			Opts *struct {
-				Buffer int `msgpack:"buffer"`
-				From   int `msgpack:"from"`
-				To     int `msgpack:"to"`
+				ResultSet int         `msgpack:"result_set"`
+				Buffer    int         `msgpack:"buffer"`
+				From      int         `msgpack:"from"`
+				To        int         `msgpack:"to"`
+				View      *resultView `msgpack:"view"`
 			}
 		},
 		) (any, error) {
-			return h.CallDisplayResult(args.ID, nvim.Buffer(args.Opts.Buffer), args.Opts.From, args.Opts.To)
+			return h.CallDisplayResult(args.ID, args.Opts.ResultSet, nvim.Buffer(args.Opts.Buffer), args.Opts.From, args.Opts.To, args.Opts.View.options())
 		})
 
 	p.RegisterEndpoint(
@@ -228,12 +569,98 @@ func mountEndpoints(p *plugin.Plugin, h *handler.Handler) {
 			Format string
 			Output string
 			Opts   *struct {
-				From     int `msgpack:"from"`
-				To       int `msgpack:"to"`
-				ExtraArg any `msgpack:"extra_arg"`
+				ResultSet int          `msgpack:"result_set"`
+				From      int          `msgpack:"from"`
+				To        int          `msgpack:"to"`
+				View      *resultView  `msgpack:"view"`
+				SQL       *sqlStore    `msgpack:"sql"`
+				Markup    *markupStore `msgpack:"markup"`
+				ExtraArg  any          `msgpack:"extra_arg"`
 			}
 		},
 		) (any, error) {
-			return nil, h.CallStoreResult(args.ID, args.Format, args.Output, args.Opts.From, args.Opts.To, args.Opts.ExtraArg)
+			return nil, h.CallStoreResult(args.ID, args.Format, args.Output, &handler.StoreOptions{
+				ResultSet: args.Opts.ResultSet,
+				From:      args.Opts.From,
+				To:        args.Opts.To,
+				View:      args.Opts.View.options(),
+				SQL:       args.Opts.SQL.options(),
+				Markup:    args.Opts.Markup.options(),
+			}, args.Opts.ExtraArg)
 		})
 }
+
+// resultView is the view of result rows sent by lua (see core.ResultViewOptions).
+type resultView struct {
+	Sort []struct {
+		Column     string `msgpack:"column"`
+		Descending bool   `msgpack:"descending"`
+	} `msgpack:"sort"`
+	Filters []struct {
+		Column   string `msgpack:"column"`
+		Operator string `msgpack:"operator"`
+		Value    string `msgpack:"value"`
+	} `msgpack:"filters"`
+	Search string `msgpack:"search"`
+}
+
+func (v *resultView) options() *core.ResultViewOptions {
+	if v == nil {
+		return nil
+	}
+
+	opts := &core.ResultViewOptions{Search: v.Search}
+	for _, s := range v.Sort {
+		opts.Sort = append(opts.Sort, core.ResultSort{Column: s.Column, Descending: s.Descending})
+	}
+	for _, f := range v.Filters {
+		opts.Filters = append(opts.Filters, core.ResultFilter{
+			Column:   f.Column,
+			Operator: core.FilterOperator(f.Operator),
+			Value:    f.Value,
+		})
+	}
+
+	return opts
+}
+
+// sqlStore are options of the "sql" store format sent by lua (see format.SQLOptions).
+type sqlStore struct {
+	Table      string   `msgpack:"table"`
+	KeyColumns []string `msgpack:"key_columns"`
+	Statement  string   `msgpack:"statement"`
+	Dialect    string   `msgpack:"dialect"`
+	BatchSize  int      `msgpack:"batch_size"`
+}
+
+func (s *sqlStore) options() *format.SQLOptions {
+	if s == nil {
+		return nil
+	}
+
+	return &format.SQLOptions{
+		Table:      s.Table,
+		KeyColumns: s.KeyColumns,
+		Statement:  format.SQLStatement(s.Statement),
+		Dialect:    s.Dialect,
+		BatchSize:  s.BatchSize,
+	}
+}
+
+// markupStore are options of the markup store formats sent by lua (see format.MarkupOptions).
+// Columns are aligned by type unless align is false.
+type markupStore struct {
+	Align        *bool `msgpack:"align"`
+	MaxCellWidth int   `msgpack:"max_cell_width"`
+}
+
+func (s *markupStore) options() *format.MarkupOptions {
+	if s == nil {
+		return &format.MarkupOptions{AlignByType: true}
+	}
+
+	return &format.MarkupOptions{
+		AlignByType:  s.Align == nil || *s.Align,
+		MaxCellWidth: s.MaxCellWidth,
+	}
+}
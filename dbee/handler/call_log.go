@@ -1,71 +1,416 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 )
 
-func (h *Handler) storeCallLog() error {
-	store := make(map[core.ConnectionID][]*core.Call)
+// legacyCallLogFileName is the location of the call log before the history directory was configurable.
+// Call logs in the old format are only imported if the history directory doesn't contain a journal yet.
+const legacyCallLogFileName = "/tmp/dbee-calllog.json"
 
-	for connID := range h.lookupConnection {
-		calls, err := h.ConnectionGetCalls(connID)
-		if err != nil || len(calls) < 1 {
-			continue
+// callLogEntry is a single record of the call log journal. Every state change of a call
+// is appended as a new entry, so the last entry of a call holds its current state.
+type callLogEntry struct {
+	ID           core.CallID       `json:"id"`
+	ConnectionID core.ConnectionID `json:"conn_id"`
+	// Call is the marshaled call, it's empty for deleted calls.
+	Call    json.RawMessage `json:"call,omitempty"`
+	Deleted bool            `json:"deleted,omitempty"`
+}
+
+func (h *Handler) callLogFileName() string {
+	return filepath.Join(h.historyDir, "calllog.jsonl")
+}
+
+func (h *Handler) callLogLockFileName() string {
+	return filepath.Join(h.historyDir, "calllog.lock")
+}
+
+// withCallLogLock runs fn while holding the lock of the call log.
+// The lock is shared by all dbee processes which use the same history directory.
+func (h *Handler) withCallLogLock(fn func() error) error {
+	// history may contain sensitive data, so it's only readable by the current user
+	// (permissions of a directory which already exists are tightened as well)
+	err := os.MkdirAll(h.historyDir, 0o700)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	err = os.Chmod(h.historyDir, 0o700)
+	if err != nil {
+		return fmt.Errorf("os.Chmod: %w", err)
+	}
+
+	lock, err := os.OpenFile(h.callLogLockFileName(), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	defer lock.Close()
+
+	err = lockFile(lock)
+	if err != nil {
+		return fmt.Errorf("lockFile: %w", err)
+	}
+	defer func() { _ = unlockFile(lock) }()
+
+	return fn()
+}
+
+// recordCall appends the current state of the call to the call log.
+func (h *Handler) recordCall(connID core.ConnectionID, call *core.Call) {
+	b, err := json.Marshal(call)
+	if err != nil {
+		h.log.Infof("json.Marshal: %s", err)
+		return
+	}
+
+	err = h.appendCallLog(callLogEntry{
+		ID:           call.GetID(),
+		ConnectionID: connID,
+		Call:         b,
+	})
+	if err != nil {
+		h.log.Infof("h.appendCallLog: %s", err)
+	}
+}
+
+// appendCallLog appends entries to the end of the call log.
+// Nothing is recorded until the history directory is configured.
+func (h *Handler) appendCallLog(entries ...callLogEntry) error {
+	if h.historyDir == "" || len(entries) < 1 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
 		}
-		store[connID] = calls
+		buf.Write(b)
+		buf.WriteByte('\n')
 	}
 
-	b, err := json.MarshalIndent(store, "", "  ")
+	return h.withCallLogLock(func() error {
+		file, err := os.OpenFile(h.callLogFileName(), os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("os.OpenFile: %w", err)
+		}
+		defer file.Close()
+
+		// start on a new line if a process crashed in the middle of writing an entry
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("file.Stat: %w", err)
+		}
+		if info.Size() > 0 {
+			last := make([]byte, 1)
+			_, err = file.ReadAt(last, info.Size()-1)
+			if err != nil {
+				return fmt.Errorf("file.ReadAt: %w", err)
+			}
+			if last[0] != '\n' {
+				_, err = file.Write([]byte{'\n'})
+				if err != nil {
+					return fmt.Errorf("file.Write: %w", err)
+				}
+			}
+		}
+
+		_, err = file.Write(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("file.Write: %w", err)
+		}
+
+		// entries have to survive a crash, or deleted calls could come back
+		err = file.Sync()
+		if err != nil {
+			return fmt.Errorf("file.Sync: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// syncCallLog merges entries of the call log by call ID and brings lookups up to date with it.
+// Calls recorded by other processes which share the history directory are added or updated
+// and calls deleted by them are removed, calls of this process are left as they are.
+// The call log is compacted afterwards, so that it only holds the last entry of every call.
+// Events about changed calls are only sent if notify is set (e.g. not on startup).
+func (h *Handler) syncCallLog(notify bool) error {
+	var entries []callLogEntry
+	err := h.withCallLogLock(func() error {
+		all, err := readCallLog(h.callLogFileName())
+		imported := false
+		if errors.Is(err, os.ErrNotExist) {
+			all, err = h.readLegacyCallLog()
+			imported = len(all) > 0
+		}
+		if err != nil {
+			return err
+		}
+
+		entries = mergeCallLog(all, core.ArchiveExists)
+		if !imported && len(entries) == len(all) {
+			return nil
+		}
+
+		return writeCallLog(h.callLogFileName(), entries)
+	})
 	if err != nil {
-		return fmt.Errorf("json.MarshalIndent: %w", err)
+		return err
+	}
+
+	type deleted struct {
+		connID core.ConnectionID
+		callID core.CallID
 	}
+	var changedCalls []*core.Call
+	var deletedCalls []deleted
+
+	h.callsMutex.Lock()
+
+	logged := make(map[core.CallID]struct{}, len(entries))
+	for _, entry := range entries {
+		logged[entry.ID] = struct{}{}
+
+		recorded, restored := h.restoredCalls[entry.ID]
+		_, known := h.lookupCall[entry.ID]
 
-	file, err := os.Create(callLogFileName)
+		switch {
+		case entry.Deleted:
+			if restored {
+				deletedCalls = append(deletedCalls, deleted{connID: entry.ConnectionID, callID: entry.ID})
+			}
+			continue
+		case known && !restored:
+			// call of this process
+			continue
+		case restored && recorded == string(entry.Call):
+			continue
+		}
+
+		c := new(core.Call)
+		err := json.Unmarshal(entry.Call, c)
+		if err != nil {
+			h.log.Infof("json.Unmarshal: %s", err)
+			continue
+		}
+
+		if restored {
+			h.removeCallLocked(entry.ConnectionID, entry.ID)
+		}
+		h.lookupCall[c.GetID()] = c
+		h.lookupConnectionCall[entry.ConnectionID] = append(h.lookupConnectionCall[entry.ConnectionID], c.GetID())
+		h.callIndex.add(entry.ConnectionID, c)
+		h.restoredCalls[c.GetID()] = string(entry.Call)
+
+		changedCalls = append(changedCalls, c)
+	}
+
+	// deletions are only kept in the log until the archive is removed
+	for id := range h.restoredCalls {
+		if _, ok := logged[id]; !ok {
+			deletedCalls = append(deletedCalls, deleted{connID: h.lookupCallConnection(id), callID: id})
+		}
+	}
+	for _, d := range deletedCalls {
+		h.removeCallLocked(d.connID, d.callID)
+		delete(h.restoredCalls, d.callID)
+	}
+
+	h.callsMutex.Unlock()
+
+	if len(deletedCalls) > 0 {
+		ids := make([]core.CallID, len(deletedCalls))
+		for i, d := range deletedCalls {
+			ids[i] = d.callID
+		}
+		err := h.scratch.ForgetCalls(ids...)
+		if err != nil {
+			h.log.Infof("h.scratch.ForgetCalls: %s", err)
+		}
+	}
+
+	if !notify {
+		return nil
+	}
+	for _, c := range changedCalls {
+		h.events.CallStateChanged(c)
+	}
+	for _, d := range deletedCalls {
+		h.events.CallDeleted(d.connID, d.callID)
+	}
+
+	return nil
+}
+
+// removeCallLocked removes the call from lookups. It expects callsMutex to be held.
+func (h *Handler) removeCallLocked(connID core.ConnectionID, id core.CallID) {
+	delete(h.lookupCall, id)
+	h.lookupConnectionCall[connID] = slices.DeleteFunc(h.lookupConnectionCall[connID], func(callID core.CallID) bool {
+		return callID == id
+	})
+	h.callIndex.remove([]core.CallID{id})
+}
+
+// lookupCallConnection returns the ID of the connection the call belongs to in lookups.
+// It expects callsMutex to be held.
+func (h *Handler) lookupCallConnection(id core.CallID) core.ConnectionID {
+	for connID, calls := range h.lookupConnectionCall {
+		if slices.Contains(calls, id) {
+			return connID
+		}
+	}
+	return ""
+}
+
+// mergeCallLog de-duplicates entries by call ID, keeping the last entry of every call
+// in order of their first appearance. Deleted calls are only represented by their deletion
+// entry, so that they stay deleted even if another process records their state afterwards.
+// Deletion entries are dropped once the archive of the call is gone (hasArchive returns false),
+// because the call can't be recorded anymore at that point.
+func mergeCallLog(entries []callLogEntry, hasArchive func(core.CallID) bool) []callLogEntry {
+	var order []core.CallID
+	last := make(map[core.CallID]callLogEntry)
+	for _, entry := range entries {
+		previous, ok := last[entry.ID]
+		if !ok {
+			order = append(order, entry.ID)
+		}
+		if !previous.Deleted {
+			last[entry.ID] = entry
+		}
+	}
+
+	merged := make([]callLogEntry, 0, len(order))
+	for _, id := range order {
+		entry := last[id]
+		if entry.Deleted && !hasArchive(id) {
+			continue
+		}
+		merged = append(merged, entry)
+	}
+
+	return merged
+}
+
+func readCallLog(path string) ([]callLogEntry, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("os.Create: %s", err)
+		return nil, fmt.Errorf("os.Open: %w", err)
 	}
 	defer file.Close()
 
-	_, err = file.Write(b)
+	var entries []callLogEntry
+
+	scanner := bufio.NewScanner(file)
+	// calls hold the whole query, so lines can be long
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var entry callLogEntry
+		// a line can only be incomplete if a process crashed while writing it
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.ID == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err: %w", err)
+	}
+
+	return entries, nil
+}
+
+// writeCallLog replaces the call log with entries. The new log is written to
+// a temporary file first, so that the log is never left incomplete.
+func writeCallLog(path string, entries []callLogEntry) error {
+	tmp := path + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		err = encoder.Encode(entry)
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("encoder.Encode: %w", err)
+		}
+	}
+
+	err = writer.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
 	if err != nil {
+		file.Close()
 		return fmt.Errorf("file.Write: %w", err)
 	}
 
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("file.Close: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+
 	return nil
 }
 
-func (h *Handler) restoreCallLog() error {
-	file, err := os.Open(callLogFileName)
+// readLegacyCallLog reads entries from the call log in the format which was written on exit
+// (a map of connection IDs to lists of calls).
+func (h *Handler) readLegacyCallLog() ([]callLogEntry, error) {
+	file, err := os.Open(filepath.Join(h.historyDir, "calllog.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(legacyCallLogFileName)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("os.Open: %w", err)
+		return nil, fmt.Errorf("os.Open: %w", err)
 	}
 	defer file.Close()
 
-	decoder := json.NewDecoder(file)
+	var store map[core.ConnectionID][]json.RawMessage
 
-	var store map[core.ConnectionID][]*core.Call
-
-	err = decoder.Decode(&store)
+	err = json.NewDecoder(file).Decode(&store)
 	if err != nil {
-		return fmt.Errorf("decoder.Decode: %w", err)
+		return nil, fmt.Errorf("decoder.Decode: %w", err)
 	}
 
+	var entries []callLogEntry
 	for connID, calls := range store {
-		callIDs := make([]core.CallID, len(calls))
+		for _, call := range calls {
+			var alias struct {
+				ID core.CallID `json:"id"`
+			}
+			if err := json.Unmarshal(call, &alias); err != nil || alias.ID == "" {
+				continue
+			}
 
-		// fill call lookup
-		for i, c := range calls {
-			h.lookupCall[c.GetID()] = c
-			callIDs[i] = c.GetID()
+			entries = append(entries, callLogEntry{
+				ID:           alias.ID,
+				ConnectionID: connID,
+				Call:         call,
+			})
 		}
-
-		// add to conn-call lookup
-		h.lookupConnectionCall[connID] = append(h.lookupConnectionCall[connID], callIDs...)
 	}
 
-	return nil
+	return entries, nil
 }
@@ -0,0 +1,15 @@
+//go:build !windows && (!unix || solaris || aix)
+
+package handler
+
+import "os"
+
+// lockFile is a no-op on platforms without flock - processes sharing
+// the history directory might interleave their writes there.
+func lockFile(*os.File) error {
+	return nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
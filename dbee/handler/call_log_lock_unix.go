@@ -0,0 +1,17 @@
+//go:build unix && !solaris && !aix
+
+package handler
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile acquires an exclusive lock of the file, waiting for other processes to release it.
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+// testCallEntry returns a call log entry of the call with the query.
+func testCallEntry(id core.CallID, connID core.ConnectionID, query string) callLogEntry {
+	return callLogEntry{
+		ID:           id,
+		ConnectionID: connID,
+		Call:         json.RawMessage(fmt.Sprintf(`{"id":%q,"query":%q,"state":"archived"}`, id, query)),
+	}
+}
+
+// testDeletedEntry returns a call log entry of the deleted call.
+func testDeletedEntry(id core.CallID, connID core.ConnectionID) callLogEntry {
+	return callLogEntry{ID: id, ConnectionID: connID, Deleted: true}
+}
+
+func TestMergeCallLog(t *testing.T) {
+	testCases := []struct {
+		name     string
+		entries  []callLogEntry
+		archives []core.CallID
+		expected []callLogEntry
+	}{
+		{
+			name:     "empty",
+			entries:  nil,
+			expected: []callLogEntry{},
+		},
+		{
+			name: "last entry of every call in order of first appearance",
+			entries: []callLogEntry{
+				testCallEntry("a", "conn", "1"),
+				testCallEntry("b", "conn", "1"),
+				testCallEntry("a", "conn", "2"),
+				testCallEntry("c", "other", "1"),
+				testCallEntry("b", "conn", "2"),
+			},
+			expected: []callLogEntry{
+				testCallEntry("a", "conn", "2"),
+				testCallEntry("b", "conn", "2"),
+				testCallEntry("c", "other", "1"),
+			},
+		},
+		{
+			name: "deleted call stays deleted while its archive exists",
+			entries: []callLogEntry{
+				testCallEntry("a", "conn", "1"),
+				testDeletedEntry("a", "conn"),
+				testCallEntry("a", "conn", "2"),
+				testCallEntry("b", "conn", "1"),
+			},
+			archives: []core.CallID{"a"},
+			expected: []callLogEntry{
+				testDeletedEntry("a", "conn"),
+				testCallEntry("b", "conn", "1"),
+			},
+		},
+		{
+			name: "deletion is dropped once the archive is gone",
+			entries: []callLogEntry{
+				testCallEntry("a", "conn", "1"),
+				testDeletedEntry("a", "conn"),
+				testCallEntry("a", "conn", "2"),
+				testCallEntry("b", "conn", "1"),
+			},
+			expected: []callLogEntry{
+				testCallEntry("b", "conn", "1"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hasArchive := func(id core.CallID) bool {
+				for _, archive := range tc.archives {
+					if archive == id {
+						return true
+					}
+				}
+				return false
+			}
+
+			require.Equal(t, tc.expected, mergeCallLog(tc.entries, hasArchive))
+		})
+	}
+}
+
+func TestReadCallLog(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []callLogEntry
+	}{
+		{
+			name:     "empty",
+			content:  "",
+			expected: nil,
+		},
+		{
+			name: "entries",
+			content: `{"id":"a","conn_id":"conn","call":{"id":"a","query":"1","state":"archived"}}
+{"id":"a","conn_id":"conn","deleted":true}
+`,
+			expected: []callLogEntry{
+				testCallEntry("a", "conn", "1"),
+				testDeletedEntry("a", "conn"),
+			},
+		},
+		{
+			name: "incomplete and invalid lines are skipped",
+			content: `{"id":"a","conn_id":"conn","call":{"id":"a","query":"1","state":"archived"}}
+{"id":"b","conn_id":"conn","call":{"id":"b","qu
+{"conn_id":"conn","deleted":true}
+
+{"id":"c","conn_id":"conn","deleted":true}
+`,
+			expected: []callLogEntry{
+				testCallEntry("a", "conn", "1"),
+				testDeletedEntry("c", "conn"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			path := filepath.Join(t.TempDir(), "calllog.jsonl")
+			r.NoError(os.WriteFile(path, []byte(tc.content), 0o600))
+
+			entries, err := readCallLog(path)
+			r.NoError(err)
+			r.Equal(tc.expected, entries)
+		})
+	}
+
+	_, err := readCallLog(filepath.Join(t.TempDir(), "missing.jsonl"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWriteCallLog(t *testing.T) {
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "calllog.jsonl")
+	entries := []callLogEntry{
+		testCallEntry("a", "conn", "1"),
+		testDeletedEntry("b", "conn"),
+	}
+
+	// existing log is replaced
+	r.NoError(os.WriteFile(path, []byte("garbage\n"), 0o600))
+	r.NoError(writeCallLog(path, entries))
+
+	actual, err := readCallLog(path)
+	r.NoError(err)
+	r.Equal(entries, actual)
+
+	_, err = os.Stat(path + ".tmp")
+	r.True(os.IsNotExist(err))
+}
+
+func TestHandler_AppendCallLog(t *testing.T) {
+	r := require.New(t)
+
+	h := newTestHandler(t)
+
+	// process crashed in the middle of writing an entry
+	r.NoError(os.WriteFile(h.callLogFileName(), []byte(`{"id":"a","conn_id":"conn","call":{"id":"a","qu`), 0o600))
+
+	r.NoError(h.appendCallLog(testCallEntry("b", "conn", "1"), testDeletedEntry("c", "conn")))
+
+	entries, err := readCallLog(h.callLogFileName())
+	r.NoError(err)
+	r.Equal([]callLogEntry{testCallEntry("b", "conn", "1"), testDeletedEntry("c", "conn")}, entries)
+}
+
+func TestHandler_SyncCallLog(t *testing.T) {
+	r := require.New(t)
+
+	// two processes share the history directory
+	h := newTestHandler(t)
+	other := New(nil, nil)
+	other.historyDir = h.historyDir
+
+	lines := func() int {
+		entries, err := readCallLog(h.callLogFileName())
+		r.NoError(err)
+		return len(entries)
+	}
+	query := func(id core.CallID) string {
+		call, ok := h.getCall(id)
+		r.True(ok, id)
+		return call.GetQuery()
+	}
+
+	// calls recorded by the other process are added
+	r.NoError(other.appendCallLog(testCallEntry("a", "conn", "1"), testCallEntry("b", "conn", "1")))
+	r.NoError(h.syncCallLog(true))
+	r.Equal("1", query("a"))
+	r.Equal("1", query("b"))
+	r.ElementsMatch([]core.CallID{"a", "b"}, h.lookupConnectionCall["conn"])
+
+	// and updated, the log is compacted afterwards
+	r.NoError(other.appendCallLog(testCallEntry("a", "conn", "2")))
+	r.Equal(3, lines())
+	r.NoError(h.syncCallLog(true))
+	r.Equal("2", query("a"))
+	r.ElementsMatch([]core.CallID{"a", "b"}, h.lookupConnectionCall["conn"])
+	r.Equal(2, lines())
+
+	// calls of this process aren't replaced by the log
+	addTestCall(t, h, "conn", "own", h.lookupCall["a"].GetTimestamp())
+	r.NoError(other.appendCallLog(testCallEntry("own", "conn", "other")))
+	r.NoError(h.syncCallLog(true))
+	r.Equal("_", query("own"))
+
+	// calls deleted by the other process are removed, deletion is dropped from the log
+	// since the calls have no archives
+	r.NoError(other.appendCallLog(testDeletedEntry("b", "conn")))
+	r.NoError(h.syncCallLog(true))
+	_, ok := h.getCall("b")
+	r.False(ok)
+	r.ElementsMatch([]core.CallID{"a", "own"}, h.lookupConnectionCall["conn"])
+	r.Equal(2, lines())
+
+	// calls deleted by this process are not restored
+	r.Empty(h.deleteCalls([]core.CallID{"a"}))
+	r.NoError(h.syncCallLog(true))
+	_, ok = h.getCall("a")
+	r.False(ok)
+	r.Equal(1, lines())
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+// CallSearch filters, sorts and paginates calls of all connections.
+// Zero values of filters don't filter anything.
+type CallSearch struct {
+	// Query is a case insensitive substring of the query,
+	// or a regular expression if Regex is set.
+	Query string
+	Regex bool
+	// States of calls, any state matches if empty.
+	States []core.CallState
+	// ConnectionIDs of calls, any connection matches if empty.
+	ConnectionIDs []core.ConnectionID
+	// Since and Until limit the timestamp of calls (both inclusive).
+	Since time.Time
+	Until time.Time
+	// MinDuration and MaxDuration limit the time taken by calls (both inclusive).
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	// HasError matches calls with (or without) an error if set.
+	HasError *bool
+
+	// SortBy is one of "timestamp" (default), "duration", "query", "state", "connection" or "rows".
+	SortBy string
+	// Ascending sorts calls in ascending order instead of descending.
+	Ascending bool
+	// Offset is the number of matching calls to skip and Limit the maximum number
+	// of calls to return (no limit if not positive).
+	Offset int
+	Limit  int
+}
+
+// CallSearchResult is a single page of calls which matched the search.
+type CallSearchResult struct {
+	Calls []*core.Call
+	// Total is the number of all calls which matched the search.
+	Total int
+}
+
+// callIndexEntry holds the immutable properties of a call which are searched by.
+type callIndexEntry struct {
+	call      *core.Call
+	connID    core.ConnectionID
+	timestamp time.Time
+	// query in lower case, for case insensitive matching
+	query string
+}
+
+// callIndex indexes calls of all connections by their timestamp.
+// It isn't safe for concurrent use, so it's guarded by the calls mutex of the handler.
+type callIndex struct {
+	// entries sorted by timestamp
+	entries []*callIndexEntry
+}
+
+func newCallIndex() *callIndex {
+	return &callIndex{}
+}
+
+// add adds the call to index. Calls are usually added in order, so the
+// position is searched from the end.
+func (ci *callIndex) add(connID core.ConnectionID, call *core.Call) {
+	entry := &callIndexEntry{
+		call:      call,
+		connID:    connID,
+		timestamp: call.GetTimestamp(),
+		query:     strings.ToLower(call.GetQuery()),
+	}
+
+	i := len(ci.entries)
+	for i > 0 && ci.entries[i-1].timestamp.After(entry.timestamp) {
+		i--
+	}
+	ci.entries = slices.Insert(ci.entries, i, entry)
+}
+
+// remove removes calls from index.
+func (ci *callIndex) remove(ids []core.CallID) {
+	ci.entries = slices.DeleteFunc(ci.entries, func(entry *callIndexEntry) bool {
+		return slices.Contains(ids, entry.call.GetID())
+	})
+}
+
+// search returns calls which match the search. Only calls in the time range are inspected.
+func (ci *callIndex) search(search *CallSearch) (*CallSearchResult, error) {
+	match, err := search.matcher()
+	if err != nil {
+		return nil, err
+	}
+	compare, err := search.comparator()
+	if err != nil {
+		return nil, err
+	}
+
+	from := 0
+	if !search.Since.IsZero() {
+		from, _ = slices.BinarySearchFunc(ci.entries, search.Since, func(entry *callIndexEntry, t time.Time) int {
+			return entry.timestamp.Compare(t)
+		})
+	}
+	to := len(ci.entries)
+	if !search.Until.IsZero() {
+		// first entry after the end of range
+		to, _ = slices.BinarySearchFunc(ci.entries, search.Until, func(entry *callIndexEntry, t time.Time) int {
+			if entry.timestamp.After(t) {
+				return 1
+			}
+			return -1
+		})
+	}
+
+	var matched []*callIndexEntry
+	for _, entry := range ci.entries[from:max(from, to)] {
+		if match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+
+	// entries are already sorted by timestamp, so the order of equal values is kept
+	slices.SortStableFunc(matched, func(a, b *callIndexEntry) int {
+		if search.Ascending {
+			return compare(a, b)
+		}
+		return compare(b, a)
+	})
+
+	total := len(matched)
+	start := min(max(search.Offset, 0), total)
+	end := total
+	if search.Limit > 0 {
+		end = min(start+search.Limit, total)
+	}
+
+	calls := make([]*core.Call, 0, end-start)
+	for _, entry := range matched[start:end] {
+		calls = append(calls, entry.call)
+	}
+
+	return &CallSearchResult{
+		Calls: calls,
+		Total: total,
+	}, nil
+}
+
+// matcher returns a function which reports if the entry matches all filters of the search.
+func (s *CallSearch) matcher() (func(*callIndexEntry) bool, error) {
+	matchQuery := func(query string) bool { return true }
+	if s.Regex {
+		re, err := regexp.Compile("(?i)" + s.Query)
+		if err != nil {
+			return nil, fmt.Errorf("regexp.Compile: %w", err)
+		}
+		matchQuery = re.MatchString
+	} else if s.Query != "" {
+		sub := strings.ToLower(s.Query)
+		matchQuery = func(query string) bool { return strings.Contains(query, sub) }
+	}
+
+	return func(entry *callIndexEntry) bool {
+		if len(s.ConnectionIDs) > 0 && !slices.Contains(s.ConnectionIDs, entry.connID) {
+			return false
+		}
+		if len(s.States) > 0 && !slices.Contains(s.States, entry.call.GetState()) {
+			return false
+		}
+
+		duration := entry.call.GetTimeTaken()
+		if (s.MinDuration > 0 && duration < s.MinDuration) || (s.MaxDuration > 0 && duration > s.MaxDuration) {
+			return false
+		}
+		if s.HasError != nil && (entry.call.Err() != nil) != *s.HasError {
+			return false
+		}
+
+		return matchQuery(entry.query)
+	}, nil
+}
+
+// comparator returns a function which compares entries in ascending order of the sort field.
+func (s *CallSearch) comparator() (func(a, b *callIndexEntry) int, error) {
+	switch s.SortBy {
+	case "", "timestamp":
+		return func(a, b *callIndexEntry) int {
+			return a.timestamp.Compare(b.timestamp)
+		}, nil
+	case "duration":
+		return func(a, b *callIndexEntry) int {
+			return cmp.Compare(a.call.GetTimeTaken(), b.call.GetTimeTaken())
+		}, nil
+	case "query":
+		return func(a, b *callIndexEntry) int {
+			return cmp.Compare(a.query, b.query)
+		}, nil
+	case "state":
+		return func(a, b *callIndexEntry) int {
+			return cmp.Compare(a.call.GetState().String(), b.call.GetState().String())
+		}, nil
+	case "connection":
+		return func(a, b *callIndexEntry) int {
+			return cmp.Or(
+				cmp.Compare(a.call.GetConnectionName(), b.call.GetConnectionName()),
+				cmp.Compare(a.connID, b.connID),
+			)
+		}, nil
+	case "rows":
+		return func(a, b *callIndexEntry) int {
+			return cmp.Compare(a.call.GetRowCount(), b.call.GetRowCount())
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown sort field: %q", s.SortBy)
+	}
+}
+
+// SearchCalls searches calls of all connections, including calls restored from history.
+func (h *Handler) SearchCalls(search *CallSearch) (*CallSearchResult, error) {
+	if search == nil {
+		search = &CallSearch{}
+	}
+
+	h.callsMutex.RLock()
+	defer h.callsMutex.RUnlock()
+
+	result, err := h.callIndex.search(search)
+	if err != nil {
+		return nil, fmt.Errorf("h.callIndex.search: %w", err)
+	}
+
+	return result, nil
+}
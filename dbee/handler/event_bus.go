@@ -15,6 +15,11 @@ type eventBus struct {
 }
 
 func (eb *eventBus) callLua(event string, data string) {
+	// events are only delivered when attached to neovim
+	if eb.vim == nil {
+		return
+	}
+
 	err := eb.vim.ExecLua(fmt.Sprintf(`require("dbee.handler.__events").trigger(%q, %s)`, event, data), nil)
 	if err != nil {
 		eb.log.Infof("eb.vim.ExecLua: %s", err)
@@ -27,6 +32,28 @@ func (eb *eventBus) CallStateChanged(call *core.Call) {
 		errMsg = fmt.Sprintf("[[%s]]", err.Error())
 	}
 
+	queryErr := "nil"
+	if qe := core.AsQueryError(call.Err()); qe != nil {
+		queryErr = fmt.Sprintf(
+			"{ severity = %q, sqlstate = %q, code = %q, message = %q, detail = %q, hint = %q, position = %d, line = %d, column = %d }",
+			qe.Severity, qe.SQLState, qe.Code, qe.Message, qe.Detail, qe.Hint, qe.Position, qe.Line, qe.Column)
+	}
+
+	estimate := "nil"
+	if est := call.GetEstimate(); est != nil {
+		estimate = fmt.Sprintf("{ bytes = %d, rows = %d, detail = %q }", est.Bytes, est.Rows, est.Detail)
+	}
+
+	rowsAffected := "nil"
+	if n := call.GetRowsAffected(); n >= 0 {
+		rowsAffected = fmt.Sprint(n)
+	}
+
+	parentID := "nil"
+	if id := call.GetParentID(); id != "" {
+		parentID = fmt.Sprintf("%q", id)
+	}
+
 	data := fmt.Sprintf(`{
 		call = {
 			id = %q,
@@ -35,17 +62,78 @@ func (eb *eventBus) CallStateChanged(call *core.Call) {
 			time_taken_us = %d,
 			timestamp_us = %d,
 			error = %s,
+			query_error = %s,
+			result_sets = %d,
+			estimate = %s,
+			conn_id = %q,
+			conn_name = %q,
+			kind = %q,
+			database = %q,
+			row_count = %d,
+			rows_affected = %s,
+			archive_size = %d,
+			query_id = %q,
+			parent_call_id = %s,
 		},
 	}`, call.GetID(),
 		call.GetQuery(),
 		call.GetState().String(),
 		call.GetTimeTaken().Microseconds(),
 		call.GetTimestamp().UnixMicro(),
-		errMsg)
+		errMsg,
+		queryErr,
+		call.GetResultSetCount(),
+		estimate,
+		call.GetConnectionID(),
+		call.GetConnectionName(),
+		call.GetKind(),
+		call.GetDatabase(),
+		call.GetRowCount(),
+		rowsAffected,
+		call.ArchiveSize(),
+		call.GetQueryID(),
+		parentID)
 
 	eb.callLua("call_state_changed", data)
 }
 
+// CallProgress is called periodically while a call is executing or retrieving.
+// Sends the number of retrieved rows, elapsed time and any progress known by the driver.
+func (eb *eventBus) CallProgress(call *core.Call, progress core.CallProgress) {
+	data := fmt.Sprintf(`{
+		call_id = %q,
+		rows = %d,
+		estimated_rows = %d,
+		rows_read = %d,
+		bytes_read = %d,
+		elapsed_us = %d,
+	}`, call.GetID(),
+		progress.Rows,
+		progress.EstimatedRows,
+		progress.RowsRead,
+		progress.BytesRead,
+		progress.Elapsed.Microseconds())
+
+	eb.callLua("call_progress", data)
+}
+
+// CallDeleted is called when a call is removed from history.
+// Sends the call ID along with the ID of its connection to the lua event handler.
+func (eb *eventBus) CallDeleted(connID core.ConnectionID, callID core.CallID) {
+	data := fmt.Sprintf(`{
+		conn_id = %q,
+		call_id = %q,
+	}`, connID, callID)
+
+	eb.callLua("call_deleted", data)
+}
+
+// SavedQueriesChanged is called when saved queries are changed, either by the
+// handler or by editing their file.
+func (eb *eventBus) SavedQueriesChanged() {
+	eb.callLua("saved_queries_changed", "{}")
+}
+
 func (eb *eventBus) CurrentConnectionChanged(id core.ConnectionID) {
 	data := fmt.Sprintf(`{
 		conn_id = %q,
@@ -75,3 +163,14 @@ func (eb *eventBus) ConnectionStateChanged(id core.ConnectionID, connected bool)
 
 	eb.callLua("connection_state_changed", data)
 }
+
+// TransactionStateChanged is called when a transaction of a connection is opened or finished.
+// Sends the connection ID and whether the transaction is active to the lua event handler.
+func (eb *eventBus) TransactionStateChanged(id core.ConnectionID, active bool) {
+	data := fmt.Sprintf(`{
+		conn_id = %q,
+		active = %t,
+	}`, id, active)
+
+	eb.callLua("transaction_state_changed", data)
+}
@@ -1,25 +1,40 @@
 package handler
 
 import (
+	"fmt"
+	"io"
+	"reflect"
+
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
 
 	"github.com/kndndrj/nvim-dbee/dbee/core"
 )
 
-var _ core.Formatter = (*Table)(nil)
+var (
+	_ core.Formatter          = (*Table)(nil)
+	_ core.MeasuringFormatter = (*Table)(nil)
+)
 
-type Table struct{}
+type Table struct {
+	// state of the stream output: widths and alignment of columns (the first one is the index column)
+	// measured over all rows, so that separately rendered batches are aligned
+	header     core.Header
+	widths     []int
+	nonNumeric []bool
+	written    bool
+}
 
 func newTable() *Table {
 	return &Table{}
 }
 
 func (tf *Table) Format(header core.Header, rows []core.Row, opts *core.FormatterOptions) ([]byte, error) {
-	tableHeaders := []any{""}
-	for _, k := range header {
-		tableHeaders = append(tableHeaders, k)
-	}
+	return []byte(tf.render(header, true, rows, opts, nil)), nil
+}
+
+// render renders the rows as a table. Columns are at least as wide as minimum widths.
+func (tf *Table) render(header core.Header, withHeader bool, rows []core.Row, opts *core.FormatterOptions, widths []int) string {
 	index := opts.ChunkStart
 
 	var tableRows []table.Row
@@ -30,7 +45,13 @@ func (tf *Table) Format(header core.Header, rows []core.Row, opts *core.Formatte
 	}
 
 	t := table.NewWriter()
-	t.AppendHeader(table.Row(tableHeaders))
+	if withHeader {
+		tableHeaders := []any{""}
+		for _, k := range header {
+			tableHeaders = append(tableHeaders, k)
+		}
+		t.AppendHeader(table.Row(tableHeaders))
+	}
 	t.AppendRows(tableRows)
 	t.AppendSeparator()
 	t.SetStyle(table.StyleLight)
@@ -41,7 +62,96 @@ func (tf *Table) Format(header core.Header, rows []core.Row, opts *core.Formatte
 	}
 	t.Style().Options.DrawBorder = false
 	t.SuppressTrailingSpaces()
-	render := t.Render()
 
-	return []byte(render), nil
+	// numeric columns are aligned right, as the table would do it if it saw all rows
+	configs := make([]table.ColumnConfig, 0, len(widths))
+	for i, width := range widths {
+		align := text.AlignRight
+		if tf.nonNumeric[i] {
+			align = text.AlignLeft
+		}
+		configs = append(configs, table.ColumnConfig{Number: i + 1, WidthMin: width, Align: align, AlignHeader: align})
+	}
+	t.SetColumnConfigs(configs)
+
+	return t.Render()
+}
+
+// MeasureRows updates widths of columns with widths of cells of the rows.
+func (tf *Table) MeasureRows(rows []core.Row, opts *core.FormatterOptions) {
+	index := opts.ChunkStart
+	for _, row := range rows {
+		index++
+		tf.measure(0, index)
+		for i, cell := range row {
+			tf.measure(i+1, cell)
+		}
+	}
+}
+
+func (tf *Table) measure(column int, value any) {
+	tf.grow(column + 1)
+	tf.widths[column] = max(tf.widths[column], text.LongestLineLen(fmt.Sprint(value)))
+	tf.nonNumeric[column] = tf.nonNumeric[column] || !isNumber(value)
+}
+
+// grow makes sure widths and alignment are known for the number of columns.
+func (tf *Table) grow(columns int) {
+	for len(tf.widths) < columns {
+		tf.widths = append(tf.widths, 0)
+		tf.nonNumeric = append(tf.nonNumeric, false)
+	}
+}
+
+// isNumber reports if the value is of a numeric type.
+func isNumber(value any) bool {
+	if value == nil {
+		return false
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// WriteHeader keeps the header, which is rendered with the first batch of rows.
+func (tf *Table) WriteHeader(_ io.Writer, header core.Header, _ *core.FormatterOptions) error {
+	tf.header = header
+	tf.written = false
+	// header names only count to widths
+	tf.grow(len(header) + 1)
+	for i, name := range header {
+		tf.widths[i+1] = max(tf.widths[i+1], text.LongestLineLen(name))
+	}
+	return nil
+}
+
+func (tf *Table) WriteRows(w io.Writer, rows []core.Row, opts *core.FormatterOptions) error {
+	if len(rows) < 1 {
+		return nil
+	}
+
+	prefix := "\n"
+	if !tf.written {
+		prefix = ""
+	}
+	withHeader := !tf.written
+	tf.written = true
+
+	_, err := io.WriteString(w, prefix+tf.render(tf.header, withHeader, rows, opts, tf.widths))
+	return err
+}
+
+// WriteFooter renders just the header if there were no rows.
+func (tf *Table) WriteFooter(w io.Writer, opts *core.FormatterOptions) error {
+	if tf.written {
+		return nil
+	}
+
+	_, err := io.WriteString(w, tf.render(tf.header, true, nil, opts, tf.widths))
+	return err
 }
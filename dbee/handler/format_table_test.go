@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+func TestTable_WriteRows(t *testing.T) {
+	header := core.Header{"id", "name", "amount", "mixed"}
+	rows := []core.Row{
+		{1, "a", 1.5, 1},
+		{22, "long\nmultiline name", nil, "text"},
+		{333, nil, 100.25, 2},
+		{4, "d", -1, nil},
+		{55555, "e", 0, 3},
+	}
+
+	testCases := []struct {
+		name      string
+		rows      []core.Row
+		batchSize int
+		start     int
+	}{
+		{name: "single batch", rows: rows, batchSize: len(rows)},
+		{name: "batches", rows: rows, batchSize: 2},
+		{name: "row per batch", rows: rows, batchSize: 1},
+		{name: "batches of a later range", rows: rows, batchSize: 2, start: 998},
+		{name: "no rows", rows: nil, batchSize: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			expected, err := newTable().Format(header, tc.rows, &core.FormatterOptions{ChunkStart: tc.start})
+			r.NoError(err)
+
+			batches := func(fn func(rows []core.Row, opts *core.FormatterOptions)) {
+				for start := 0; start < len(tc.rows); start += tc.batchSize {
+					end := min(start+tc.batchSize, len(tc.rows))
+					fn(tc.rows[start:end], &core.FormatterOptions{ChunkStart: tc.start + start})
+				}
+			}
+
+			tf := newTable()
+			batches(func(rows []core.Row, opts *core.FormatterOptions) {
+				tf.MeasureRows(rows, opts)
+			})
+
+			var buf bytes.Buffer
+			r.NoError(tf.WriteHeader(&buf, header, &core.FormatterOptions{ChunkStart: tc.start}))
+
+			// measured widths don't change while rows are written
+			widths := append([]int(nil), tf.widths...)
+			batches(func(rows []core.Row, opts *core.FormatterOptions) {
+				r.NoError(tf.WriteRows(&buf, rows, opts))
+				r.Equal(widths, tf.widths)
+			})
+			r.NoError(tf.WriteFooter(&buf, &core.FormatterOptions{ChunkStart: tc.start}))
+
+			// streamed output is the same as the table formatted at once
+			r.Equal(string(expected), buf.String())
+		})
+	}
+}
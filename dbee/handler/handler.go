@@ -1,13 +1,16 @@
 package handler
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/neovim/go-client/nvim"
@@ -18,7 +21,22 @@ import (
 	"github.com/kndndrj/nvim-dbee/dbee/plugin"
 )
 
-const callLogFileName = "/tmp/dbee-calllog.json"
+// Options are the handler settings provided by plugin setup.
+type Options struct {
+	// ResultWindowSize is the number of rows a call result keeps in memory.
+	// The rest of the rows is read from the call archive.
+	ResultWindowSize int
+	// HistoryDir is the directory where the call log and archives of calls are stored.
+	// It defaults to dbee/history in the state directory of neovim, same as the lua config.
+	HistoryDir string
+	// Retention limits the size of the history.
+	Retention Retention
+	// HistoryOmitParams keeps bind params of calls out of the call log, which stores them in plain text.
+	// Calls with params can't be rerun after a restart then.
+	HistoryOmitParams bool
+	// SavedQueriesFile is the JSON or YAML file with saved queries.
+	SavedQueriesFile string
+}
 
 type Handler struct {
 	vim    *nvim.Nvim
@@ -28,8 +46,28 @@ type Handler struct {
 	lookupConnection     map[core.ConnectionID]*core.Connection
 	lookupCall           map[core.CallID]*core.Call
 	lookupConnectionCall map[core.ConnectionID][]core.CallID
-
+	callIndex            *callIndex
+	// restoredCalls are calls read from the call log (not executed by this process)
+	// with their recorded state, so that they can be updated when other processes record them
+	restoredCalls map[core.CallID]string
+	// guards call lookups and index, which are also modified by the history garbage collector
+	callsMutex sync.RWMutex
+
+	// current connection is set by calls started from any goroutine (e.g. by fan-out calls)
 	currentConnectionID core.ConnectionID
+	currentMutex        sync.RWMutex
+
+	// history is restored and garbage collected once the handler is configured
+	historyDir  string
+	retention   Retention
+	historyOnce sync.Once
+	closeCh     chan struct{}
+
+	savedQueries      *savedQueryStore
+	savedQueriesWatch sync.Once
+
+	// scratch runs SQL over results of calls, its tables of deleted calls are dropped
+	scratch *adapters.Scratch
 }
 
 func New(vim *nvim.Nvim, logger *plugin.Logger) *Handler {
@@ -44,40 +82,109 @@ func New(vim *nvim.Nvim, logger *plugin.Logger) *Handler {
 		lookupConnection:     make(map[core.ConnectionID]*core.Connection),
 		lookupCall:           make(map[core.CallID]*core.Call),
 		lookupConnectionCall: make(map[core.ConnectionID][]core.CallID),
+		callIndex:            newCallIndex(),
+		restoredCalls:        make(map[core.CallID]string),
+
+		closeCh: make(chan struct{}),
+
+		savedQueries: &savedQueryStore{},
 	}
 
-	// restore the call log concurrently
-	go func() {
-		err := h.restoreCallLog()
-		if err != nil {
-			h.log.Infof("h.restoreCallLog: %s", err)
-		}
-	}()
+	// scratch connections run SQL over results of calls of this handler
+	h.scratch = adapters.NewScratch(h.scratchCalls)
+	_ = new(adapters.Mux).AddAdapter(adapters.ScratchType, h.scratch)
 
 	return h
 }
 
+// scratchCalls returns all calls, see adapters.Scratch.
+func (h *Handler) scratchCalls() []*core.Call {
+	h.callsMutex.RLock()
+	defer h.callsMutex.RUnlock()
+
+	calls := make([]*core.Call, 0, len(h.lookupCall))
+	for _, c := range h.lookupCall {
+		calls = append(calls, c)
+	}
+	return calls
+}
+
 func (h *Handler) Close() {
+	close(h.closeCh)
+
 	// wait for unfinished calls
+	h.callsMutex.RLock()
+	calls := make([]*core.Call, 0, len(h.lookupCall))
 	for _, c := range h.lookupCall {
+		calls = append(calls, c)
+	}
+	h.callsMutex.RUnlock()
+
+	// states of calls are recorded to the call log as they change
+	for _, c := range calls {
 		select {
 		case <-c.Done():
 		case <-time.After(10 * time.Second):
 		}
 	}
 
-	// store call log
-	err := h.storeCallLog()
-	if err != nil {
-		h.log.Infof("h.storeCallLog: %s", err)
-	}
-
 	// close connections
 	for _, c := range h.lookupConnection {
 		c.Close()
 	}
 }
 
+func (h *Handler) Configure(opts *Options) error {
+	if opts == nil {
+		return fmt.Errorf("no options provided")
+	}
+
+	if opts.ResultWindowSize != 0 {
+		core.SetResultWindowSize(opts.ResultWindowSize)
+	}
+	core.SetPersistQueryParams(!opts.HistoryOmitParams)
+
+	h.callsMutex.Lock()
+	h.retention = opts.Retention
+	h.callsMutex.Unlock()
+
+	// history location can only be set once, before the call log is restored
+	h.historyOnce.Do(func() {
+		dir := opts.HistoryDir
+		if dir == "" {
+			dir = h.defaultHistoryDir()
+		}
+		h.historyDir = dir
+		core.SetArchiveDir(filepath.Join(dir, "archives"))
+
+		go h.runHistory()
+	})
+
+	if opts.SavedQueriesFile != "" {
+		h.savedQueries.setPath(opts.SavedQueriesFile)
+		h.savedQueriesWatch.Do(func() {
+			go h.watchSavedQueries()
+		})
+	}
+
+	return nil
+}
+
+// defaultHistoryDir returns the same directory as the default of the lua config
+// and falls back to the temporary directory if neovim can't tell where its state is.
+func (h *Handler) defaultHistoryDir() string {
+	if h.vim != nil {
+		var state string
+		err := h.vim.Call("stdpath", &state, "state")
+		if err == nil && state != "" {
+			return filepath.Join(state, "dbee", "history")
+		}
+		h.log.Infof("h.vim.Call: %s", err)
+	}
+
+	return filepath.Join(os.TempDir(), "dbee", "history")
+}
+
 func (h *Handler) CreateConnection(params *core.ConnectionParams) (core.ConnectionID, error) {
 	c, err := adapters.NewConnection(params)
 	if err != nil {
@@ -126,11 +233,17 @@ func (h *Handler) ConnectionDisconnect(id core.ConnectionID) error {
 		return fmt.Errorf("connection with id does not exist. id: %s", id)
 	}
 
+	// disconnecting rolls back any open transaction
+	inTransaction := c.InTransaction()
+
 	err := c.Disconnect()
 	if err != nil {
 		return fmt.Errorf("c.Disconnect: %w", err)
 	}
 
+	if inTransaction {
+		h.events.TransactionStateChanged(id, false)
+	}
 	h.events.ConnectionStateChanged(id, false)
 	return nil
 }
@@ -144,6 +257,61 @@ func (h *Handler) ConnectionIsConnected(id core.ConnectionID) (bool, error) {
 	return c.IsConnected(), nil
 }
 
+func (h *Handler) ConnectionBeginTransaction(id core.ConnectionID) error {
+	c, ok := h.lookupConnection[id]
+	if !ok {
+		return fmt.Errorf("unknown connection with id: %q", id)
+	}
+
+	err := c.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("c.BeginTransaction: %w", err)
+	}
+
+	h.events.TransactionStateChanged(id, true)
+	return nil
+}
+
+func (h *Handler) ConnectionCommitTransaction(id core.ConnectionID) error {
+	c, ok := h.lookupConnection[id]
+	if !ok {
+		return fmt.Errorf("unknown connection with id: %q", id)
+	}
+
+	err := c.CommitTransaction()
+	// failed commit finishes the transaction as well
+	h.events.TransactionStateChanged(id, c.InTransaction())
+	if err != nil {
+		return fmt.Errorf("c.CommitTransaction: %w", err)
+	}
+
+	return nil
+}
+
+func (h *Handler) ConnectionRollbackTransaction(id core.ConnectionID) error {
+	c, ok := h.lookupConnection[id]
+	if !ok {
+		return fmt.Errorf("unknown connection with id: %q", id)
+	}
+
+	err := c.RollbackTransaction()
+	h.events.TransactionStateChanged(id, c.InTransaction())
+	if err != nil {
+		return fmt.Errorf("c.RollbackTransaction: %w", err)
+	}
+
+	return nil
+}
+
+func (h *Handler) ConnectionInTransaction(id core.ConnectionID) (bool, error) {
+	c, ok := h.lookupConnection[id]
+	if !ok {
+		return false, fmt.Errorf("unknown connection with id: %q", id)
+	}
+
+	return c.InTransaction(), nil
+}
+
 func (h *Handler) GetConnections(ids []core.ConnectionID) []*core.Connection {
 	var conns []*core.Connection
 
@@ -171,7 +339,11 @@ func (h *Handler) ConnectionGetHelpers(connID core.ConnectionID, opts *core.Tabl
 }
 
 func (h *Handler) GetCurrentConnection() (*core.Connection, error) {
-	c, ok := h.lookupConnection[h.currentConnectionID]
+	h.currentMutex.RLock()
+	connID := h.currentConnectionID
+	h.currentMutex.RUnlock()
+
+	c, ok := h.lookupConnection[connID]
 	if !ok {
 		return nil, fmt.Errorf("current connection has not been set yet")
 	}
@@ -188,46 +360,287 @@ func (h *Handler) SetCurrentConnection(connID core.ConnectionID) error {
 		return fmt.Errorf("connection must be connected before setting as current")
 	}
 
-	if h.currentConnectionID == connID {
-		return nil
-	}
-
-	// update connection and trigger event
+	h.currentMutex.Lock()
+	changed := h.currentConnectionID != connID
 	h.currentConnectionID = connID
-	h.events.CurrentConnectionChanged(connID)
+	h.currentMutex.Unlock()
+
+	// trigger event
+	if changed {
+		h.events.CurrentConnectionChanged(connID)
+	}
 
 	return nil
 }
 
-func (h *Handler) ConnectionExecute(connID core.ConnectionID, query string) (*core.Call, error) {
+// ConnectionExecute executes the query on the connection.
+// Optional params (list of positional or map of named values) are passed to the driver as bind variables.
+// Non-zero call options override the defaults of the connection.
+func (h *Handler) ConnectionExecute(connID core.ConnectionID, query string, params any, opts *core.CallOptions) (*core.Call, error) {
 	c, ok := h.lookupConnection[connID]
 	if !ok {
 		return nil, fmt.Errorf("unknown connection with id: %q", connID)
 	}
 
-	call := c.Execute(query, func(state core.CallState, c *core.Call) {
-		if err := c.Err(); err != nil {
-			// Only log internal errors, not user-facing SQL errors
-			// Snowflake errors (like missing tables) should not be logged as system errors
-			errStr := err.Error()
-			if !containsUserFacingError(errStr) {
-				h.log.Errorf("cl.Err: %s", err)
+	queryParams, err := core.NewQueryParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("core.NewQueryParams: %w", err)
+	}
+
+	return h.trackCall(connID, func(onEvent func(core.CallState, *core.Call), onProgress func(core.CallProgress, *core.Call)) *core.Call {
+		return c.ExecuteWithOptions(query, queryParams, opts, onEvent, onProgress)
+	}), nil
+}
+
+// DefaultExecuteManyConcurrency is the default number of connections ExecuteMany executes the query on at once.
+const DefaultExecuteManyConcurrency = 4
+
+// ExecuteMany executes the query on all connections, on at most concurrency of them at once.
+// Every execution is a separate call. Calls which need confirmation (see core.CallOptions.ConfirmAboveBytes)
+// are rejected and reported in the summary with core.ErrFanOutNeedsConfirmation. The returned call of the first connection combines
+// their results once all of them finish (see core.CombineFanOut). Connections are connected if needed.
+func (h *Handler) ExecuteMany(connIDs []core.ConnectionID, query string, params any, opts *core.CallOptions, concurrency int) (*core.Call, error) {
+	if len(connIDs) < 1 {
+		return nil, errors.New("no connections provided")
+	}
+
+	connections := make([]*core.Connection, len(connIDs))
+	for i, id := range connIDs {
+		c, ok := h.lookupConnection[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown connection with id: %q", id)
+		}
+		connections[i] = c
+	}
+
+	queryParams, err := core.NewQueryParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("core.NewQueryParams: %w", err)
+	}
+
+	if concurrency <= 0 {
+		concurrency = DefaultExecuteManyConcurrency
+	}
+
+	combinedQuery := fmt.Sprintf("-- executed on %d connections\n%s", len(connections), query)
+
+	return h.trackCall(connIDs[0], func(onEvent func(core.CallState, *core.Call), _ func(core.CallProgress, *core.Call)) *core.Call {
+		return connections[0].ExecuteStream(core.CallKindFanOut, combinedQuery, func(ctx context.Context) (core.ResultStream, error) {
+			calls := h.executeFanOut(ctx, connections, query, queryParams, opts, concurrency)
+			return core.CombineFanOut(calls)
+		}, onEvent)
+	}), nil
+}
+
+// executeFanOut executes the query on every connection and waits for all calls to finish.
+// Once ctx is canceled, running calls are canceled and the remaining ones aren't started.
+func (h *Handler) executeFanOut(ctx context.Context, connections []*core.Connection, query string, params *core.QueryParams, opts *core.CallOptions, concurrency int) []*core.FanOutCall {
+	calls := make([]*core.FanOutCall, len(connections))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, c := range connections {
+		name := c.GetName()
+		if name == "" {
+			name = string(c.GetID())
+		}
+		calls[i] = &core.FanOutCall{Connection: name}
+
+		if err := ctx.Err(); err != nil {
+			calls[i].Err = err
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			calls[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(fc *core.FanOutCall, c *core.Connection) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if !c.IsConnected() {
+				err := h.ConnectionConnect(c.GetID())
+				if err != nil {
+					fc.Err = err
+					return
+				}
+			}
+
+			// calls of the fan-out don't change the current connection
+			fc.Call = h.registerCall(c.GetID(), func(onEvent func(core.CallState, *core.Call), onProgress func(core.CallProgress, *core.Call)) *core.Call {
+				return c.ExecuteWithOptions(query, params, opts, func(state core.CallState, call *core.Call) {
+					// nobody is asked to confirm calls of a fan-out, so they are rejected instead
+					if state == core.CallStateAwaitingConfirmation {
+						fc.Err = fmt.Errorf("%w (estimated %d bytes)", core.ErrFanOutNeedsConfirmation, call.GetEstimate().Bytes)
+						_ = call.Confirm(false)
+					}
+					onEvent(state, call)
+				}, onProgress)
+			})
+
+			select {
+			case <-fc.Call.Done():
+			case <-ctx.Done():
+				fc.Call.Cancel()
+				<-fc.Call.Done()
 			}
+		}(calls[i], c)
+	}
+
+	wg.Wait()
+	return calls
+}
+
+// CallRerun executes the query of the call again, with the same params. The new call
+// is linked to the original one. If connID is empty, the connection of the original call is used.
+// Derived calls (diffs, column stats and fan-outs) can't be rerun, their results have to be derived again.
+func (h *Handler) CallRerun(callID core.CallID, connID core.ConnectionID) (*core.Call, error) {
+	call, ok := h.getCall(callID)
+	if !ok {
+		return nil, fmt.Errorf("unknown call with id: %q", callID)
+	}
+	if kind := call.GetKind(); kind.IsDerived() {
+		return nil, fmt.Errorf("call %q is a %s: %w", callID, kind, core.ErrDerivedCallRerun)
+	}
+
+	if connID == "" {
+		connID = h.callConnectionID(call)
+	}
+	c, ok := h.lookupConnection[connID]
+	if !ok {
+		return nil, fmt.Errorf("unknown connection with id: %q", connID)
+	}
+
+	return h.trackCall(connID, func(onEvent func(core.CallState, *core.Call), onProgress func(core.CallProgress, *core.Call)) *core.Call {
+		return c.Rerun(call, nil, onEvent, onProgress)
+	}), nil
+}
+
+// CallDiff compares result sets of two calls (see core.DiffResults). The diff is a new call
+// of the connection which executed the "to" call, so it can be displayed and stored like any other call.
+func (h *Handler) CallDiff(fromID, toID core.CallID, resultSet int, opts *core.DiffOptions) (*core.Call, error) {
+	from, ok := h.getCall(fromID)
+	if !ok {
+		return nil, fmt.Errorf("unknown call with id: %q", fromID)
+	}
+	to, ok := h.getCall(toID)
+	if !ok {
+		return nil, fmt.Errorf("unknown call with id: %q", toID)
+	}
+
+	query := fmt.Sprintf("-- diff of calls %s and %s\n%s", fromID, toID, to.GetQuery())
+
+	return h.deriveCall(to, core.CallKindDiff, query, func() (core.ResultStream, error) {
+		fromResult, err := from.GetResultSet(resultSet)
+		if err != nil {
+			return nil, fmt.Errorf("from.GetResultSet: %w", err)
+		}
+		toResult, err := to.GetResultSet(resultSet)
+		if err != nil {
+			return nil, fmt.Errorf("to.GetResultSet: %w", err)
 		}
 
+		return core.DiffResults(fromResult, toResult, opts)
+	})
+}
+
+// CallColumnStats computes stats of columns of the result set (see core.ColumnStats).
+// The stats are a new call of the connection which executed the call.
+func (h *Handler) CallColumnStats(callID core.CallID, resultSet int, opts *core.ColumnStatsOptions) (*core.Call, error) {
+	call, ok := h.getCall(callID)
+	if !ok {
+		return nil, fmt.Errorf("unknown call with id: %q", callID)
+	}
+
+	query := fmt.Sprintf("-- column stats of call %s\n%s", callID, call.GetQuery())
+
+	return h.deriveCall(call, core.CallKindColumnStats, query, func() (core.ResultStream, error) {
+		result, err := call.GetResultSet(resultSet)
+		if err != nil {
+			return nil, fmt.Errorf("call.GetResultSet: %w", err)
+		}
+
+		return core.ColumnStats(result, opts)
+	})
+}
+
+// deriveCall starts a call of the kind whose result is computed by stream from results of the source call,
+// on the connection which executed the source call.
+func (h *Handler) deriveCall(source *core.Call, kind core.CallKind, query string, stream func() (core.ResultStream, error)) (*core.Call, error) {
+	connID := h.callConnectionID(source)
+	c, ok := h.lookupConnection[connID]
+	if !ok {
+		return nil, fmt.Errorf("unknown connection with id: %q", connID)
+	}
+
+	return h.trackCall(connID, func(onEvent func(core.CallState, *core.Call), _ func(core.CallProgress, *core.Call)) *core.Call {
+		return c.ExecuteStream(kind, query, func(context.Context) (core.ResultStream, error) {
+			return stream()
+		}, onEvent)
+	}), nil
+}
+
+// callConnectionID returns the ID of the connection which executed the call.
+func (h *Handler) callConnectionID(call *core.Call) core.ConnectionID {
+	if id := call.GetConnectionID(); id != "" {
+		return id
+	}
+
+	// calls from older history only know their connection from the lookup
+	h.callsMutex.RLock()
+	defer h.callsMutex.RUnlock()
+	for id, calls := range h.lookupConnectionCall {
+		if slices.Contains(calls, call.GetID()) {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// trackCall starts a call of the connection with execute (see registerCall)
+// and makes the connection the current one.
+func (h *Handler) trackCall(connID core.ConnectionID, execute func(onEvent func(core.CallState, *core.Call), onProgress func(core.CallProgress, *core.Call)) *core.Call) *core.Call {
+	call := h.registerCall(connID, execute)
+
+	// update current conn
+	_ = h.SetCurrentConnection(connID)
+
+	return call
+}
+
+// registerCall starts a call of the connection with execute. Events of the call are sent to lua
+// and recorded to the call log, and the call is added to lookups.
+func (h *Handler) registerCall(connID core.ConnectionID, execute func(onEvent func(core.CallState, *core.Call), onProgress func(core.CallProgress, *core.Call)) *core.Call) *core.Call {
+	call := execute(func(state core.CallState, c *core.Call) {
+		// only log internal errors, errors reported by the database for the
+		// query (like missing tables) are shown to the user anyway
+		if err := c.Err(); err != nil && core.AsQueryError(err) == nil && !errors.Is(err, core.ErrCallRejected) {
+			h.log.Errorf("cl.Err: %s", err)
+		}
+
+		h.recordCall(connID, c)
 		h.events.CallStateChanged(c)
+	}, func(progress core.CallProgress, c *core.Call) {
+		h.events.CallProgress(c, progress)
 	})
 
 	id := call.GetID()
 
 	// add to lookup
+	h.callsMutex.Lock()
 	h.lookupCall[id] = call
 	h.lookupConnectionCall[connID] = append(h.lookupConnectionCall[connID], id)
+	h.callIndex.add(connID, call)
+	h.callsMutex.Unlock()
 
-	// update current call and conn
-	_ = h.SetCurrentConnection(connID)
-
-	return call, nil
+	return call
 }
 
 func (h *Handler) ConnectionGetCalls(connID core.ConnectionID) ([]*core.Call, error) {
@@ -236,6 +649,9 @@ func (h *Handler) ConnectionGetCalls(connID core.ConnectionID) ([]*core.Call, er
 		return nil, fmt.Errorf("unknown connection with id: %q", connID)
 	}
 
+	h.callsMutex.RLock()
+	defer h.callsMutex.RUnlock()
+
 	var calls []*core.Call
 	callIDs, ok := h.lookupConnectionCall[connID]
 	if !ok {
@@ -321,8 +737,38 @@ func (h *Handler) ConnectionSelectDatabase(connID core.ConnectionID, database st
 	return nil
 }
 
+// ConnectionExplain returns the normalized plan of the query.
+// If buffer is provided, the plan is also displayed in it as indented text.
+func (h *Handler) ConnectionExplain(connID core.ConnectionID, query string, opts *core.ExplainOptions, buffer nvim.Buffer) (*core.PlanNode, error) {
+	c, ok := h.lookupConnection[connID]
+	if !ok {
+		return nil, fmt.Errorf("unknown connection with id: %q", connID)
+	}
+
+	plan, err := c.Explain(query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("c.Explain: %w", err)
+	}
+
+	if buffer == 0 {
+		return plan, nil
+	}
+
+	text, err := format.NewPlanText().Format(plan)
+	if err != nil {
+		return nil, fmt.Errorf("format.Format: %w", err)
+	}
+
+	_, err = newBuffer(h.vim, buffer).Write(text)
+	if err != nil {
+		return nil, fmt.Errorf("buffer.Write: %w", err)
+	}
+
+	return plan, nil
+}
+
 func (h *Handler) CallCancel(callID core.CallID) error {
-	call, ok := h.lookupCall[callID]
+	call, ok := h.getCall(callID)
 	if !ok {
 		return fmt.Errorf("unknown call with id: %q", callID)
 	}
@@ -331,20 +777,37 @@ func (h *Handler) CallCancel(callID core.CallID) error {
 	return nil
 }
 
-func (h *Handler) CallDisplayResult(callID core.CallID, buffer nvim.Buffer, from, to int) (int, error) {
-	call, ok := h.lookupCall[callID]
+// CallConfirm approves or rejects execution of a call which is awaiting confirmation.
+func (h *Handler) CallConfirm(callID core.CallID, approve bool) error {
+	call, ok := h.getCall(callID)
+	if !ok {
+		return fmt.Errorf("unknown call with id: %q", callID)
+	}
+
+	err := call.Confirm(approve)
+	if err != nil {
+		return fmt.Errorf("call.Confirm: %w", err)
+	}
+
+	return nil
+}
+
+// CallDisplayResult displays the range of rows of the result set in the buffer. Optional view sorts
+// and filters the rows on the client side, in which case the number of rows in the view is returned.
+func (h *Handler) CallDisplayResult(callID core.CallID, resultSet int, buffer nvim.Buffer, from, to int, view *core.ResultViewOptions) (int, error) {
+	call, ok := h.getCall(callID)
 	if !ok {
 		return 0, fmt.Errorf("unknown call with id: %q", callID)
 	}
 
-	res, err := call.GetResult()
+	res, err := call.GetResultSet(resultSet)
 	if err != nil {
-		return 0, fmt.Errorf("call.GetResult: %w", err)
+		return 0, fmt.Errorf("call.GetResultSet: %w", err)
 	}
 
-	text, err := res.Format(newTable(), from, to)
+	text, length, err := res.FormatView(newTable(), view, from, to)
 	if err != nil {
-		return 0, fmt.Errorf("res.Format: %w", err)
+		return 0, fmt.Errorf("res.FormatView: %w", err)
 	}
 
 	_, err = newBuffer(h.vim, buffer).Write(text)
@@ -352,14 +815,34 @@ func (h *Handler) CallDisplayResult(callID core.CallID, buffer nvim.Buffer, from
 		return 0, fmt.Errorf("buffer.Write: %w", err)
 	}
 
-	return res.Len(), nil
+	return length, nil
+}
+
+// StoreOptions select rows of the result stored by CallStoreResult and configure their format.
+type StoreOptions struct {
+	// ResultSet is the zero based index of the stored result set.
+	ResultSet int
+	// From and To are the range of rows, applied the same way as in core.Result.Format.
+	From int
+	To   int
+	// View sorts and filters the rows first, the range is then applied to rows of the view.
+	View *core.ResultViewOptions
+	// SQL configures the "sql" format, which needs it. Its dialect defaults to the type of the call's connection.
+	SQL *format.SQLOptions
+	// Markup configures the "markdown", "html" and "asciidoc" formats.
+	Markup *format.MarkupOptions
 }
 
-func (h *Handler) CallStoreResult(callID core.CallID, fmat, out string, from, to int, arg ...any) error {
-	stat, ok := h.lookupCall[callID]
+// CallStoreResult stores rows of the result set selected by opts in the output with the format.
+// All rows of the first result set are stored if opts are nil.
+func (h *Handler) CallStoreResult(callID core.CallID, fmat, out string, opts *StoreOptions, arg ...any) error {
+	stat, ok := h.getCall(callID)
 	if !ok {
 		return fmt.Errorf("unknown call with id: %q", callID)
 	}
+	if opts == nil {
+		opts = &StoreOptions{To: -1}
+	}
 
 	var formatter core.Formatter
 	switch fmat {
@@ -369,6 +852,27 @@ func (h *Handler) CallStoreResult(callID core.CallID, fmat, out string, from, to
 		formatter = format.NewCSV()
 	case "table":
 		formatter = newTable()
+	case "markdown":
+		formatter = format.NewMarkdown(opts.Markup)
+	case "html":
+		formatter = format.NewHTML(opts.Markup)
+	case "asciidoc":
+		formatter = format.NewAsciiDoc(opts.Markup)
+	case "sql":
+		sqlOpts := &format.SQLOptions{}
+		if opts.SQL != nil {
+			sqlOpts = opts.SQL
+		}
+		if sqlOpts.Dialect == "" {
+			if conn, ok := h.lookupConnection[h.callConnectionID(stat)]; ok {
+				sqlOpts.Dialect = conn.GetType()
+			}
+		}
+		f, err := format.NewSQL(sqlOpts)
+		if err != nil {
+			return fmt.Errorf("format.NewSQL: %w", err)
+		}
+		formatter = f
 	default:
 		return fmt.Errorf("store output: %q is not supported", fmat)
 	}
@@ -379,14 +883,28 @@ func (h *Handler) CallStoreResult(callID core.CallID, fmat, out string, from, to
 	}
 	defer cleanup()
 
-	res, err := stat.GetResult()
+	res, err := stat.GetResultSet(opts.ResultSet)
 	if err != nil {
-		return fmt.Errorf("stat.GetResult: %w", err)
+		return fmt.Errorf("stat.GetResultSet: %w", err)
+	}
+
+	// files are written as rows are read, so the output doesn't have to fit in memory
+	if streamFormatter, ok := formatter.(core.StreamFormatter); ok && out == "file" {
+		buffered := bufio.NewWriter(writer)
+		err = res.FormatStream(buffered, streamFormatter, opts.View, opts.From, opts.To)
+		if err != nil {
+			return fmt.Errorf("res.FormatStream: %w", err)
+		}
+		err = buffered.Flush()
+		if err != nil {
+			return fmt.Errorf("buffered.Flush: %w", err)
+		}
+		return nil
 	}
 
-	text, err := res.Format(formatter, from, to)
+	text, _, err := res.FormatView(formatter, opts.View, opts.From, opts.To)
 	if err != nil {
-		return fmt.Errorf("res.Format: %w", err)
+		return fmt.Errorf("res.FormatView: %w", err)
 	}
 
 	_, err = writer.Write(text)
@@ -444,25 +962,3 @@ func (h *Handler) getStoreWriter(output string, arg ...any) (writer io.Writer, c
 
 	return nil, func() {}, fmt.Errorf("store output: %q is not supported", output)
 }
-
-// containsUserFacingError checks if an error is a user-facing SQL error (like missing table)
-// rather than a system error that should be logged
-func containsUserFacingError(errStr string) bool {
-	userFacingErrors := []string{
-		"SQL compilation error",
-		"does not exist or not authorized",
-		"Object",
-		"syntax error",
-		"Access denied",
-		"Query failed:",
-		"Failed to get",
-	}
-	
-	errLower := strings.ToLower(errStr)
-	for _, userErr := range userFacingErrors {
-		if strings.Contains(errLower, strings.ToLower(userErr)) {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+	"github.com/kndndrj/nvim-dbee/dbee/core/mock"
+)
+
+// addTestConnection adds a connected connection of the mock adapter to the handler.
+func addTestConnection(t *testing.T, h *Handler, params *core.ConnectionParams, opts ...mock.AdapterOption) *core.Connection {
+	t.Helper()
+
+	c, err := core.NewConnection(params, mock.NewAdapter(mock.NewRows(0, 3), opts...))
+	require.NoError(t, err)
+	require.NoError(t, c.Connect())
+
+	h.lookupConnection[params.ID] = c
+	return c
+}
+
+func TestHandler_CallRerunDerived(t *testing.T) {
+	r := require.New(t)
+
+	h := newTestHandler(t)
+
+	call := new(core.Call)
+	r.NoError(json.Unmarshal([]byte(`{"id": "diff", "query": "-- diff", "state": "archived", "kind": "diff"}`), call))
+	h.lookupCall[call.GetID()] = call
+
+	_, err := h.CallRerun(call.GetID(), "")
+	r.ErrorIs(err, core.ErrDerivedCallRerun)
+}
+
+func TestHandler_ExecuteManyCurrentConnection(t *testing.T) {
+	r := require.New(t)
+
+	h := newTestHandler(t)
+	for _, id := range []core.ConnectionID{"a", "b", "c"} {
+		addTestConnection(t, h, &core.ConnectionParams{ID: id, Name: string(id)})
+	}
+
+	// the fan-out is a call of the first connection, calls of the fan-out don't change the current one
+	call, err := h.ExecuteMany([]core.ConnectionID{"a", "b", "c"}, "_", nil, nil, 1)
+	r.NoError(err)
+	<-call.Done()
+	r.NoError(call.Err())
+
+	current, err := h.GetCurrentConnection()
+	r.NoError(err)
+	r.Equal(core.ConnectionID("a"), current.GetID())
+}
+
+func TestHandler_ExecuteManyConfirmation(t *testing.T) {
+	r := require.New(t)
+
+	h := newTestHandler(t)
+	addTestConnection(t, h, &core.ConnectionParams{ID: "small", Name: "small"})
+	addTestConnection(t, h, &core.ConnectionParams{
+		ID:          "large",
+		Name:        "large",
+		CallOptions: core.CallOptions{ConfirmAboveBytes: 100},
+	}, mock.AdapterWithEstimate("_", &core.Estimate{Bytes: 1000}))
+
+	// the fan-out doesn't wait for the call which needs confirmation
+	call, err := h.ExecuteMany([]core.ConnectionID{"small", "large"}, "_", nil, nil, 2)
+	r.NoError(err)
+	select {
+	case <-call.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("fan-out did not finish in expected time")
+	}
+	r.NoError(call.Err())
+
+	summary, err := call.GetResultSet(1)
+	r.NoError(err)
+	rows, err := summary.Rows(0, -1)
+	r.NoError(err)
+	r.Len(rows, 2)
+	r.Nil(rows[0][5])
+	r.Contains(rows[1][5], core.ErrFanOutNeedsConfirmation.Error())
+}
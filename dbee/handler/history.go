@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+// defaultGCInterval is used if retention doesn't specify the interval of garbage collection.
+const defaultGCInterval = 10 * time.Minute
+
+// Retention limits the call history. Finished calls over any of the limits are
+// removed together with their archives. Zero values mean no limit.
+type Retention struct {
+	// MaxAge is the maximum age of a call.
+	MaxAge time.Duration
+	// MaxCalls is the maximum number of calls kept per connection.
+	MaxCalls int
+	// MaxSize is the maximum size of all call archives on disk in bytes.
+	MaxSize int64
+	// Interval is the interval of garbage collection.
+	Interval time.Duration
+}
+
+// runHistory restores the call log and periodically enforces the retention until the handler
+// is closed. The call log is synced every time, so that calls of other processes show up.
+func (h *Handler) runHistory() {
+	notify := false
+	for {
+		err := h.syncCallLog(notify)
+		if err != nil {
+			h.log.Infof("h.syncCallLog: %s", err)
+		}
+		notify = true
+
+		h.collectGarbage()
+
+		h.callsMutex.RLock()
+		interval := h.retention.Interval
+		h.callsMutex.RUnlock()
+		if interval <= 0 {
+			interval = defaultGCInterval
+		}
+
+		select {
+		case <-h.closeCh:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// collectGarbage removes the oldest finished calls which exceed the retention.
+func (h *Handler) collectGarbage() {
+	h.callsMutex.RLock()
+	retention := h.retention
+
+	now := time.Now()
+	var expired []core.CallID
+	var kept []*core.Call
+	for _, ids := range h.lookupConnectionCall {
+		var calls []*core.Call
+		for _, id := range ids {
+			call, ok := h.lookupCall[id]
+			if ok && isFinished(call) {
+				calls = append(calls, call)
+			}
+		}
+
+		// newest calls first
+		slices.SortFunc(calls, func(a, b *core.Call) int {
+			return b.GetTimestamp().Compare(a.GetTimestamp())
+		})
+
+		for i, call := range calls {
+			if (retention.MaxCalls > 0 && i >= retention.MaxCalls) ||
+				(retention.MaxAge > 0 && now.Sub(call.GetTimestamp()) > retention.MaxAge) {
+				expired = append(expired, call.GetID())
+				continue
+			}
+			kept = append(kept, call)
+		}
+	}
+	h.callsMutex.RUnlock()
+
+	// archives of the newest calls are kept until they reach the size limit
+	if retention.MaxSize > 0 {
+		slices.SortFunc(kept, func(a, b *core.Call) int {
+			return b.GetTimestamp().Compare(a.GetTimestamp())
+		})
+
+		var size int64
+		for _, call := range kept {
+			size += call.ArchiveSize()
+			if size > retention.MaxSize {
+				expired = append(expired, call.GetID())
+			}
+		}
+	}
+
+	for _, err := range h.deleteCalls(expired) {
+		h.log.Infof("h.deleteCalls: %s", err)
+	}
+
+	// archives which don't belong to any call can only be left behind by crashed sessions,
+	// but they might also belong to another instance sharing the directory, so only old ones are removed
+	if retention.MaxAge > 0 {
+		err := core.RemoveOrphanedArchives(func(id core.CallID) bool {
+			_, ok := h.getCall(id)
+			return ok
+		}, retention.MaxAge)
+		if err != nil {
+			h.log.Infof("core.RemoveOrphanedArchives: %s", err)
+		}
+	}
+}
+
+// CallDelete removes the call from history together with its archive.
+// Unfinished calls are canceled first.
+func (h *Handler) CallDelete(callID core.CallID) error {
+	_, ok := h.getCall(callID)
+	if !ok {
+		return fmt.Errorf("unknown call with id: %q", callID)
+	}
+
+	errs := h.deleteCalls([]core.CallID{callID})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	return nil
+}
+
+// ConnectionPurgeHistory removes all calls of the connection from history together with their archives.
+// Unfinished calls are canceled first. The connection doesn't have to be registered anymore.
+func (h *Handler) ConnectionPurgeHistory(connID core.ConnectionID) error {
+	h.callsMutex.RLock()
+	ids := slices.Clone(h.lookupConnectionCall[connID])
+	h.callsMutex.RUnlock()
+
+	errs := h.deleteCalls(ids)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	return nil
+}
+
+// deleteCalls removes calls from lookups, drops their scratch tables and clears their archives.
+// Archives of unfinished calls are cleared in the background once the calls are canceled.
+func (h *Handler) deleteCalls(ids []core.CallID) []error {
+	if len(ids) < 1 {
+		return nil
+	}
+
+	type deleted struct {
+		connID core.ConnectionID
+		call   *core.Call
+	}
+
+	var calls []deleted
+	h.callsMutex.Lock()
+	for connID, connCalls := range h.lookupConnectionCall {
+		h.lookupConnectionCall[connID] = slices.DeleteFunc(connCalls, func(id core.CallID) bool {
+			if !slices.Contains(ids, id) {
+				return false
+			}
+			if call, ok := h.lookupCall[id]; ok {
+				calls = append(calls, deleted{connID: connID, call: call})
+			}
+			return true
+		})
+	}
+	for _, id := range ids {
+		delete(h.lookupCall, id)
+		delete(h.restoredCalls, id)
+	}
+	h.callIndex.remove(ids)
+	h.callsMutex.Unlock()
+
+	var errs []error
+	err := h.scratch.ForgetCalls(ids...)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("h.scratch.ForgetCalls: %w", err))
+	}
+
+	tombstones := make([]callLogEntry, len(calls))
+	for i, d := range calls {
+		tombstones[i] = callLogEntry{ID: d.call.GetID(), ConnectionID: d.connID, Deleted: true}
+	}
+
+	err = h.appendCallLog(tombstones...)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("h.appendCallLog: %w", err))
+	}
+
+	for _, d := range calls {
+		if isFinished(d.call) {
+			err := d.call.ClearArchive()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("call.ClearArchive: %w", err))
+			}
+		} else {
+			go func(call *core.Call) {
+				err := call.ClearArchive()
+				if err != nil {
+					h.log.Infof("call.ClearArchive: %s", err)
+				}
+			}(d.call)
+		}
+
+		h.events.CallDeleted(d.connID, d.call.GetID())
+	}
+
+	return errs
+}
+
+// getCall returns the call from lookup.
+func (h *Handler) getCall(id core.CallID) (*core.Call, bool) {
+	h.callsMutex.RLock()
+	defer h.callsMutex.RUnlock()
+
+	call, ok := h.lookupCall[id]
+	return call, ok
+}
+
+func isFinished(call *core.Call) bool {
+	select {
+	case <-call.Done():
+		return true
+	default:
+		return false
+	}
+}
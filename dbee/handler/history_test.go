@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+// newTestHandler returns a handler which isn't attached to neovim and keeps its history in a temporary directory.
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	h := New(nil, nil)
+	h.historyDir = t.TempDir()
+	core.SetArchiveDir(filepath.Join(h.historyDir, "archives"))
+	// calls record their history until they are done
+	t.Cleanup(h.Close)
+
+	return h
+}
+
+// addTestCall adds a finished call with the timestamp to lookups of the connection.
+func addTestCall(t *testing.T, h *Handler, connID core.ConnectionID, id string, timestamp time.Time) {
+	t.Helper()
+
+	call := new(core.Call)
+	err := json.Unmarshal([]byte(fmt.Sprintf(`{"id": %q, "query": "_", "state": "archived", "timestamp_us": %d}`,
+		id, timestamp.UnixMicro())), call)
+	require.NoError(t, err)
+
+	h.callsMutex.Lock()
+	defer h.callsMutex.Unlock()
+	h.lookupCall[call.GetID()] = call
+	h.lookupConnectionCall[connID] = append(h.lookupConnectionCall[connID], call.GetID())
+	h.callIndex.add(connID, call)
+}
+
+func TestHandler_CollectGarbage(t *testing.T) {
+	r := require.New(t)
+
+	h := newTestHandler(t)
+	h.retention = Retention{MaxAge: 24 * time.Hour, MaxCalls: 2}
+
+	now := time.Now()
+	addTestCall(t, h, "a", "a_newest", now)
+	addTestCall(t, h, "a", "a_newer", now.Add(-time.Minute))
+	addTestCall(t, h, "a", "a_oldest", now.Add(-2*time.Minute))
+	addTestCall(t, h, "b", "b_expired", now.Add(-48*time.Hour))
+	addTestCall(t, h, "b", "b_kept", now.Add(-time.Hour))
+
+	// archive which doesn't belong to any call is removed once it's older than max age
+	archives := filepath.Join(h.historyDir, "archives")
+	r.NoError(os.MkdirAll(archives, 0o700))
+	orphan := filepath.Join(archives, "orphan.archive")
+	r.NoError(os.WriteFile(orphan, []byte("_"), 0o600))
+	r.NoError(os.Chtimes(orphan, now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+	recent := filepath.Join(archives, "recent.archive")
+	r.NoError(os.WriteFile(recent, []byte("_"), 0o600))
+
+	h.collectGarbage()
+
+	r.ElementsMatch([]core.CallID{"a_newest", "a_newer"}, h.lookupConnectionCall["a"])
+	r.ElementsMatch([]core.CallID{"b_kept"}, h.lookupConnectionCall["b"])
+	for _, id := range []core.CallID{"a_oldest", "b_expired"} {
+		_, ok := h.getCall(id)
+		r.False(ok, id)
+	}
+
+	_, err := os.Stat(orphan)
+	r.True(os.IsNotExist(err))
+	_, err = os.Stat(recent)
+	r.NoError(err)
+
+	// removed calls are recorded to the call log
+	entries, err := readCallLog(h.callLogFileName())
+	r.NoError(err)
+	var deleted []core.CallID
+	for _, entry := range entries {
+		if entry.Deleted {
+			deleted = append(deleted, entry.ID)
+		}
+	}
+	r.ElementsMatch([]core.CallID{"a_oldest", "b_expired"}, deleted)
+}
+
+func TestHandler_HistoryDirPermissions(t *testing.T) {
+	r := require.New(t)
+
+	h := newTestHandler(t)
+	r.NoError(os.Chmod(h.historyDir, 0o755))
+
+	r.NoError(h.appendCallLog(callLogEntry{ID: "id", ConnectionID: "conn", Deleted: true}))
+
+	info, err := os.Stat(h.historyDir)
+	r.NoError(err)
+	r.Equal(os.FileMode(0o700), info.Mode().Perm())
+}
@@ -39,23 +39,113 @@ func (cw *callWrap) MarshalMsgPack(enc *msgpack.Encoder) error {
 		errMsg = err.Error()
 	}
 
+	var queryErr *queryErrorWrap
+	if qe := core.AsQueryError(cw.call.Err()); qe != nil {
+		queryErr = &queryErrorWrap{
+			Severity: qe.Severity,
+			SQLState: qe.SQLState,
+			Code:     qe.Code,
+			Message:  qe.Message,
+			Detail:   qe.Detail,
+			Hint:     qe.Hint,
+			Position: qe.Position,
+			Line:     qe.Line,
+			Column:   qe.Column,
+		}
+	}
+
+	var estimate *estimateWrap
+	if est := cw.call.GetEstimate(); est != nil {
+		estimate = &estimateWrap{
+			Bytes:  est.Bytes,
+			Rows:   est.Rows,
+			Detail: est.Detail,
+		}
+	}
+
+	var rowsAffected *int64
+	if n := cw.call.GetRowsAffected(); n >= 0 {
+		rowsAffected = &n
+	}
+
 	return enc.Encode(&struct {
-		ID        string `msgpack:"id"`
-		Query     string `msgpack:"query"`
-		State     string `msgpack:"state"`
-		TimeTaken int64  `msgpack:"time_taken_us"`
-		Timestamp int64  `msgpack:"timestamp_us"`
-		Error     string `msgpack:"error,omitempty"`
+		ID             string          `msgpack:"id"`
+		Query          string          `msgpack:"query"`
+		State          string          `msgpack:"state"`
+		TimeTaken      int64           `msgpack:"time_taken_us"`
+		Timestamp      int64           `msgpack:"timestamp_us"`
+		Error          string          `msgpack:"error,omitempty"`
+		QueryError     *queryErrorWrap `msgpack:"query_error,omitempty"`
+		ResultSets     int             `msgpack:"result_sets"`
+		Estimate       *estimateWrap   `msgpack:"estimate,omitempty"`
+		ConnectionID   string          `msgpack:"conn_id"`
+		ConnectionName string          `msgpack:"conn_name"`
+		Kind           string          `msgpack:"kind"`
+		Database       string          `msgpack:"database"`
+		RowCount       int             `msgpack:"row_count"`
+		RowsAffected   *int64          `msgpack:"rows_affected,omitempty"`
+		ArchiveSize    int64           `msgpack:"archive_size"`
+		QueryID        string          `msgpack:"query_id"`
+		ParentID       string          `msgpack:"parent_call_id,omitempty"`
 	}{
-		ID:        string(cw.call.GetID()),
-		Query:     cw.call.GetQuery(),
-		State:     cw.call.GetState().String(),
-		TimeTaken: cw.call.GetTimeTaken().Microseconds(),
-		Timestamp: cw.call.GetTimestamp().UnixMicro(),
-		Error:     errMsg,
+		ID:             string(cw.call.GetID()),
+		Query:          cw.call.GetQuery(),
+		State:          cw.call.GetState().String(),
+		TimeTaken:      cw.call.GetTimeTaken().Microseconds(),
+		Timestamp:      cw.call.GetTimestamp().UnixMicro(),
+		Error:          errMsg,
+		QueryError:     queryErr,
+		ResultSets:     cw.call.GetResultSetCount(),
+		Estimate:       estimate,
+		ConnectionID:   string(cw.call.GetConnectionID()),
+		ConnectionName: cw.call.GetConnectionName(),
+		Kind:           string(cw.call.GetKind()),
+		Database:       cw.call.GetDatabase(),
+		RowCount:       cw.call.GetRowCount(),
+		RowsAffected:   rowsAffected,
+		ArchiveSize:    cw.call.ArchiveSize(),
+		QueryID:        cw.call.GetQueryID(),
+		ParentID:       string(cw.call.GetParentID()),
 	})
 }
 
+// callSearchResultWrap is the msgpack representation of CallSearchResult
+type callSearchResultWrap struct {
+	Calls []*callWrap `msgpack:"calls"`
+	Total int         `msgpack:"total"`
+}
+
+func WrapCallSearchResult(result *CallSearchResult) *callSearchResultWrap {
+	if result == nil {
+		return nil
+	}
+
+	return &callSearchResultWrap{
+		Calls: WrapCalls(result.Calls),
+		Total: result.Total,
+	}
+}
+
+// queryErrorWrap is the msgpack representation of core.QueryError
+type queryErrorWrap struct {
+	Severity string `msgpack:"severity"`
+	SQLState string `msgpack:"sqlstate"`
+	Code     string `msgpack:"code"`
+	Message  string `msgpack:"message"`
+	Detail   string `msgpack:"detail"`
+	Hint     string `msgpack:"hint"`
+	Position int    `msgpack:"position"`
+	Line     int    `msgpack:"line"`
+	Column   int    `msgpack:"column"`
+}
+
+// estimateWrap is the msgpack representation of core.Estimate
+type estimateWrap struct {
+	Bytes  int64  `msgpack:"bytes"`
+	Rows   int64  `msgpack:"rows"`
+	Detail string `msgpack:"detail"`
+}
+
 // connectionWrap is wrapper around core.Connection with msgpack marshaling capabilities
 type connectionWrap struct {
 	connection *core.Connection
@@ -112,15 +202,25 @@ func (cw *connectionParamsWrap) MarshalMsgPack(enc *msgpack.Encoder) error {
 		return enc.Encode(nil)
 	}
 	return enc.Encode(&struct {
-		ID   string `msgpack:"id"`
-		Name string `msgpack:"name"`
-		Type string `msgpack:"type"`
-		URL  string `msgpack:"url"`
+		ID        string `msgpack:"id"`
+		Name      string `msgpack:"name"`
+		Type      string `msgpack:"type"`
+		URL       string `msgpack:"url"`
+		TimeoutMs int64  `msgpack:"timeout_ms,omitempty"`
+		MaxRows   int    `msgpack:"max_rows,omitempty"`
+		MaxBytes  int    `msgpack:"max_bytes,omitempty"`
+
+		ConfirmAboveBytes int `msgpack:"confirm_above_bytes,omitempty"`
 	}{
-		ID:   string(cw.params.ID),
-		Name: cw.params.Name,
-		Type: cw.params.Type,
-		URL:  cw.params.URL,
+		ID:        string(cw.params.ID),
+		Name:      cw.params.Name,
+		Type:      cw.params.Type,
+		URL:       cw.params.URL,
+		TimeoutMs: cw.params.CallOptions.Timeout.Milliseconds(),
+		MaxRows:   cw.params.CallOptions.MaxRows,
+		MaxBytes:  cw.params.CallOptions.MaxBytes,
+
+		ConfirmAboveBytes: cw.params.CallOptions.ConfirmAboveBytes,
 	})
 }
 
@@ -199,3 +299,49 @@ func (cw *columnWrap) MarshalMsgPack(enc *msgpack.Encoder) error {
 		Type: cw.column.Type,
 	})
 }
+
+// planNodeWrap is a wrapper around core.PlanNode with msgpack marshaling capabilities
+type planNodeWrap struct {
+	node *core.PlanNode
+}
+
+func WrapPlanNode(node *core.PlanNode) *planNodeWrap {
+	return &planNodeWrap{
+		node: node,
+	}
+}
+
+func WrapPlanNodes(nodes []*core.PlanNode) []*planNodeWrap {
+	wraps := make([]*planNodeWrap, len(nodes))
+
+	for i := range nodes {
+		wraps[i] = &planNodeWrap{
+			node: nodes[i],
+		}
+	}
+
+	return wraps
+}
+
+func (pw *planNodeWrap) MarshalMsgPack(enc *msgpack.Encoder) error {
+	if pw.node == nil {
+		return enc.Encode(nil)
+	}
+	return enc.Encode(&struct {
+		Operation  string          `msgpack:"operation"`
+		Detail     string          `msgpack:"detail"`
+		Cost       float64         `msgpack:"cost"`
+		Rows       float64         `msgpack:"rows"`
+		ActualRows float64         `msgpack:"actual_rows"`
+		Time       int64           `msgpack:"time_us"`
+		Children   []*planNodeWrap `msgpack:"children"`
+	}{
+		Operation:  pw.node.Operation,
+		Detail:     pw.node.Detail,
+		Cost:       pw.node.Cost,
+		Rows:       pw.node.Rows,
+		ActualRows: pw.node.ActualRows,
+		Time:       pw.node.Time.Microseconds(),
+		Children:   WrapPlanNodes(pw.node.Children),
+	})
+}
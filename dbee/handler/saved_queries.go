@@ -0,0 +1,374 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kndndrj/nvim-dbee/dbee/core"
+)
+
+// savedQueriesPollInterval is the interval of checking the saved queries file for changes.
+const savedQueriesPollInterval = 2 * time.Second
+
+// SavedQuery is a query saved to the library of queries.
+type SavedQuery struct {
+	// ID defaults to the name for queries which are written to the file by hand.
+	ID          string   `json:"id" yaml:"id" msgpack:"id"`
+	Name        string   `json:"name" yaml:"name" msgpack:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty" msgpack:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty" msgpack:"tags,omitempty"`
+	// ConnectionType is the type of connections the query is meant for (e.g. "postgres")
+	// and ConnectionID the single connection it's meant for. Both are optional.
+	ConnectionType string            `json:"connection_type,omitempty" yaml:"connection_type,omitempty" msgpack:"connection_type,omitempty"`
+	ConnectionID   core.ConnectionID `json:"connection_id,omitempty" yaml:"connection_id,omitempty" msgpack:"connection_id,omitempty"`
+	Query          string            `json:"query" yaml:"query" msgpack:"query"`
+}
+
+// savedQueryStore keeps saved queries in a plain JSON or YAML file (depending on the extension),
+// so that it can be edited by hand or shared in a repository. The file is re-read whenever it
+// changes on disk, and changes are written to it right away.
+type savedQueryStore struct {
+	mu      sync.Mutex
+	path    string
+	queries []*SavedQuery
+	// modification time and size of the file when it was last read
+	modTime time.Time
+	size    int64
+}
+
+// setPath changes the location of the store file.
+func (s *savedQueryStore) setPath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path != path {
+		s.path = path
+		s.queries = nil
+		s.modTime = time.Time{}
+		s.size = 0
+	}
+}
+
+// reload re-reads the file if it changed since it was last read.
+// It reports if the queries changed.
+func (s *savedQueryStore) reload() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reloadLocked()
+}
+
+func (s *savedQueryStore) reloadLocked() (bool, error) {
+	if s.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		changed := len(s.queries) > 0
+		s.queries = nil
+		s.modTime = time.Time{}
+		s.size = 0
+		return changed, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("os.Stat: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return false, nil
+	}
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var queries []*SavedQuery
+	if len(bytes.TrimSpace(b)) > 0 {
+		if s.isYAML() {
+			err = yaml.Unmarshal(b, &queries)
+		} else {
+			err = json.Unmarshal(b, &queries)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed decoding saved queries file %q: %w", s.path, err)
+		}
+	}
+
+	// queries without a name can't be referenced
+	queries = slices.DeleteFunc(queries, func(q *SavedQuery) bool {
+		return q == nil || q.Name == ""
+	})
+	for _, q := range queries {
+		if q.ID == "" {
+			q.ID = q.Name
+		}
+	}
+
+	s.queries = queries
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+
+	return true, nil
+}
+
+// write replaces the file with current queries. The file is written to a temporary
+// file first, so that it's never left incomplete.
+func (s *savedQueryStore) write() error {
+	if s.path == "" {
+		return errors.New("saved queries file is not configured")
+	}
+
+	queries := s.queries
+	if queries == nil {
+		queries = []*SavedQuery{}
+	}
+
+	var b []byte
+	var err error
+	if s.isYAML() {
+		b, err = yaml.Marshal(queries)
+	} else {
+		b, err = json.MarshalIndent(queries, "", "  ")
+		b = append(b, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed encoding saved queries: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, b, 0o644)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	err = os.Rename(tmp, s.path)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("os.Stat: %w", err)
+	}
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+
+	return nil
+}
+
+func (s *savedQueryStore) isYAML() bool {
+	ext := strings.ToLower(filepath.Ext(s.path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// list returns copies of all queries.
+func (s *savedQueryStore) list() ([]*SavedQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.reloadLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]*SavedQuery, len(s.queries))
+	for i, q := range s.queries {
+		queries[i] = q.clone()
+	}
+	return queries, nil
+}
+
+func (s *savedQueryStore) get(id string) (*SavedQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.reloadLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	i := s.index(id)
+	if i < 0 {
+		return nil, fmt.Errorf("unknown saved query with id: %q", id)
+	}
+	return s.queries[i].clone(), nil
+}
+
+// save adds the query, or replaces the query with the same ID.
+// Queries without an ID get a new one.
+func (s *savedQueryStore) save(query *SavedQuery) (*SavedQuery, error) {
+	if query == nil {
+		return nil, errors.New("no saved query provided")
+	}
+	if query.Name == "" {
+		return nil, errors.New("saved query has no name")
+	}
+	if strings.TrimSpace(query.Query) == "" {
+		return nil, errors.New("saved query has no query")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// don't overwrite changes made on disk in the meantime
+	_, err := s.reloadLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	query = query.clone()
+	if query.ID == "" {
+		query.ID = uuid.New().String()
+	}
+
+	if i := s.index(query.ID); i >= 0 {
+		s.queries[i] = query
+	} else {
+		s.queries = append(s.queries, query)
+	}
+
+	err = s.write()
+	if err != nil {
+		return nil, err
+	}
+
+	return query.clone(), nil
+}
+
+func (s *savedQueryStore) delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.reloadLocked()
+	if err != nil {
+		return err
+	}
+
+	i := s.index(id)
+	if i < 0 {
+		return fmt.Errorf("unknown saved query with id: %q", id)
+	}
+	s.queries = slices.Delete(s.queries, i, i+1)
+
+	return s.write()
+}
+
+func (s *savedQueryStore) index(id string) int {
+	return slices.IndexFunc(s.queries, func(q *SavedQuery) bool {
+		return q.ID == id
+	})
+}
+
+func (q *SavedQuery) clone() *SavedQuery {
+	c := *q
+	c.Tags = slices.Clone(q.Tags)
+	return &c
+}
+
+// watchSavedQueries notifies lua about changes of the saved queries file
+// until the handler is closed.
+func (h *Handler) watchSavedQueries() {
+	// the file can't be read while it's being edited, errors are only logged once
+	var lastErr string
+	for {
+		changed, err := h.savedQueries.reload()
+		if err != nil && err.Error() != lastErr {
+			h.log.Infof("h.savedQueries.reload: %s", err)
+		}
+		lastErr = ""
+		if err != nil {
+			lastErr = err.Error()
+		}
+		if changed {
+			h.events.SavedQueriesChanged()
+		}
+
+		select {
+		case <-h.closeCh:
+			return
+		case <-time.After(savedQueriesPollInterval):
+		}
+	}
+}
+
+// SavedQueryList returns all saved queries.
+func (h *Handler) SavedQueryList() ([]*SavedQuery, error) {
+	queries, err := h.savedQueries.list()
+	if err != nil {
+		return nil, fmt.Errorf("h.savedQueries.list: %w", err)
+	}
+	return queries, nil
+}
+
+// SavedQueryGet returns the saved query with the id.
+func (h *Handler) SavedQueryGet(id string) (*SavedQuery, error) {
+	return h.savedQueries.get(id)
+}
+
+// SavedQuerySave adds the query to saved queries, or updates the saved query with the same ID.
+// A new ID is assigned to queries without one.
+func (h *Handler) SavedQuerySave(query *SavedQuery) (*SavedQuery, error) {
+	saved, err := h.savedQueries.save(query)
+	if err != nil {
+		return nil, fmt.Errorf("h.savedQueries.save: %w", err)
+	}
+
+	h.events.SavedQueriesChanged()
+	return saved, nil
+}
+
+// SavedQueryDelete removes the saved query with the id.
+func (h *Handler) SavedQueryDelete(id string) error {
+	err := h.savedQueries.delete(id)
+	if err != nil {
+		return fmt.Errorf("h.savedQueries.delete: %w", err)
+	}
+
+	h.events.SavedQueriesChanged()
+	return nil
+}
+
+// CallSaveQuery saves the query of the call. Query text, connection ID and connection type
+// are taken from the call and its connection, the rest of the fields from the provided query.
+// If the name isn't provided, the first line of the query is used.
+func (h *Handler) CallSaveQuery(callID core.CallID, query *SavedQuery) (*SavedQuery, error) {
+	call, ok := h.getCall(callID)
+	if !ok {
+		return nil, fmt.Errorf("unknown call with id: %q", callID)
+	}
+
+	saved := &SavedQuery{}
+	if query != nil {
+		saved = query.clone()
+	}
+	saved.Query = call.GetQuery()
+
+	connID := h.callConnectionID(call)
+	saved.ConnectionID = connID
+	if conn, ok := h.lookupConnection[connID]; ok {
+		saved.ConnectionType = conn.GetType()
+	}
+
+	if saved.Name == "" {
+		saved.Name, _, _ = strings.Cut(strings.TrimSpace(saved.Query), "\n")
+	}
+
+	return h.SavedQuerySave(saved)
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeSavedQueriesFile writes content to the saved queries file, as if it was edited by hand.
+// Modification time is moved forward, so that the change is noticed even on coarse file systems.
+func writeSavedQueriesFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	modified := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, modified, modified))
+}
+
+func TestSavedQueryStore(t *testing.T) {
+	testCases := []struct {
+		name string
+		file string
+		// edited is the file with queries edited by hand and expected are the queries read from it
+		edited   string
+		expected []*SavedQuery
+	}{
+		{
+			name: "json",
+			file: "queries.json",
+			edited: `[
+  {"name": "by hand", "query": "select 1"},
+  {"id": "explicit", "name": "with id", "tags": ["a"], "query": "select 2"},
+  {"query": "no name"},
+  null
+]`,
+			expected: []*SavedQuery{
+				{ID: "by hand", Name: "by hand", Query: "select 1"},
+				{ID: "explicit", Name: "with id", Tags: []string{"a"}, Query: "select 2"},
+			},
+		},
+		{
+			name: "yaml",
+			file: "queries.yaml",
+			edited: `- name: by hand
+  query: select 1
+- id: explicit
+  name: with id
+  tags: [a]
+  query: |-
+    select 2
+- query: no name
+`,
+			expected: []*SavedQuery{
+				{ID: "by hand", Name: "by hand", Query: "select 1"},
+				{ID: "explicit", Name: "with id", Tags: []string{"a"}, Query: "select 2"},
+			},
+		},
+		{
+			name: "yml",
+			file: "queries.yml",
+			edited: `- name: by hand
+  query: select 1
+`,
+			expected: []*SavedQuery{
+				{ID: "by hand", Name: "by hand", Query: "select 1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			path := filepath.Join(t.TempDir(), "nested", tc.file)
+			store := &savedQueryStore{}
+			store.setPath(path)
+
+			// missing file is an empty library
+			queries, err := store.list()
+			r.NoError(err)
+			r.Empty(queries)
+
+			// round trip
+			first, err := store.save(&SavedQuery{
+				Name:           "first",
+				Description:    "multi\nline",
+				Tags:           []string{"x", "y"},
+				ConnectionType: "postgres",
+				ConnectionID:   "conn",
+				Query:          "select *\nfrom t\nwhere a = 'b: c'",
+			})
+			r.NoError(err)
+			r.NotEmpty(first.ID)
+			second, err := store.save(&SavedQuery{ID: "second", Name: "second", Query: "select 1"})
+			r.NoError(err)
+			r.Equal("second", second.ID)
+
+			reread := &savedQueryStore{}
+			reread.setPath(path)
+			queries, err = reread.list()
+			r.NoError(err)
+			r.Equal([]*SavedQuery{first, second}, queries)
+
+			// saving the same ID replaces the query
+			second.Query = "select 22"
+			_, err = store.save(second)
+			r.NoError(err)
+			changed, err := reread.reload()
+			r.NoError(err)
+			r.True(changed)
+			saved, err := reread.get("second")
+			r.NoError(err)
+			r.Equal("select 22", saved.Query)
+
+			// nothing changed since
+			changed, err = reread.reload()
+			r.NoError(err)
+			r.False(changed)
+
+			// edits made by hand are picked up
+			writeSavedQueriesFile(t, path, tc.edited)
+			changed, err = store.reload()
+			r.NoError(err)
+			r.True(changed)
+			queries, err = store.list()
+			r.NoError(err)
+			r.Equal(tc.expected, queries)
+
+			// and are kept when saving
+			added, err := store.save(&SavedQuery{Name: "added", Query: "select 3"})
+			r.NoError(err)
+			queries, err = reread.list()
+			r.NoError(err)
+			r.Equal(append(tc.expected, added), queries)
+
+			r.NoError(store.delete(added.ID))
+			r.Error(store.delete(added.ID))
+			queries, err = reread.list()
+			r.NoError(err)
+			r.Equal(tc.expected, queries)
+
+			// invalid file is reported, removed file empties the library
+			writeSavedQueriesFile(t, path, "{[")
+			_, err = store.list()
+			r.Error(err)
+
+			r.NoError(os.Remove(path))
+			changed, err = store.reload()
+			r.NoError(err)
+			r.True(changed)
+			queries, err = store.list()
+			r.NoError(err)
+			r.Empty(queries)
+		})
+	}
+}
+
+func TestSavedQueryStore_Validation(t *testing.T) {
+	r := require.New(t)
+
+	store := &savedQueryStore{}
+
+	_, err := store.save(nil)
+	r.Error(err)
+	_, err = store.save(&SavedQuery{Query: "select 1"})
+	r.Error(err)
+	_, err = store.save(&SavedQuery{Name: "name", Query: " \n"})
+	r.Error(err)
+
+	// file has to be configured
+	_, err = store.save(&SavedQuery{Name: "name", Query: "select 1"})
+	r.Error(err)
+}
@@ -82,15 +82,17 @@ end
 
 ---Store currently displayed result.
 ---Convenience wrapper around some api functions.
----@param format string format of the output -> "csv"|"json"|"table"
+---The view of the displayed result (sort, filters and search) is used unless provided.
+---@param format string format of the output -> "csv"|"json"|"table"|"sql"|"markdown"|"html"|"asciidoc"
 ---@param output string where to pipe the results -> "file"|"yank"|"buffer"
----@param opts { from: integer, to: integer, extra_arg: any }
+---@param opts { from: integer, to: integer, extra_arg: any, result_set: integer, view: ResultView, sql: SqlStoreOptions, markup: MarkupStoreOptions }
 function dbee.store(format, output, opts)
   local call = api.ui.result_get_call()
   if not call then
     error("no current call to store")
   end
 
+  opts = vim.tbl_extend("keep", opts or {}, { view = api.ui.result_get_view() })
   api.core.call_store_result(call.id, format, output, opts)
 end
 
@@ -19,23 +19,42 @@ return function()
   vim.fn["remote#host#RegisterPlugin"]("nvim_dbee", "0", {
     { type = "function", name = "DbeeAddHelpers", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeCallCancel", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeCallColumnStats", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeCallConfirm", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeCallDelete", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeCallDiff", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeCallDisplayResult", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeCallRerun", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeCallSaveQuery", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeCallStoreResult", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeConfigure", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeConnectionBeginTransaction", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeConnectionCommitTransaction", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionConnect", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionDisconnect", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionExecute", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeConnectionExplain", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionGetCalls", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionGetColumns", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionGetHelpers", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionGetParams", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionGetStructure", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeConnectionInTransaction", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionIsConnected", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionListDatabases", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeConnectionPurgeHistory", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeConnectionRollbackTransaction", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeConnectionSelectDatabase", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeCreateConnection", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeDeleteConnection", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeExecuteMany", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeGetConnections", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeGetCurrentConnection", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeSavedQueryDelete", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeSavedQueryGet", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeSavedQueryList", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeSavedQuerySave", sync = true, opts = vim.empty_dict() },
+    { type = "function", name = "DbeeSearchCalls", sync = true, opts = vim.empty_dict() },
     { type = "function", name = "DbeeSetCurrentConnection", sync = true, opts = vim.empty_dict() },
   })
 end
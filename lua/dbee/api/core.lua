@@ -114,11 +114,72 @@ function core.set_current_connection(id)
 end
 
 ---Execute a query on a connection.
+---Parameters in opts are passed to the database as bind variables:
+---a list for positional ones (e.g. $1 in postgres, ? in mysql) or a map for named ones (e.g. :name in oracle).
+---Limits in opts (timeout_ms, max_rows, max_bytes and confirm_above_bytes) override the defaults of the connection,
+---negative values disable the limit.
 ---@param id connection_id
 ---@param query string
+---@param opts? { params: any[]|table<string, any>, timeout_ms: integer, max_rows: integer, max_bytes: integer, confirm_above_bytes: integer }
 ---@return CallDetails
-function core.connection_execute(id, query)
-  return state.handler():connection_execute(id, query)
+function core.connection_execute(id, query, opts)
+  return state.handler():connection_execute(id, query, opts)
+end
+
+---Execute a query on many connections (e.g. shards), on at most opts.concurrency of them at once (4 by default).
+---Every execution is a separate call of its connection. The returned call (of the first connection)
+---finishes once all of them do and has two result sets: rows of all calls led by a "connection" column,
+---followed by a summary with a row per connection (connection, call_id, state, rows, time_taken, error).
+---Connections are connected if needed. Params and limits in opts apply to every call (see connection_execute).
+---Calls estimated over the confirm_above_bytes call option can't be confirmed, they are rejected and
+---reported in the summary with "query needs confirmation".
+---@param ids connection_id[]
+---@param query string
+---@param opts? ExecuteManyOptions
+---@return CallDetails
+function core.execute_many(ids, query, opts)
+  return state.handler():execute_many(ids, query, opts)
+end
+
+---Explain a query on a connection and return its plan as a tree of nodes.
+---With analyze set in opts, the query is executed to collect actual rows and timing of the nodes.
+---Postgres executes it in a transaction which is rolled back and BigQuery only analyzes SELECT statements.
+---If the query is estimated over the confirm_above_bytes call option of the connection,
+---analyze fails with "explain analyze needs confirmation", unless confirm is set in opts.
+---If buffer is set in opts, the plan is also rendered as text in that buffer.
+---@param id connection_id
+---@param query string
+---@param opts? { analyze: boolean, confirm: boolean, buffer: integer }
+---@return PlanNode?
+function core.connection_explain(id, query, opts)
+  return state.handler():connection_explain(id, query, opts)
+end
+
+---Begin a transaction on a connection.
+---Until the transaction is committed or rolled back, all calls of the connection
+---are executed in it, on a single database connection.
+---@param id connection_id
+function core.connection_begin_transaction(id)
+  state.handler():connection_begin_transaction(id)
+end
+
+---Commit the open transaction of a connection.
+---@param id connection_id
+function core.connection_commit_transaction(id)
+  state.handler():connection_commit_transaction(id)
+end
+
+---Roll back the open transaction of a connection.
+---@param id connection_id
+function core.connection_rollback_transaction(id)
+  state.handler():connection_rollback_transaction(id)
+end
+
+---Check if a connection has an open transaction.
+---@param id connection_id
+---@return boolean
+function core.connection_in_transaction(id)
+  return state.handler():connection_in_transaction(id)
 end
 
 ---Get database structure of a connection.
@@ -169,28 +230,133 @@ function core.connection_get_calls(id)
   return state.handler():connection_get_calls(id)
 end
 
----Cancel call execution.
+---Search past calls of all connections.
+---Calls are filtered, sorted (newest first by default) and paginated with opts.
+---@param opts? CallSearchOptions
+---@return CallSearchResult
+function core.search_calls(opts)
+  return state.handler():search_calls(opts)
+end
+
+---Remove all past calls of a connection from history, together with their archived results.
+---Unfinished calls are canceled first.
+---@param id connection_id
+function core.connection_purge_history(id)
+  state.handler():connection_purge_history(id)
+end
+
+---Cancel call execution or retrieval of its results.
+---If the adapter supports it, the query is also canceled on the database server.
 ---If call is finished, nothing happens.
 ---@param id call_id
 function core.call_cancel(id)
   state.handler():call_cancel(id)
 end
 
+---Approve or reject execution of a call which is awaiting confirmation.
+---Calls wait for confirmation if their estimate (e.g. bytes scanned) is over
+---the confirm_above_bytes threshold of the connection.
+---@param id call_id
+---@param approve boolean
+function core.call_confirm(id, approve)
+  state.handler():call_confirm(id, approve)
+end
+
+---Remove a call from history, together with its archived results.
+---If the call is still running, it's canceled first.
+---@param id call_id
+function core.call_delete(id)
+  state.handler():call_delete(id)
+end
+
+---Execute the query of a call again, with the same params.
+---The new call is linked to the original one through its parent_call_id.
+---If conn_id is not provided, the connection of the original call is used.
+---@param id call_id
+---@param conn_id? connection_id
+---@return CallDetails
+function core.call_rerun(id, conn_id)
+  return state.handler():call_rerun(id, conn_id)
+end
+
+---Compare results of two calls, e.g. the same query before and after a migration.
+---Rows are matched by key columns, or by their position if no key columns are provided.
+---The diff is a new call of the connection of the "to" call, whose result has a leading
+---"diff" column with markers of rows: "+" (added), "-" (removed), "~" (changed) or "=" (unchanged).
+---Changed cells hold both values in the form of "old → new".
+---@param from_id call_id
+---@param to_id call_id
+---@param opts? CallDiffOptions
+---@return CallDetails
+function core.call_diff(from_id, to_id, opts)
+  return state.handler():call_diff(from_id, to_id, opts)
+end
+
+---Compute stats of columns of a call result: number of values, nulls and distinct values,
+---min, max, mean (of numbers), min and max length (of text) and the most frequent values.
+---The stats are a new call of the connection of the call, whose result has a row per column.
+---@param id call_id
+---@param opts? ColumnStatsOptions
+---@return CallDetails
+function core.call_column_stats(id, opts)
+  return state.handler():call_column_stats(id, opts)
+end
+
+---Save the query of a call to saved queries.
+---Connection of the call is recorded with the query. If name is not provided,
+---the first line of the query is used.
+---@param id call_id
+---@param opts? { name: string, description: string, tags: string[] }
+---@return SavedQuery
+function core.call_save_query(id, opts)
+  return state.handler():call_save_query(id, opts)
+end
+
+---Get all saved queries.
+---@return SavedQuery[]
+function core.saved_query_list()
+  return state.handler():saved_query_list()
+end
+
+---Get a saved query.
+---@param id string
+---@return SavedQuery
+function core.saved_query_get(id)
+  return state.handler():saved_query_get(id)
+end
+
+---Add a query to saved queries or update the saved query with the same id.
+---A new id is assigned to queries without one.
+---@param query SavedQuery
+---@return SavedQuery
+function core.saved_query_save(query)
+  return state.handler():saved_query_save(query)
+end
+
+---Remove a query from saved queries.
+---@param id string
+function core.saved_query_delete(id)
+  state.handler():saved_query_delete(id)
+end
+
 ---Display the result of a call formatted as a table in a buffer.
 ---@param id call_id id of the call
 ---@param bufnr integer
 ---@param from integer
 ---@param to integer
----@return integer total number of rows
-function core.call_display_result(id, bufnr, from, to)
-  return state.handler():call_display_result(id, bufnr, from, to)
+---@param result_set? integer zero based index of the result set (defaults to the first one)
+---@param view? ResultView sort, filters and search of rows (from and to are then applied to rows of the view)
+---@return integer total number of rows (of the view)
+function core.call_display_result(id, bufnr, from, to, result_set, view)
+  return state.handler():call_display_result(id, bufnr, from, to, result_set, view)
 end
 
 ---Store the result of a call.
+---Files are written in batches of rows read from the call archive, so the output doesn't have to fit in memory.
 ---@param id call_id
----@param format string format of the output -> "csv"|"json"|"table"
+---@param format string format of the output -> "csv"|"json"|"table"|"sql"|"markdown"|"html"|"asciidoc"
 ---@param output string where to pipe the results -> "file"|"yank"|"buffer"
----@param opts { from: integer, to: integer, extra_arg: any }
+---@param opts { from: integer, to: integer, extra_arg: any, result_set: integer, view: ResultView, sql: SqlStoreOptions, markup: MarkupStoreOptions }
 function core.call_store_result(id, format, output, opts)
   state.handler():call_store_result(id, format, output, opts)
 end
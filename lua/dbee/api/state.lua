@@ -40,6 +40,16 @@ local function setup_handler()
   vim.env.PATH = install.dir() .. pathsep .. vim.env.PATH
 
   m.handler = Handler:new(m.config.sources)
+  m.handler:configure({
+    result_window_size = m.config.result.window_size,
+    history_dir = m.config.history.directory,
+    history_max_age_s = m.config.history.max_age,
+    history_max_calls = m.config.history.max_calls,
+    history_max_size = m.config.history.max_size,
+    history_gc_interval_s = m.config.history.gc_interval,
+    history_omit_params = not m.config.history.store_params,
+    saved_queries_file = m.config.saved_queries.file,
+  })
   m.handler:add_helpers(m.config.extra_helpers)
 
   -- activate default connection if present
@@ -185,6 +185,19 @@ function ui.result_get_call()
   return state.result():get_call()
 end
 
+--- Sets the view of the displayed result (sort, filters and search) and displays its first page.
+--- The view is reset when another call or result set is displayed.
+---@param view? ResultView
+function ui.result_set_view(view)
+  state.result():set_view(view)
+end
+
+--- Gets the view of the displayed result.
+---@return ResultView
+function ui.result_get_view()
+  return state.result():get_view()
+end
+
 --- Display the currently selected page in results UI.
 function ui.result_page_current()
   state.result():page_current()
@@ -210,6 +223,16 @@ function ui.result_page_first()
   state.result():page_first()
 end
 
+--- Go to next result set in results UI and display its first page.
+function ui.result_set_next()
+  state.result():result_set_next()
+end
+
+--- Go to previous result set in results UI and display its first page.
+function ui.result_set_prev()
+  state.result():result_set_prev()
+end
+
 --- Open the result UI.
 ---@param winid integer
 function ui.result_show(winid)
@@ -8,6 +8,8 @@ local config = {}
 ---@field sources? Source[] list of connection sources
 ---@field extra_helpers? table<string, table<string, string>>
 ---@field float_options? table<string, any>
+---@field history? history_config
+---@field saved_queries? saved_queries_config
 ---@field drawer? drawer_config
 ---@field editor? editor_config
 ---@field result? result_config
@@ -25,7 +27,7 @@ local config = {}
 ---@divider -
 
 ---Configuration for result UI tile.
----@alias result_config { focus_result: boolean, mappings: key_mapping[], page_size: integer, progress: progress_config, window_options: table<string, any>, buffer_options: table<string, any> }
+---@alias result_config { focus_result: boolean, mappings: key_mapping[], page_size: integer, window_size: integer, progress: progress_config, window_options: table<string, any>, buffer_options: table<string, any> }
 
 ---Configuration for editor UI tile.
 ---@alias editor_config { directory: string, mappings: key_mapping[], window_options: table<string, any>, buffer_options: table<string, any> }
@@ -33,6 +35,12 @@ local config = {}
 ---Configuration for call log UI tile.
 ---@alias call_log_config { mappings: key_mapping[], disable_candies: boolean, candies: table<string, Candy>, window_options: table<string, any>, buffer_options: table<string, any> }
 
+---Configuration of call history and its retention.
+---@alias history_config { directory: string, max_age: integer, max_calls: integer, max_size: integer, gc_interval: integer, store_params: boolean }
+
+---Configuration of the saved queries library.
+---@alias saved_queries_config { file: string }
+
 ---Configuration for drawer UI tile.
 ---@alias drawer_config { disable_candies: boolean, candies: table<string, Candy>, mappings: key_mapping[], disable_help: boolean, window_options: table<string, any>, buffer_options: table<string, any> }
 
@@ -63,6 +71,30 @@ config.default = {
   -- options passed to floating windows - :h nvim_open_win()
   float_options = {},
 
+  -- call history (call log and archived results of calls)
+  history = {
+    -- directory where the history is stored
+    directory = vim.fn.stdpath("state") .. "/dbee/history",
+    -- calls older than this (in seconds) are removed (0 means no limit)
+    max_age = 30 * 24 * 60 * 60,
+    -- maximum number of calls kept per connection (0 means no limit)
+    max_calls = 200,
+    -- maximum size of all archived results on disk in bytes (0 means no limit)
+    max_size = 1024 * 1024 * 1024,
+    -- how often (in seconds) the limits above are enforced
+    gc_interval = 10 * 60,
+    -- store bind params of calls with their queries, so that calls can be rerun after a restart
+    -- (params are stored in plain text, disable it if they hold secrets)
+    store_params = true,
+  },
+
+  -- library of saved queries
+  saved_queries = {
+    -- JSON or YAML file (by extension) with saved queries - it can be edited by hand
+    -- or shared in a repository, changes on disk are picked up automatically
+    file = vim.fn.stdpath("data") .. "/dbee/saved_queries.json",
+  },
+
   -- drawer window config
   drawer = {
     -- these two option settings can be added to all UI elements and
@@ -235,6 +267,10 @@ config.default = {
     -- number of rows in the results set to display per page
     page_size = 100,
 
+    -- number of rows of a single result kept in memory,
+    -- the rest is read from the call archive on disk
+    window_size = 10000,
+
     -- whether to focus the result window after a query
     focus_result = true,
 
@@ -253,6 +289,9 @@ config.default = {
       { key = "H", mode = "", action = "page_prev" },
       { key = "E", mode = "", action = "page_last" },
       { key = "F", mode = "", action = "page_first" },
+      -- next/previous result set (for calls with multiple result sets)
+      { key = "]s", mode = "", action = "result_set_next" },
+      { key = "[s", mode = "", action = "result_set_prev" },
       -- yank rows as csv/json
       { key = "yaj", mode = "n", action = "yank_current_json" },
       { key = "yaj", mode = "v", action = "yank_selection_json" },
@@ -260,9 +299,27 @@ config.default = {
       { key = "yac", mode = "n", action = "yank_current_csv" },
       { key = "yac", mode = "v", action = "yank_selection_csv" },
       { key = "yaC", mode = "", action = "yank_all_csv" },
+      -- yank rows as INSERT statements (prompts for the target table)
+      { key = "yas", mode = "n", action = "yank_current_sql" },
+      { key = "yas", mode = "v", action = "yank_selection_sql" },
+      { key = "yaS", mode = "", action = "yank_all_sql" },
+      -- sort rows by the column under cursor (ascending/descending), sorting
+      -- by another column adds it to the sort
+      { key = "so", mode = "n", action = "sort_asc" },
+      { key = "sO", mode = "n", action = "sort_desc" },
+      -- filter rows by the column under cursor and search values of all columns
+      { key = "sf", mode = "n", action = "filter_column" },
+      { key = "s/", mode = "n", action = "search" },
+      -- reset sort, filters and search
+      { key = "sc", mode = "n", action = "clear_view" },
+      -- show stats of columns of the result (nulls, distinct values, min/max, most frequent values...)
+      { key = "ss", mode = "n", action = "column_stats" },
 
       -- cancel current call execution
       { key = "<C-c>", mode = "", action = "cancel_call" },
+      -- approve/reject execution of the current call (if it's awaiting confirmation)
+      { key = "A", mode = "", action = "approve_call" },
+      { key = "R", mode = "", action = "reject_call" },
     },
   },
 
@@ -285,6 +342,12 @@ config.default = {
       { key = "BS", mode = "n", action = "run_statement" },
       -- visually select the SQL statement under cursor
       { key = "SS", mode = "n", action = "select_statement" },
+      -- show the query plan of what's currently selected on the active connection
+      { key = "BE", mode = "v", action = "explain_selection" },
+      -- show the query plan of the SQL statement under cursor
+      { key = "BE", mode = "n", action = "explain_statement" },
+      -- jump to the location of the last query error (errors are also shown as diagnostics)
+      { key = "BG", mode = "n", action = "goto_error" },
     },
   },
 
@@ -298,8 +361,17 @@ config.default = {
     mappings = {
       -- show the result of the currently selected call record
       { key = "<CR>", mode = "", action = "show_result" },
-      -- cancel the currently selected call (if its still executing)
+      -- cancel the currently selected call (if its still executing or retrieving)
       { key = "<C-c>", mode = "", action = "cancel_call" },
+      -- remove the currently selected call and its results from history
+      { key = "dd", mode = "", action = "delete_call" },
+      -- save the query of the currently selected call to saved queries
+      { key = "S", mode = "", action = "save_query" },
+      -- execute the query of the currently selected call again
+      { key = "r", mode = "", action = "rerun_call" },
+      -- compare the result of the currently selected call with the result of a previously marked call
+      -- (the first press marks the call)
+      { key = "D", mode = "", action = "diff_call" },
     },
 
     -- candies (icons and highlights)
@@ -346,6 +418,21 @@ config.default = {
         icon_highlight = "Error",
         text_highlight = "",
       },
+      truncated = {
+        icon = "",
+        icon_highlight = "WarningMsg",
+        text_highlight = "",
+      },
+      timed_out = {
+        icon = "",
+        icon_highlight = "Error",
+        text_highlight = "",
+      },
+      awaiting_confirmation = {
+        icon = "",
+        icon_highlight = "WarningMsg",
+        text_highlight = "",
+      },
     },
   },
 
@@ -362,12 +449,15 @@ function config.validate(cfg)
     sources = { cfg.sources, "table" },
     extra_helpers = { cfg.extra_helpers, "table" },
     float_options = { cfg.float_options, "table" },
+    history = { cfg.history, "table" },
+    saved_queries = { cfg.saved_queries, "table" },
 
     drawer_disable_candies = { cfg.drawer.disable_candies, "boolean" },
     drawer_disable_help = { cfg.drawer.disable_help, "boolean" },
     drawer_candies = { cfg.drawer.candies, "table" },
     drawer_mappings = { cfg.drawer.mappings, "table" },
     result_page_size = { cfg.result.page_size, "number" },
+    result_window_size = { cfg.result.window_size, "number" },
     result_progress = { cfg.result.progress, "table" },
     result_mappings = { cfg.result.mappings, "table" },
     editor_mappings = { cfg.editor.mappings, "table" },
@@ -47,6 +47,37 @@
 ---| '"archived"'
 ---| '"archive_failed"'
 ---| '"canceled"'
+---| '"truncated"'
+---| '"timed_out"'
+---| '"awaiting_confirmation"'
+
+---How the result of a call is produced. Results of calls other than "query"
+---are derived from results of other calls, so these calls can't be rerun.
+---@alias call_kind
+---| '"query"'
+---| '"diff"'
+---| '"column_stats"'
+---| '"fan_out"'
+
+---Estimate of resources a query uses, made before it's executed.
+---Values which are not provided by the database are 0.
+---@class Estimate
+---@field bytes integer estimated number of bytes processed by the query
+---@field rows integer estimated number of rows produced by the query
+---@field detail string description of the estimate
+
+---Error reported by the database for a query.
+---Values which are not provided by the database are empty strings or 0.
+---@class QueryError
+---@field severity string severity of the error (e.g. "ERROR")
+---@field sqlstate string standard SQLSTATE code
+---@field code string vendor specific error code
+---@field message string
+---@field detail string
+---@field hint string
+---@field position integer 1-based character position of the error in the query
+---@field line integer 1-based line of the error in the query (if position is not reported)
+---@field column integer 1-based column of the error in the query (if position is not reported)
 
 ---Details and stats of a single call to database.
 ---@class CallDetails
@@ -56,6 +87,108 @@
 ---@field state call_state
 ---@field timestamp_us integer time in microseconds
 ---@field error? string error message in case of error
+---@field query_error? QueryError structured error in case the database reported an error for the query
+---@field result_sets integer number of result sets produced by the call
+---@field estimate? Estimate estimate of the query (if it was estimated before execution)
+---@field conn_id connection_id connection which executed the call (empty for calls from older history)
+---@field conn_name string name of the connection at the time of execution
+---@field kind call_kind how the result of the call is produced
+---@field database string database (or schema) selected on the connection at the time of execution (empty if unknown)
+---@field row_count integer number of rows retrieved in all result sets
+---@field rows_affected? integer number of rows affected by the query (if reported by the adapter)
+---@field archive_size integer size of the archived results on disk in bytes
+---@field query_id string server side identifier of the query, e.g. query id or job id (empty if not reported by the adapter)
+---@field parent_call_id? call_id call which was rerun by this call
+
+---Filters, sorting and pagination of call search.
+---Filters which are not set match all calls.
+---@class CallSearchOptions
+---@field query? string case insensitive substring of the query (or a regular expression if regex is set)
+---@field regex? boolean treat query as a regular expression
+---@field states? call_state[] states of calls
+---@field conn_ids? connection_id[] connections of calls
+---@field since_us? integer earliest timestamp of calls in microseconds
+---@field until_us? integer latest timestamp of calls in microseconds
+---@field min_duration_us? integer minimum time taken by calls in microseconds
+---@field max_duration_us? integer maximum time taken by calls in microseconds
+---@field has_error? boolean only calls with (true) or without (false) an error
+---@field sort_by? "timestamp"|"duration"|"query"|"state"|"connection"|"rows" sort field (defaults to "timestamp")
+---@field ascending? boolean sort in ascending order (calls are sorted in descending order by default)
+---@field offset? integer number of matching calls to skip
+---@field limit? integer maximum number of calls to return
+
+---Page of calls which matched the search.
+---@class CallSearchResult
+---@field calls CallDetails[]
+---@field total integer number of all calls which matched the search
+
+---Options of comparing results of two calls.
+---@class CallDiffOptions
+---@field key_columns? string[] columns which identify the same row in both results (rows are compared by position if empty)
+---@field include_unchanged? boolean include rows which are the same in both results
+---@field max_rows? integer maximum number of rows of each result compared by key columns (defaults to 100000, negative for no limit)
+---@field result_set? integer zero based index of the compared result set
+
+---Options of column stats of a call result.
+---@class ColumnStatsOptions
+---@field columns? string[] columns to compute stats of (all columns if empty)
+---@field top? integer number of the most frequent values (defaults to 5)
+---@field result_set? integer zero based index of the result set
+
+---Options of the "sql" store format, which renders rows as statements loading them to a table.
+---@class SqlStoreOptions
+---@field table string target table, optionally qualified (e.g. "schema.table", parts with dots can be quoted: '"my.schema".table')
+---@field statement? "insert"|"insert_multi"|"update"|"upsert" kind of statements (defaults to "insert")
+---@field key_columns? string[] columns which identify rows of "update" and "upsert" statements
+---@field dialect? string database type which determines quoting (defaults to the type of the call's connection)
+---@field batch_size? integer rows per "insert_multi" statement (defaults to 100)
+
+---Options of the "markdown", "html" and "asciidoc" store formats.
+---@class MarkupStoreOptions
+---@field align? boolean align columns by type: numbers right, booleans center and the rest left (defaults to true)
+---@field max_cell_width? integer truncate cells longer than this number of characters (cells aren't truncated if 0)
+
+---Comparison of a result view filter.
+---@alias filter_operator
+---| '"eq"' equal to the value
+---| '"ne"' not equal to the value
+---| '"contains"' contains the value (case insensitive)
+---| '"regex"' matches the value as a regular expression
+---| '"null"' is null
+---| '"not_null"' is not null
+---| '"gt"' greater than the value
+---| '"ge"' greater than or equal to the value
+---| '"lt"' less than the value
+---| '"le"' less than or equal to the value
+
+---Sort column of a result view.
+---@class ResultSort
+---@field column string
+---@field descending? boolean
+
+---Filter of a result view. Values are compared as numbers if both of them are numeric.
+---Timestamps are compared as times with values which are dates or timestamps (e.g. "2024-01-02"
+---or "2024-01-02T10:00:00Z"), values without a zone are in the zone of the timestamps.
+---@class ResultFilter
+---@field column string
+---@field operator filter_operator
+---@field value? string
+
+---View of result rows, which is computed by dbee over the retrieved rows without querying the database.
+---Rows are filtered first and then sorted.
+---@class ResultView
+---@field sort? ResultSort[] sort columns in order of precedence
+---@field filters? ResultFilter[] filters which all have to match
+---@field search? string case insensitive text which any value of the row has to contain
+
+---Progress of a call which is executing or retrieving (data of "call_progress" event).
+---@class CallProgress
+---@field call_id call_id
+---@field rows integer number of rows retrieved so far
+---@field estimated_rows integer estimated total number of rows (0 if unknown)
+---@field rows_read integer rows read by the database server (0 if not reported by the adapter)
+---@field bytes_read integer bytes read by the database server (0 if not reported by the adapter)
+---@field elapsed_us integer time since the start of the call in microseconds
 
 ---@divider -
 ---@tag dbee.ref.types.connection
@@ -63,6 +196,16 @@
 ---Connection related types.
 ---@brief ]]
 
+---A query saved to the library of saved queries.
+---@class SavedQuery
+---@field id string defaults to the name for queries written to the file by hand
+---@field name string
+---@field description? string
+---@field tags? string[]
+---@field connection_type? string type of connections the query is meant for (e.g. "postgres")
+---@field connection_id? connection_id connection the query is meant for
+---@field query string
+
 ---ID of a connection.
 ---@alias connection_id string
 
@@ -72,6 +215,30 @@
 ---@field name string
 ---@field type string
 ---@field url string
+---@field timeout_ms? integer default timeout of calls in milliseconds
+---@field max_rows? integer default maximum number of rows retrieved by a call
+---@field max_bytes? integer default maximum size of rows retrieved by a call
+---@field confirm_above_bytes? integer estimated bytes processed by a query above which the call awaits confirmation
+
+---Options of execution of a query on many connections.
+---@class ExecuteManyOptions
+---@field concurrency? integer number of connections the query is executed on at once (defaults to 4)
+---@field params? any[]|table<string, any> bind variables of the query
+---@field timeout_ms? integer timeout of every call in milliseconds
+---@field max_rows? integer maximum number of rows retrieved by every call
+---@field max_bytes? integer maximum size of rows retrieved by every call
+---@field confirm_above_bytes? integer estimated bytes processed by a query above which a call awaits confirmation
+
+---Node of a query plan, normalized across databases.
+---Numeric values which are not provided by the database are 0.
+---@class PlanNode
+---@field operation string operation performed by the node (e.g. "Seq Scan")
+---@field detail string relation, index, condition... of the node
+---@field cost number estimated cost of the node (including its children)
+---@field rows number estimated number of rows produced by the node
+---@field actual_rows number number of rows produced by the node (only with analyze)
+---@field time_us integer time spent in the node in microseconds (only with analyze)
+---@field children PlanNode[]
 
 ---@divider -
 ---@tag dbee.ref.types.structure
@@ -103,8 +270,12 @@
 ---Avaliable core events.
 ---@alias core_event_name
 ---| '"call_state_changed"' {call}
+---| '"call_progress"' {call_id, rows, estimated_rows, rows_read, bytes_read, elapsed_us}
+---| '"call_deleted"' {conn_id, call_id}
+---| '"saved_queries_changed"' {}
 ---| '"current_connection_changed"' {conn_id}
 ---| '"database_selected"' {conn_id, database_name}
+---| '"transaction_state_changed"' {conn_id, active}
 
 ---Available editor events.
 ---@alias editor_event_name
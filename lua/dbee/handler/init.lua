@@ -30,6 +30,11 @@ function Handler:new(sources)
   return o
 end
 
+---@param opts { result_window_size: integer, history_dir: string, history_max_age_s: integer, history_max_calls: integer, history_max_size: integer, history_gc_interval_s: integer, history_omit_params: boolean, saved_queries_file: string }
+function Handler:configure(opts)
+  vim.fn.DbeeConfigure(opts)
+end
+
 ---@param event core_event_name
 ---@param listener event_listener
 function Handler:register_event_listener(event, listener)
@@ -231,10 +236,76 @@ function Handler:connection_is_connected(id)
 end
 
 ---@param id connection_id
+function Handler:connection_begin_transaction(id)
+  vim.fn.DbeeConnectionBeginTransaction(id)
+end
+
+---@param id connection_id
+function Handler:connection_commit_transaction(id)
+  vim.fn.DbeeConnectionCommitTransaction(id)
+end
+
+---@param id connection_id
+function Handler:connection_rollback_transaction(id)
+  vim.fn.DbeeConnectionRollbackTransaction(id)
+end
+
+---@param id connection_id
+---@return boolean
+function Handler:connection_in_transaction(id)
+  local ret = vim.fn.DbeeConnectionInTransaction(id)
+  if not ret or ret == vim.NIL then
+    return false
+  end
+  return ret
+end
+
+---@param id connection_id
+---@param query string
+---@param opts? { params: any[]|table<string, any>, timeout_ms: integer, max_rows: integer, max_bytes: integer, confirm_above_bytes: integer }
+---@return CallDetails
+function Handler:connection_execute(id, query, opts)
+  opts = opts or {}
+  return vim.fn.DbeeConnectionExecute(id, query, {
+    params = opts.params,
+    timeout_ms = opts.timeout_ms,
+    max_rows = opts.max_rows,
+    max_bytes = opts.max_bytes,
+    confirm_above_bytes = opts.confirm_above_bytes,
+  })
+end
+
+---@param ids connection_id[]
 ---@param query string
+---@param opts? ExecuteManyOptions
 ---@return CallDetails
-function Handler:connection_execute(id, query)
-  return vim.fn.DbeeConnectionExecute(id, query)
+function Handler:execute_many(ids, query, opts)
+  opts = opts or {}
+  return vim.fn.DbeeExecuteMany(ids, query, {
+    params = opts.params,
+    concurrency = opts.concurrency,
+    timeout_ms = opts.timeout_ms,
+    max_rows = opts.max_rows,
+    max_bytes = opts.max_bytes,
+    confirm_above_bytes = opts.confirm_above_bytes,
+  })
+end
+
+---@param id connection_id
+---@param query string
+---@param opts? { analyze: boolean, confirm: boolean, buffer: integer }
+---@return PlanNode?
+function Handler:connection_explain(id, query, opts)
+  opts = opts or {}
+  local ret = vim.fn.DbeeConnectionExplain(
+    id,
+    query,
+    { analyze = opts.analyze, confirm = opts.confirm, buffer = opts.buffer }
+  )
+  if not ret or ret == vim.NIL then
+    return nil
+  end
+  return ret
 end
 
 ---@param id connection_id
@@ -297,31 +368,172 @@ function Handler:connection_get_calls(id)
   return ret
 end
 
+---@param opts? CallSearchOptions
+---@return CallSearchResult
+function Handler:search_calls(opts)
+  opts = opts or {}
+  local ret = vim.fn.DbeeSearchCalls({
+    query = opts.query,
+    regex = opts.regex,
+    states = opts.states,
+    conn_ids = opts.conn_ids,
+    since_us = opts.since_us,
+    until_us = opts.until_us,
+    min_duration_us = opts.min_duration_us,
+    max_duration_us = opts.max_duration_us,
+    has_error = opts.has_error,
+    sort_by = opts.sort_by or "timestamp",
+    ascending = opts.ascending,
+    offset = opts.offset,
+    limit = opts.limit,
+  })
+  if not ret or ret == vim.NIL then
+    return { calls = {}, total = 0 }
+  end
+  return ret
+end
+
+---@param id connection_id
+function Handler:connection_purge_history(id)
+  vim.fn.DbeeConnectionPurgeHistory(id)
+end
+
 ---@param id call_id
 function Handler:call_cancel(id)
   vim.fn.DbeeCallCancel(id)
 end
 
+---@param id call_id
+---@param approve boolean
+function Handler:call_confirm(id, approve)
+  vim.fn.DbeeCallConfirm(id, approve)
+end
+
+---@param id call_id
+function Handler:call_delete(id)
+  vim.fn.DbeeCallDelete(id)
+end
+
+---@param id call_id
+---@param conn_id? connection_id
+---@return CallDetails
+function Handler:call_rerun(id, conn_id)
+  return vim.fn.DbeeCallRerun(id, conn_id or "")
+end
+
+---@param from_id call_id
+---@param to_id call_id
+---@param opts? CallDiffOptions
+---@return CallDetails
+function Handler:call_diff(from_id, to_id, opts)
+  opts = opts or {}
+  return vim.fn.DbeeCallDiff(from_id, to_id, {
+    key_columns = opts.key_columns,
+    include_unchanged = opts.include_unchanged or false,
+    max_rows = opts.max_rows or 0,
+    result_set = opts.result_set or 0,
+  })
+end
+
+---@param id call_id
+---@param opts? ColumnStatsOptions
+---@return CallDetails
+function Handler:call_column_stats(id, opts)
+  opts = opts or {}
+  return vim.fn.DbeeCallColumnStats(id, {
+    result_set = opts.result_set or 0,
+    columns = opts.columns,
+    top = opts.top,
+  })
+end
+
+---@param id call_id
+---@param opts? { name: string, description: string, tags: string[] }
+---@return SavedQuery
+function Handler:call_save_query(id, opts)
+  opts = opts or {}
+  return vim.fn.DbeeCallSaveQuery(id, {
+    name = opts.name or "",
+    description = opts.description,
+    tags = opts.tags,
+  })
+end
+
+---@return SavedQuery[]
+function Handler:saved_query_list()
+  local ret = vim.fn.DbeeSavedQueryList()
+  if not ret or ret == vim.NIL then
+    return {}
+  end
+  return ret
+end
+
+---@param id string
+---@return SavedQuery
+function Handler:saved_query_get(id)
+  return vim.fn.DbeeSavedQueryGet(id)
+end
+
+---@param query SavedQuery
+---@return SavedQuery
+function Handler:saved_query_save(query)
+  return vim.fn.DbeeSavedQuerySave({
+    id = query.id or "",
+    name = query.name,
+    description = query.description,
+    tags = query.tags,
+    connection_type = query.connection_type,
+    connection_id = query.connection_id,
+    query = query.query,
+  })
+end
+
+---@param id string
+function Handler:saved_query_delete(id)
+  vim.fn.DbeeSavedQueryDelete(id)
+end
+
+-- Converts the view of a result to the form expected by endpoints (nil if the view shows all rows).
+---@param view? ResultView
+---@return table?
+local function result_view(view)
+  if not view then
+    return nil
+  end
+  local sort = view.sort or {}
+  local filters = view.filters or {}
+  local search = view.search or ""
+  if vim.tbl_isempty(sort) and vim.tbl_isempty(filters) and search == "" then
+    return nil
+  end
+  return { sort = sort, filters = filters, search = search }
+end
+
 ---@param id call_id
 ---@param bufnr integer
 ---@param from integer
 ---@param to integer
----@return integer # total number of rows
-function Handler:call_display_result(id, bufnr, from, to)
-  local length = vim.fn.DbeeCallDisplayResult(id, { buffer = bufnr, from = from, to = to })
+---@param result_set? integer zero based index of the result set (defaults to the first one)
+---@param view? ResultView sort, filters and search of rows
+---@return integer # total number of rows (of the view)
+function Handler:call_display_result(id, bufnr, from, to, result_set, view)
+  local length = vim.fn.DbeeCallDisplayResult(
+    id,
+    { result_set = result_set or 0, buffer = bufnr, from = from, to = to, view = result_view(view) }
+  )
   if not length or length == vim.NIL then
     return 0
   end
   return length
 end
 
----@alias store_format "csv"|"json"|"table"
+---@alias store_format "csv"|"json"|"table"|"sql"|"markdown"|"html"|"asciidoc"
 ---@alias store_output "file"|"yank"|"buffer"
 
 ---@param id call_id
 ---@param format store_format format of the output
 ---@param output store_output where to pipe the results
----@param opts { from: integer, to: integer, extra_arg: any }
+---@param opts { from: integer, to: integer, extra_arg: any, result_set: integer, view: ResultView, sql: SqlStoreOptions, markup: MarkupStoreOptions }
 function Handler:call_store_result(id, format, output, opts)
   opts = opts or {}
 
@@ -329,8 +541,12 @@ function Handler:call_store_result(id, format, output, opts)
   local to = opts.to or -1
 
   vim.fn.DbeeCallStoreResult(id, format, output, {
+    result_set = opts.result_set or 0,
     from = from,
     to = to,
+    view = result_view(opts.view),
+    sql = opts.sql,
+    markup = opts.markup,
     extra_arg = opts.extra_arg,
   })
 end
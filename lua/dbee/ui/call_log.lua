@@ -12,6 +12,8 @@ local common = require("dbee.ui.common")
 ---@field private bufnr integer
 ---@field private candies table<string, Candy> map of eye-candy stuff (icons, highlight)
 ---@field private current_connection_id? connection_id
+---@field private calls table<call_id, CallDetails> calls of the current connection by their id
+---@field private diff_from? CallDetails call marked to be compared with another call
 ---@field private hover_close? fun() function that closes the hover window
 ---@field private window_options table<string, any> a table of window options.
 ---@field private buffer_options table<string, any> a table of buffer options.
@@ -43,6 +45,7 @@ function CallLogUI:new(handler, result, opts)
     candies = candies,
     hover_close = function() end,
     current_connection_id = (handler:get_current_connection() or {}).id,
+    calls = {},
     window_options = vim.tbl_extend("force", {
       wrap = false,
       winfixheight = true,
@@ -77,6 +80,10 @@ function CallLogUI:new(handler, result, opts)
     ---@diagnostic disable-next-line
     o:on_current_connection_changed(data)
   end)
+  handler:register_event_listener("call_deleted", function(data)
+    ---@diagnostic disable-next-line
+    o:on_call_deleted(data)
+  end)
 
   return o
 end
@@ -88,6 +95,16 @@ function CallLogUI:on_call_state_changed(_)
   self:refresh()
 end
 
+-- event listener for calls removed from history
+---@private
+---@param data { conn_id: connection_id, call_id: call_id }
+function CallLogUI:on_call_deleted(data)
+  if data.conn_id ~= self.current_connection_id then
+    return
+  end
+  self:refresh()
+end
+
 -- event listener for current connection change
 ---@private
 ---@param data { conn_id: connection_id }
@@ -156,9 +173,20 @@ function CallLogUI:create_tree(bufnr)
         state_preview = call_state_initials(call.state)
       end
 
+      -- reruns are indented under the original call
+      local query_len = 40
+      if node:get_depth() > 1 then
+        line:append("  ")
+        query_len = query_len - 2
+      end
+
       line:append(make_length(state_preview, 3), candy.icon_highlight)
       line:append(" ┃ ", "NonText")
-      line:append(make_length(string.gsub(call.query, "\n", " "), 40), candy.text_highlight)
+      line:append(make_length(string.gsub(call.query, "\n", " "), query_len), candy.text_highlight)
+
+      if node:has_children() then
+        line:append(string.format(" (%d reruns)", #node:get_child_ids()), "NonText")
+      end
 
       return line
     end,
@@ -185,7 +213,12 @@ function CallLogUI:get_actions()
         return
       end
 
-      if call.state == "archived" or call.state == "retrieving" then
+      if
+        call.state == "archived"
+        or call.state == "retrieving"
+        or call.state == "truncated"
+        or call.state == "timed_out"
+      then
         self.result:set_call(call)
         self.result:page_current()
       end
@@ -202,6 +235,99 @@ function CallLogUI:get_actions()
 
       self.handler:call_cancel(call.id)
     end,
+    delete_call = function()
+      local node = self.tree:get_node()
+      if not node then
+        return
+      end
+      local call = node.call
+      if not call then
+        return
+      end
+
+      self.handler:call_delete(call.id)
+    end,
+    rerun_call = function()
+      local node = self.tree:get_node()
+      if not node then
+        return
+      end
+      local call = node.call
+      if not call then
+        return
+      end
+      if call.kind and call.kind ~= "query" then
+        local msg = "Results of " .. call.kind .. " calls are derived from other calls, they can't be rerun"
+        utils.log("warn", msg, "call log")
+        return
+      end
+
+      self.handler:call_rerun(call.id, self.current_connection_id)
+    end,
+    diff_call = function()
+      local node = self.tree:get_node()
+      if not node then
+        return
+      end
+      local call = node.call
+      if not call then
+        return
+      end
+
+      -- first call is only marked
+      if not self.diff_from or self.diff_from.id == call.id then
+        self.diff_from = call
+        utils.log("info", "Call marked for diff, select another call to compare it with", "call log")
+        return
+      end
+
+      local from = self.diff_from
+      self.diff_from = nil
+
+      common.float_prompt({ { key = "key_columns" } }, {
+        title = "Diff Calls (key columns are comma separated, rows are compared by position if empty)",
+        callback = function(res)
+          local keys = vim.split(res.key_columns or "", ",", { trimempty = true })
+          for i, key in ipairs(keys) do
+            keys[i] = vim.trim(key)
+          end
+
+          local diff = self.handler:call_diff(from.id, call.id, { key_columns = keys })
+          self.result:set_call(diff)
+        end,
+      })
+    end,
+    save_query = function()
+      local node = self.tree:get_node()
+      if not node then
+        return
+      end
+      local call = node.call
+      if not call then
+        return
+      end
+
+      local name = vim.split(vim.trim(call.query), "\n")[1]
+      local prompt = {
+        { key = "name", value = name },
+        { key = "description" },
+        { key = "tags" },
+      }
+      common.float_prompt(prompt, {
+        title = "Save Query",
+        callback = function(res)
+          local tags = vim.split(res.tags or "", ",", { trimempty = true })
+          for i, tag in ipairs(tags) do
+            tags[i] = vim.trim(tag)
+          end
+          self.handler:call_save_query(call.id, {
+            name = res.name,
+            description = res.description,
+            tags = tags,
+          })
+        end,
+      })
+    end,
   }
 end
 
@@ -221,6 +347,11 @@ function CallLogUI:refresh()
   end
   local calls = self.handler:connection_get_calls(self.current_connection_id)
 
+  self.calls = {}
+  for _, c in ipairs(calls) do
+    self.calls[c.id] = c
+  end
+
   -- dummy node if no calls
   if vim.tbl_isempty(calls) then
     self.tree:set_nodes { NuiTree.Node { id = tostring(math.random()), text = "Call log will be displayed here!" } }
@@ -232,9 +363,39 @@ function CallLogUI:refresh()
     return k1.timestamp_us > k2.timestamp_us
   end)
 
-  local nodes = {}
+  -- group reruns under the original call, following the chain of parents
+  ---@param call CallDetails
+  ---@return call_id
+  local function root_id(call)
+    local seen = {}
+    while call.parent_call_id and self.calls[call.parent_call_id] and not seen[call.id] do
+      seen[call.id] = true
+      call = self.calls[call.parent_call_id]
+    end
+    return call.id
+  end
+
+  local roots = {}
+  local reruns = {}
   for _, c in ipairs(calls) do
-    table.insert(nodes, NuiTree.Node { id = tostring(math.random()), call = c })
+    local id = root_id(c)
+    if id == c.id then
+      table.insert(roots, c)
+    else
+      reruns[id] = reruns[id] or {}
+      table.insert(reruns[id], c)
+    end
+  end
+
+  local nodes = {}
+  for _, c in ipairs(roots) do
+    local children = {}
+    for _, rerun in ipairs(reruns[c.id] or {}) do
+      table.insert(children, NuiTree.Node { id = rerun.id, call = rerun })
+    end
+    local node = NuiTree.Node({ id = c.id, call = c }, children)
+    node:expand()
+    table.insert(nodes, node)
   end
 
   self.tree:set_nodes(nodes)
@@ -288,6 +449,42 @@ function CallLogUI:configure_preview(bufnr)
         { key = "timestamp", value = tostring(os.date("%c", (call.timestamp_us or 0) / 1000000)) },
       }
 
+      if call.conn_name and call.conn_name ~= "" then
+        table.insert(call_summary, { key = "connection", value = call.conn_name })
+      end
+      if call.database and call.database ~= "" then
+        table.insert(call_summary, { key = "database", value = call.database })
+      end
+      table.insert(call_summary, { key = "rows", value = tostring(call.row_count or 0) })
+      if call.rows_affected then
+        table.insert(call_summary, { key = "rows_affected", value = tostring(call.rows_affected) })
+      end
+      if call.archive_size and call.archive_size > 0 then
+        table.insert(call_summary, { key = "archive_size", value = string.format("%d bytes", call.archive_size) })
+      end
+      if call.query_id and call.query_id ~= "" then
+        table.insert(call_summary, { key = "query_id", value = call.query_id })
+      end
+      if call.state == "archived" or call.state == "archive_failed" or call.state == "truncated" then
+        -- name of the table with the result in scratch connections
+        local short_id = call.id:gsub("-", ""):sub(1, 8):lower()
+        table.insert(call_summary, { key = "table", value = "call_" .. short_id })
+      end
+
+      -- changes since the rerun call
+      local parent = call.parent_call_id and self.calls[call.parent_call_id]
+      if parent then
+        table.insert(call_summary, { key = "rerun_of", value = parent.id })
+        table.insert(call_summary, {
+          key = "time_change",
+          value = string.format("%+.3f seconds", ((call.time_taken_us or 0) - (parent.time_taken_us or 0)) / 1000000),
+        })
+        table.insert(call_summary, {
+          key = "rows_change",
+          value = string.format("%+d", (call.row_count or 0) - (parent.row_count or 0)),
+        })
+      end
+
       if call.error and call.error ~= "" then
         table.insert(call_summary, { key = "error", value = string.gsub(call.error, "\n", " ") })
       end
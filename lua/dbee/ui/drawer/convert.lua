@@ -259,13 +259,29 @@ local function handler_real_nodes(handler, result)
         -- remove connection
         action_3 = delete_action,
         -- connect/disconnect toggle
-        action_4 = function(cb)
+        action_4 = function(cb, select)
           local ok, is_connected = pcall(handler.connection_is_connected, handler, conn.id)
           if not ok then
             is_connected = false
           end
           
           if is_connected then
+            -- warn about uncommitted work, disconnecting rolls it back
+            local tx_ok, in_transaction = pcall(handler.connection_in_transaction, handler, conn.id)
+            if tx_ok and in_transaction then
+              select {
+                title = "Roll Back Open Transaction?",
+                items = { "Yes", "No" },
+                on_confirm = function(selection)
+                  if selection == "Yes" then
+                    pcall(handler.connection_disconnect, handler, conn.id)
+                  end
+                  cb()
+                end,
+              }
+              return
+            end
+
             pcall(handler.connection_disconnect, handler, conn.id)
           else
             pcall(handler.connection_connect, handler, conn.id)
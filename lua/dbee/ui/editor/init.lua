@@ -18,8 +18,13 @@ local welcome = require("dbee.ui.editor.welcome")
 ---@field private event_callbacks table<editor_event_name, event_listener[]> callbacks for events
 ---@field private window_options table<string, any> a table of window options.
 ---@field private buffer_options table<string, any> a table of buffer options for all notes.
+---@field private call_buffers table<call_id, integer> buffers from which calls were executed
+---@field private last_error? { bufnr: integer, row: integer, col: integer } location of the last query error
 local EditorUI = {}
 
+-- namespace of query error diagnostics
+local error_namespace = vim.api.nvim_create_namespace("dbee_query_error")
+
 ---@param handler Handler
 ---@param result ResultUI
 ---@param opts? editor_config
@@ -41,6 +46,7 @@ function EditorUI:new(handler, result, opts)
     result = result,
     notes = {},
     event_callbacks = {},
+    call_buffers = {},
     directory = opts.directory or vim.fn.stdpath("state") .. "/dbee/notes",
     mappings = opts.mappings,
     window_options = vim.tbl_extend("force", {}, opts.window_options or {}),
@@ -53,6 +59,10 @@ function EditorUI:new(handler, result, opts)
   setmetatable(o, self)
   self.__index = self
 
+  handler:register_event_listener("call_state_changed", function(data)
+    o:on_call_state_changed(data)
+  end)
+
   -- set the current note as first note from global namespace
   local global_notes = o:namespace_get_notes("global")
   if not vim.tbl_isempty(global_notes) then
@@ -111,14 +121,51 @@ function EditorUI:get_actions()
       local lines = vim.api.nvim_buf_get_lines(bufnr, 0, -1, false)
       local query = table.concat(lines, "\n")
 
-      local conn = self.handler:get_current_connection()
-      if not conn then
+      self:execute(bufnr, query)
+    end,
+    run_selection = function()
+      local srow, scol, erow, ecol = utils.visual_selection()
+
+      local selection = vim.api.nvim_buf_get_text(0, srow, scol, erow, ecol, {})
+      local query = table.concat(selection, "\n")
+
+      self:execute(vim.api.nvim_get_current_buf(), query)
+    end,
+    run_statement = function()
+      if not self.winid or not vim.api.nvim_win_is_valid(self.winid) then
+        return
+      end
+
+      local bufnr = vim.api.nvim_win_get_buf(self.winid)
+      local cursor_pos = vim.api.nvim_win_get_cursor(self.winid)
+      local row = cursor_pos[1] - 1 -- 0-indexed
+
+      -- Get SQL statement at cursor
+      local query = utils.get_sql_statement_at_cursor(bufnr, row)
+
+      if not query or query == "" then
+        vim.notify("No SQL statement found at cursor", vim.log.levels.WARN)
         return
       end
-      local call = self.handler:connection_execute(conn.id, query)
-      self.result:set_call(call)
+
+      self:execute(bufnr, query)
     end,
-    run_selection = function()
+    select_statement = function()
+      utils.select_sql_statement_at_cursor()
+    end,
+    goto_error = function()
+      local loc = self.last_error
+      if not loc or not vim.api.nvim_buf_is_valid(loc.bufnr) then
+        return
+      end
+      if not self.winid or not vim.api.nvim_win_is_valid(self.winid) then
+        return
+      end
+      vim.api.nvim_win_set_buf(self.winid, loc.bufnr)
+      vim.api.nvim_set_current_win(self.winid)
+      pcall(vim.api.nvim_win_set_cursor, self.winid, { loc.row + 1, loc.col })
+    end,
+    explain_selection = function()
       local srow, scol, erow, ecol = utils.visual_selection()
 
       local selection = vim.api.nvim_buf_get_text(0, srow, scol, erow, ecol, {})
@@ -128,10 +175,9 @@ function EditorUI:get_actions()
       if not conn then
         return
       end
-      local call = self.handler:connection_execute(conn.id, query)
-      self.result:set_call(call)
+      self.result:display_plan(conn.id, query)
     end,
-    run_statement = function()
+    explain_statement = function()
       if not self.winid or not vim.api.nvim_win_is_valid(self.winid) then
         return
       end
@@ -140,7 +186,6 @@ function EditorUI:get_actions()
       local cursor_pos = vim.api.nvim_win_get_cursor(self.winid)
       local row = cursor_pos[1] - 1 -- 0-indexed
 
-      -- Get SQL statement at cursor
       local query = utils.get_sql_statement_at_cursor(bufnr, row)
 
       if not query or query == "" then
@@ -152,15 +197,70 @@ function EditorUI:get_actions()
       if not conn then
         return
       end
-      local call = self.handler:connection_execute(conn.id, query)
-      self.result:set_call(call)
-    end,
-    select_statement = function()
-      utils.select_sql_statement_at_cursor()
+      self.result:display_plan(conn.id, query)
     end,
   }
 end
 
+-- Executes the query from the buffer on the current connection.
+---@private
+---@param bufnr integer
+---@param query string
+function EditorUI:execute(bufnr, query)
+  local conn = self.handler:get_current_connection()
+  if not conn then
+    return
+  end
+
+  -- errors of previous calls are outdated
+  vim.diagnostic.reset(error_namespace, bufnr)
+
+  local call = self.handler:connection_execute(conn.id, query)
+  self.call_buffers[call.id] = bufnr
+  self.result:set_call(call)
+end
+
+-- event listener for calls - marks query errors in buffers the calls were executed from
+---@private
+---@param data { call: CallDetails }
+function EditorUI:on_call_state_changed(data)
+  local call = data.call
+  local bufnr = self.call_buffers[call.id]
+  if not bufnr then
+    return
+  end
+  if call.state ~= "executing_failed" and call.state ~= "retrieving_failed" then
+    return
+  end
+  self.call_buffers[call.id] = nil
+
+  local qe = call.query_error
+  if not qe or not vim.api.nvim_buf_is_valid(bufnr) then
+    return
+  end
+
+  local row, col = utils.locate_query_error(bufnr, call.query, qe)
+  if not row then
+    return
+  end
+
+  local message = qe.message
+  if qe.hint ~= "" then
+    message = message .. "\nHint: " .. qe.hint
+  end
+  vim.diagnostic.set(error_namespace, bufnr, {
+    {
+      lnum = row,
+      col = col,
+      message = message,
+      code = qe.sqlstate ~= "" and qe.sqlstate or qe.code,
+      severity = vim.diagnostic.severity.ERROR,
+      source = "dbee",
+    },
+  })
+  self.last_error = { bufnr = bufnr, row = row, col = col }
+end
+
 ---Triggers an in-built action.
 ---@param action string
 function EditorUI:do_action(action)
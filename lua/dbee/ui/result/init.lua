@@ -8,12 +8,17 @@ local common = require("dbee.ui.common")
 ---@field private winid? integer
 ---@field private bufnr integer
 ---@field private current_call? CallDetails
+---@field private current_progress? CallProgress last progress of the current call
+---@field private result_length integer number of rows of the displayed result set
 ---@field private page_size integer
 ---@field private focus_result boolean
 ---@field private mappings key_mapping[]
 ---@field private page_index integer index of the current page
 ---@field private page_ammount integer number of pages in the current result set
+---@field private result_set integer zero based index of the displayed result set
+---@field private view ResultView sort, filters and search of the displayed result set
 ---@field private stop_progress fun() function that stops progress display
+---@field private set_progress_details fun(details: string) function that updates details of progress display
 ---@field private progress_opts progress_config
 ---@field private window_options table<string, any> a table of window options.
 ---@field private buffer_options table<string, any> a table of buffer options.
@@ -35,9 +40,13 @@ function ResultUI:new(handler, opts)
     page_size = opts.page_size or 100,
     page_index = 0,
     page_ammount = 0,
+    result_set = 0,
+    result_length = 0,
+    view = {},
     focus_result = opts.focus_result,
     mappings = opts.mappings or {},
     stop_progress = function() end,
+    set_progress_details = function(_) end,
     progress_opts = opts.progress or {},
     window_options = vim.tbl_extend("force", {
       wrap = false,
@@ -67,6 +76,14 @@ function ResultUI:new(handler, opts)
     o:on_call_state_changed(data)
   end)
 
+  handler:register_event_listener("call_progress", function(data)
+    o:on_call_progress(data)
+  end)
+
+  handler:register_event_listener("call_deleted", function(data)
+    o:on_call_deleted(data)
+  end)
+
   return o
 end
 
@@ -91,7 +108,19 @@ function ResultUI:on_call_state_changed(data)
   elseif call.state == "retrieving" then
     self.stop_progress()
     self:page_current()
-  elseif call.state == "executing_failed" or call.state == "retrieving_failed" or call.state == "canceled" then
+  elseif call.state == "truncated" then
+    -- refresh the status in winbar
+    self.stop_progress()
+    self:page_current()
+  elseif call.state == "awaiting_confirmation" then
+    self.stop_progress()
+    self:display_confirmation()
+  elseif
+    call.state == "executing_failed"
+    or call.state == "retrieving_failed"
+    or call.state == "canceled"
+    or call.state == "timed_out"
+  then
     self.stop_progress()
     self:display_status()
   else
@@ -99,13 +128,55 @@ function ResultUI:on_call_state_changed(data)
   end
 end
 
+-- event listener for calls removed from history
+---@private
+---@param data { conn_id: connection_id, call_id: call_id }
+function ResultUI:on_call_deleted(data)
+  -- we only care about the current call
+  if not self.current_call or data.call_id ~= self.current_call.id then
+    return
+  end
+
+  self:set_call(nil)
+
+  vim.api.nvim_buf_set_option(self.bufnr, "modifiable", true)
+  vim.api.nvim_buf_set_lines(self.bufnr, 0, -1, false, { "Call was removed from history" })
+  vim.api.nvim_buf_set_option(self.bufnr, "modifiable", false)
+
+  self:set_default_result_window()
+end
+
+-- event listener for progress of calls
+---@private
+---@param data CallProgress
+function ResultUI:on_call_progress(data)
+  -- we only care about the current call
+  if not self.current_call or data.call_id ~= self.current_call.id then
+    return
+  end
+
+  self.current_progress = data
+
+  if self.current_call.state == "executing" then
+    self.set_progress_details(progress.format(data))
+  elseif self.current_call.state == "retrieving" then
+    self:set_result_winbar(self.page_index)
+  end
+end
+
 ---@private
 function ResultUI:apply_highlight(winid)
   -- switch to provided window, apply hightlight and jump back
   local current_win = vim.api.nvim_get_current_win()
   vim.api.nvim_set_current_win(winid)
+  vim.fn.clearmatches()
   -- match table separators and leading row numbers
   vim.cmd([[match NonText /^\s*\d\+\|─\|│\|┼/]])
+  -- match markers of diff rows and changed cells of call diffs
+  vim.fn.matchadd("DiffAdd", [[^\s*\d\+ │ +\s.*]])
+  vim.fn.matchadd("DiffDelete", [[^\s*\d\+ │ -\s.*]])
+  vim.fn.matchadd("DiffChange", [[^\s*\d\+ │ \~\s]])
+  vim.fn.matchadd("DiffText", [[[^│]* → [^│]*]])
   vim.api.nvim_set_current_win(current_win)
 end
 
@@ -134,7 +205,7 @@ end
 
 ---@private
 function ResultUI:display_progress()
-  self.stop_progress = progress.display(self.bufnr, self.progress_opts)
+  self.stop_progress, self.set_progress_details = progress.display(self.bufnr, self.progress_opts)
 end
 
 ---@private
@@ -152,6 +223,8 @@ function ResultUI:display_status()
     msg = "Failed retrieving results"
   elseif state == "canceled" then
     msg = "Call canceled"
+  elseif state == "timed_out" then
+    msg = "Call timed out"
   end
 
   local seconds = self.current_call.time_taken_us / 1000000
@@ -177,6 +250,49 @@ function ResultUI:display_status()
   vim.api.nvim_buf_set_option(self.bufnr, "modified", false)
 end
 
+-- Displays the estimate of a call which is awaiting confirmation.
+---@private
+function ResultUI:display_confirmation()
+  if not self.current_call then
+    error("no call set to result")
+  end
+
+  local estimate = self.current_call.estimate or { bytes = 0, rows = 0, detail = "" }
+
+  local lines = {
+    "Call is awaiting confirmation",
+    "Estimate:",
+    "    " .. progress.format_bytes(estimate.bytes) .. " processed",
+  }
+  if estimate.rows > 0 then
+    table.insert(lines, string.format("    %d rows", estimate.rows))
+  end
+  if estimate.detail ~= "" then
+    table.insert(lines, "    " .. string.gsub(estimate.detail, "\n", " "))
+  end
+
+  -- show keys of confirmation actions
+  local verbs = { approve_call = "approve", reject_call = "reject" }
+  for _, m in ipairs(self.mappings) do
+    if verbs[m.action] then
+      table.insert(lines, string.format("Press %s to %s the call", m.key, verbs[m.action]))
+    end
+  end
+
+  vim.api.nvim_buf_set_option(self.bufnr, "modifiable", true)
+  vim.api.nvim_buf_set_lines(self.bufnr, 0, -1, false, lines)
+  vim.api.nvim_buf_set_option(self.bufnr, "modifiable", false)
+
+  if self:has_window() then
+    vim.api.nvim_win_set_option(self.winid, "winbar", "Awaiting Confirmation")
+  end
+
+  self:focus_result_window()
+
+  -- reset modified flag
+  vim.api.nvim_buf_set_option(self.bufnr, "modified", false)
+end
+
 --- Displays a page of the current result in the results buffer
 ---@private
 ---@param page integer zero based page index
@@ -196,25 +312,19 @@ function ResultUI:display_result(page)
   local to = self.page_size * (page + 1)
 
   -- call go function
-  local length = self.handler:call_display_result(self.current_call.id, self.bufnr, from, to)
+  local length =
+    self.handler:call_display_result(self.current_call.id, self.bufnr, from, to, self.result_set, self.view)
 
   -- adjust page ammount
+  self.result_length = length
   self.page_ammount = math.floor(length / self.page_size)
   if length % self.page_size == 0 and self.page_ammount ~= 0 then
     self.page_ammount = self.page_ammount - 1
   end
 
-  -- convert from microseconds to seconds
-  local seconds = self.current_call.time_taken_us / 1000000
-
   -- set winbar status
-  if self:has_window() then
-    vim.api.nvim_win_set_option(
-      self.winid,
-      "winbar",
-      string.format("%d/%d (%d)%%=Took %.3fs", page + 1, self.page_ammount + 1, length, seconds)
-    )
-  end
+  self:set_result_winbar(page)
+
   -- set focus if window exists
   self:focus_result_window()
 
@@ -224,6 +334,64 @@ function ResultUI:display_result(page)
   return page
 end
 
+--- Sets the winbar status of the displayed result
+---@private
+---@param page integer zero based index of the displayed page
+function ResultUI:set_result_winbar(page)
+  if not self:has_window() then
+    return
+  end
+
+  -- convert from microseconds to seconds
+  local seconds = self.current_call.time_taken_us / 1000000
+
+  -- rows over the limits of the call were discarded
+  local truncated_status = ""
+  if self.current_call.state == "truncated" then
+    truncated_status = " (truncated)"
+  end
+
+  -- rows are sorted or filtered by the view
+  local view_status = ""
+  if not vim.tbl_isempty(self.view.filters or {}) or (self.view.search or "") ~= "" then
+    view_status = " (filtered)"
+  elseif not vim.tbl_isempty(self.view.sort or {}) then
+    view_status = " (sorted)"
+  end
+
+  -- show the result set index only if there is more than one
+  local set_status = ""
+  local result_sets = self.current_call.result_sets or 1
+  if result_sets > 1 then
+    set_status = string.format("[%d/%d] ", self.result_set + 1, result_sets)
+  end
+
+  -- while retrieving, show progress instead of the time taken
+  local time_status = string.format("Took %.3fs", seconds)
+  if self.current_call.state == "retrieving" and self.current_progress then
+    time_status = string.format(
+      "Retrieving %s %.3fs",
+      progress.format(self.current_progress),
+      self.current_progress.elapsed_us / 1000000
+    )
+  end
+
+  vim.api.nvim_win_set_option(
+    self.winid,
+    "winbar",
+    string.format(
+      "%s%d/%d (%d)%s%s%%=%s",
+      set_status,
+      page + 1,
+      self.page_ammount + 1,
+      self.result_length,
+      view_status,
+      truncated_status,
+      time_status
+    )
+  )
+end
+
 ---@private
 ---@return table<string, fun()>
 function ResultUI:get_actions()
@@ -240,6 +408,12 @@ function ResultUI:get_actions()
     page_first = function()
       self:page_first()
     end,
+    result_set_next = function()
+      self:result_set_next()
+    end,
+    result_set_prev = function()
+      self:result_set_prev()
+    end,
 
     -- yank functions
     yank_current_json = function()
@@ -260,12 +434,63 @@ function ResultUI:get_actions()
     yank_all_csv = function()
       self:store_all_wrapper("csv", vim.v.register)
     end,
+    yank_current_sql = function()
+      self:store_current_wrapper("sql", vim.v.register)
+    end,
+    yank_selection_sql = function()
+      self:store_selection_wrapper("sql", vim.v.register)
+    end,
+    yank_all_sql = function()
+      self:store_all_wrapper("sql", vim.v.register)
+    end,
+
+    -- client side view of rows
+    sort_asc = function()
+      self:sort_current_column(false)
+    end,
+    sort_desc = function()
+      self:sort_current_column(true)
+    end,
+    filter_column = function()
+      self:filter_current_column()
+    end,
+    search = function()
+      local search = self.view.search or ""
+      common.float_prompt({ { key = "search", value = search } }, {
+        title = "Search Result",
+        callback = function(res)
+          self:set_view(vim.tbl_extend("force", self.view, { search = vim.trim(res.search or "") }))
+        end,
+      })
+    end,
+    clear_view = function()
+      self:set_view({})
+    end,
+    column_stats = function()
+      if not self.current_call then
+        return
+      end
+      local stats = self.handler:call_column_stats(self.current_call.id, { result_set = self.result_set })
+      self:set_call(stats)
+    end,
 
     cancel_call = function()
       if self.current_call then
         self.handler:call_cancel(self.current_call.id)
       end
     end,
+
+    -- confirmation of calls with estimate over the threshold
+    approve_call = function()
+      if self.current_call then
+        self.handler:call_confirm(self.current_call.id, true)
+      end
+    end,
+    reject_call = function()
+      if self.current_call then
+        self.handler:call_confirm(self.current_call.id, false)
+      end
+    end,
   }
 end
 
@@ -280,15 +505,58 @@ function ResultUI:do_action(action)
 end
 
 -- sets call's result to Result's buffer
----@param call CallDetails
+---@param call CallDetails?
 function ResultUI:set_call(call)
   self.page_index = 0
   self.page_ammount = 0
+  self.result_set = 0
+  self.result_length = 0
+  self.view = {}
   self.current_call = call
+  self.current_progress = nil
 
   self.stop_progress()
 end
 
+-- Explains the query and displays its plan in Result's buffer.
+-- The displayed call (if any) is unset.
+---@param conn_id connection_id
+---@param query string
+---@param opts? { analyze: boolean, confirm: boolean }
+function ResultUI:display_plan(conn_id, query, opts)
+  opts = opts or {}
+
+  self:set_call(nil)
+
+  local ok, err = pcall(self.handler.connection_explain, self.handler, conn_id, query, {
+    analyze = opts.analyze,
+    confirm = opts.confirm,
+    buffer = self.bufnr,
+  })
+  if not ok and tostring(err):find("explain analyze needs confirmation", 1, true) then
+    -- analyze executes the query, so expensive queries are confirmed first
+    vim.ui.select({ "Yes", "No" }, { prompt = tostring(err) .. ". Analyze anyway?" }, function(choice)
+      if choice == "Yes" then
+        self:display_plan(conn_id, query, vim.tbl_extend("force", opts, { confirm = true }))
+      end
+    end)
+    return
+  end
+  if not ok then
+    vim.notify("Explaining query failed: " .. tostring(err), vim.log.levels.ERROR)
+    return
+  end
+
+  if self:has_window() then
+    vim.api.nvim_win_set_option(self.winid, "winbar", opts.analyze and "Query Plan (analyzed)" or "Query Plan")
+  end
+
+  self:focus_result_window()
+
+  -- reset modified flag
+  vim.api.nvim_buf_set_option(self.bufnr, "modified", false)
+end
+
 -- Gets the currently displayed call.
 ---@return CallDetails?
 function ResultUI:get_call()
@@ -315,6 +583,167 @@ function ResultUI:page_first()
   self.page_index = self:display_result(0)
 end
 
+--- Displays the first page of the result set with provided index.
+---@private
+---@param index integer zero based result set index
+function ResultUI:display_result_set(index)
+  if not self.current_call then
+    error("no call set to result")
+  end
+
+  local result_sets = self.current_call.result_sets or 1
+  if index < 0 or index >= result_sets then
+    return
+  end
+
+  self.result_set = index
+  self.view = {}
+  self.page_ammount = 0
+  self.page_index = self:display_result(0)
+end
+
+function ResultUI:result_set_next()
+  self:display_result_set(self.result_set + 1)
+end
+
+function ResultUI:result_set_prev()
+  self:display_result_set(self.result_set - 1)
+end
+
+-- Sets the view of the displayed result and displays its first page.
+---@param view? ResultView
+function ResultUI:set_view(view)
+  self.view = view or {}
+  if not self.current_call then
+    return
+  end
+
+  self.page_ammount = 0
+  self.page_index = self:display_result(0)
+end
+
+-- Gets the view of the displayed result.
+---@return ResultView
+function ResultUI:get_view()
+  return self.view
+end
+
+-- Returns the name of the column under cursor.
+---@private
+---@return string?
+function ResultUI:current_column()
+  if not self:has_window() then
+    return
+  end
+
+  local line = vim.api.nvim_get_current_line()
+  local col = vim.api.nvim_win_get_cursor(self.winid)[2]
+
+  -- columns are separated with "│" and the first one holds row numbers
+  local _, index = line:sub(1, col):gsub("│", "")
+  if index == 0 then
+    return
+  end
+
+  local header = vim.api.nvim_buf_get_lines(self.bufnr, 0, 1, false)[1] or ""
+  local column = vim.split(header, "│", { plain = true })[index + 1]
+  if not column then
+    return
+  end
+  return vim.trim(column)
+end
+
+-- Sorts the result by the column under cursor. Sorting by more columns
+-- adds them to the sort in order of precedence.
+---@private
+---@param descending boolean
+function ResultUI:sort_current_column(descending)
+  local column = self:current_column()
+  if not column then
+    return
+  end
+
+  local sort = vim.deepcopy(self.view.sort or {})
+  local found = false
+  for _, s in ipairs(sort) do
+    if s.column == column then
+      s.descending = descending
+      found = true
+    end
+  end
+  if not found then
+    table.insert(sort, { column = column, descending = descending })
+  end
+
+  self:set_view(vim.tbl_extend("force", self.view, { sort = sort }))
+end
+
+-- operators of filter expressions, longer prefixes first
+local filter_prefixes = {
+  { prefix = "!=", operator = "ne" },
+  { prefix = ">=", operator = "ge" },
+  { prefix = "<=", operator = "le" },
+  { prefix = "=", operator = "eq" },
+  { prefix = ">", operator = "gt" },
+  { prefix = "<", operator = "lt" },
+  { prefix = "~", operator = "contains" },
+}
+
+-- Parses a filter expression: "null", "!null", "/regex/", an operator ("=", "!=", ">", ">=", "<", "<=", "~")
+-- followed by a value, or just a value, which is matched with "contains".
+---@param expr string
+---@return filter_operator
+---@return string? value
+local function parse_filter(expr)
+  if expr == "null" then
+    return "null"
+  elseif expr == "!null" then
+    return "not_null"
+  end
+
+  local regex = expr:match("^/(.*)/$")
+  if regex then
+    return "regex", regex
+  end
+
+  for _, p in ipairs(filter_prefixes) do
+    if vim.startswith(expr, p.prefix) then
+      return p.operator, vim.trim(expr:sub(#p.prefix + 1))
+    end
+  end
+
+  return "contains", expr
+end
+
+-- Prompts for a filter of the column under cursor. Empty filter removes filters of the column.
+---@private
+function ResultUI:filter_current_column()
+  local column = self:current_column()
+  if not column then
+    return
+  end
+
+  common.float_prompt({ { key = "filter" } }, {
+    title = string.format('Filter "%s" (null, !null, /regex/, = != > >= < <= ~ value)', column),
+    callback = function(res)
+      local filters = {}
+      for _, f in ipairs(self.view.filters or {}) do
+        if f.column ~= column then
+          table.insert(filters, f)
+        end
+      end
+
+      local expr = vim.trim(res.filter or "")
+      if expr ~= "" then
+        local operator, value = parse_filter(expr)
+        table.insert(filters, { column = column, operator = operator, value = value })
+      end
+
+      self:set_view(vim.tbl_extend("force", self.view, { filters = filters }))
+    end,
+  })
+end
+
 -- wrapper for storing the current row
 ---@private
 ---@param format string
@@ -333,12 +762,15 @@ function ResultUI:store_current_wrapper(format, register)
     index = 0
   end
 
-  self.handler:call_store_result(
-    self.current_call.id,
-    format,
-    "yank",
-    { from = index, to = index + 1, extra_arg = register }
-  )
+  local call_id = self.current_call.id
+  self:with_store_options(format, function(sql)
+    self.handler:call_store_result(
+      call_id,
+      format,
+      "yank",
+      { from = index, to = index + 1, extra_arg = register, result_set = self.result_set, view = self.view, sql = sql }
+    )
+  end)
 end
 
 -- wrapper for storing the current visualy selected rows
@@ -357,12 +789,15 @@ function ResultUI:store_selection_wrapper(format, register)
     sindex = 0
   end
 
-  self.handler:call_store_result(
-    self.current_call.id,
-    format,
-    "yank",
-    { from = sindex, to = eindex, extra_arg = register }
-  )
+  local call_id = self.current_call.id
+  self:with_store_options(format, function(sql)
+    self.handler:call_store_result(
+      call_id,
+      format,
+      "yank",
+      { from = sindex, to = eindex, extra_arg = register, result_set = self.result_set, view = self.view, sql = sql }
+    )
+  end)
 end
 
 -- wrapper for storing all rows
@@ -373,7 +808,34 @@ function ResultUI:store_all_wrapper(format, register)
   if not self.current_call then
     error("no call set to result")
   end
-  self.handler:call_store_result(self.current_call.id, format, "yank", { extra_arg = register })
+  local call_id = self.current_call.id
+  self:with_store_options(format, function(sql)
+    self.handler:call_store_result(
+      call_id,
+      format,
+      "yank",
+      { extra_arg = register, result_set = self.result_set, view = self.view, sql = sql }
+    )
+  end)
+end
+
+-- calls store with options of the format,
+-- the target table of "sql" format is prompted for after the rows are selected
+---@private
+---@param format string
+---@param store fun(sql?: SqlStoreOptions)
+function ResultUI:with_store_options(format, store)
+  if format ~= "sql" then
+    store()
+    return
+  end
+
+  vim.ui.input({ prompt = "Target table: " }, function(name)
+    if not name or name == "" then
+      return
+    end
+    store({ table = name })
+  end)
 end
 
 ---@private
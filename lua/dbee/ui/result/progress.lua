@@ -2,13 +2,47 @@ local M = {}
 
 ---@alias progress_config { text_prefix: string, spinner: string[] }
 
+--- Format a number of bytes to a human readable size
+---@param bytes integer
+---@return string
+function M.format_bytes(bytes)
+  local size = bytes
+  local units = { "B", "KiB", "MiB", "GiB", "TiB", "PiB" }
+  local unit = 1
+  while size >= 1024 and unit < #units do
+    size = size / 1024
+    unit = unit + 1
+  end
+  return string.format("%.1f %s", size, units[unit])
+end
+
+--- Format progress of a call to a short human readable description
+---@param p CallProgress
+---@return string
+function M.format(p)
+  local text = string.format("%d rows", p.rows)
+  if p.estimated_rows > 0 then
+    text = string.format("%d/~%d rows", p.rows, p.estimated_rows)
+  end
+
+  if p.rows_read > 0 then
+    text = text .. string.format(", read %d rows", p.rows_read)
+  end
+  if p.bytes_read > 0 then
+    text = text .. string.format(" (%s)", M.format_bytes(p.bytes_read))
+  end
+
+  return text
+end
+
 --- Display an updated progress loader in the specified buffer
 ---@param bufnr integer -- buffer to display the progres in
 ---@param opts? progress_config
 ---@return fun() # cancel function
+---@return fun(details: string) # function that sets details displayed next to the loader
 function M.display(bufnr, opts)
   if not bufnr then
-    return function() end
+    return function() end, function(_) end
   end
   opts = opts or {}
   local text_prefix = opts.text_prefix or "Loading..."
@@ -16,13 +50,14 @@ function M.display(bufnr, opts)
 
   local icon_index = 1
   local start_time = vim.fn.reltimefloat(vim.fn.reltime())
+  local details = ""
 
   local function update()
     local passed_time = vim.fn.reltimefloat(vim.fn.reltime()) - start_time
     icon_index = (icon_index % #spinner) + 1
 
     vim.api.nvim_buf_set_option(bufnr, "modifiable", true)
-    local line = string.format("%s %.3f seconds %s ", text_prefix, passed_time, spinner[icon_index])
+    local line = string.format("%s %.3f seconds %s %s", text_prefix, passed_time, spinner[icon_index], details)
     vim.api.nvim_buf_set_lines(bufnr, 0, -1, false, { line })
     vim.api.nvim_buf_set_option(bufnr, "modifiable", false)
   end
@@ -30,6 +65,8 @@ function M.display(bufnr, opts)
   local timer = vim.fn.timer_start(100, update, { ["repeat"] = -1 })
   return function()
     pcall(vim.fn.timer_stop, timer)
+  end, function(d)
+    details = d or ""
   end
 end
 
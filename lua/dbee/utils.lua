@@ -277,6 +277,45 @@ function M.get_sql_statement_at_cursor(bufnr, cursor_row)
   return nil
 end
 
+---Find the location of a query error in the buffer the query was executed from
+---@param bufnr integer buffer number
+---@param query string executed query (part of the buffer)
+---@param qe QueryError
+---@return integer|nil row 0-indexed row of the error
+---@return integer|nil col 0-indexed byte column of the error
+function M.locate_query_error(bufnr, query, qe)
+  local text = table.concat(vim.api.nvim_buf_get_lines(bufnr, 0, -1, false), "\n")
+  local start = text:find(query, 1, true)
+  if not start then
+    return nil, nil
+  end
+
+  -- 0-indexed byte offset of the error in the query
+  local offset
+  if qe.position > 0 then
+    -- position is in characters
+    local ok, index = pcall(vim.str_byteindex, query, qe.position - 1)
+    offset = ok and index or qe.position - 1
+  elseif qe.line > 0 then
+    offset = 0
+    for _ = 2, qe.line do
+      local newline = query:find("\n", offset + 1, true)
+      if not newline then
+        break
+      end
+      offset = newline
+    end
+    offset = offset + math.max(qe.column - 1, 0)
+  else
+    return nil, nil
+  end
+
+  local before = text:sub(1, start - 1 + offset)
+  local _, row = before:gsub("\n", "")
+  local last_newline = before:match(".*()\n") or 0
+  return row, #before - last_newline
+end
+
 ---Get the line boundaries of SQL statement at cursor position
 ---@param bufnr integer buffer number
 ---@param cursor_row integer 0-indexed cursor row
@@ -79,7 +79,7 @@ end, {
     local nargs = #line
     if nargs == 1 then
       -- format
-      return { "csv", "json", "table" }
+      return { "csv", "json", "table", "markdown", "html", "asciidoc" }
     elseif nargs == 2 then
       -- output
       return { "file", "yank", "buffer" }